@@ -0,0 +1,648 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/ipam"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// OpenAPI is the OpenAPI 3 description of the API, served at /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// maxBody limits request bodies
+const maxBody = 1 << 20
+
+// errNotFound marks errors answered with 404
+var errNotFound = errors.New("not found")
+
+// Options configure where the server listens. The Unix socket is used when
+// Address is empty; TCP requires the server to have a token.
+type Options struct {
+	Socket  string // path of the Unix socket
+	Address string // host:port for TCP
+}
+
+// Server serves the REST API on top of a controller
+type Server struct {
+	ctrl      controller.Controller
+	clientDir string
+	audit     *audit.Logger
+	token     string
+
+	mu     sync.Mutex // serializes changes to config files and devices
+	http   *http.Server
+	socket string
+}
+
+// New creates an API server. clientDir is where client configs are generated
+// and served from.
+func New(ctrl controller.Controller, clientDir string, auditLog *audit.Logger, token string) *Server {
+	return &Server{ctrl: ctrl, clientDir: clientDir, audit: auditLog, token: token}
+}
+
+// Handler returns the API routes, wrapped in token authentication
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", s.openAPI)
+	mux.HandleFunc("GET /v1/tunnels", s.listTunnels)
+	mux.HandleFunc("POST /v1/tunnels", s.createTunnel)
+	mux.HandleFunc("GET /v1/tunnels/{name}", s.getTunnel)
+	mux.HandleFunc("PUT /v1/tunnels/{name}", s.updateTunnel)
+	mux.HandleFunc("DELETE /v1/tunnels/{name}", s.deleteTunnel)
+	mux.HandleFunc("POST /v1/tunnels/{name}/up", s.toggle(true))
+	mux.HandleFunc("POST /v1/tunnels/{name}/down", s.toggle(false))
+	mux.HandleFunc("POST /v1/tunnels/{name}/peers", s.addPeer)
+	mux.HandleFunc("DELETE /v1/tunnels/{name}/peers/{key}", s.removePeer)
+	mux.HandleFunc("GET /v1/tunnels/{name}/clients", s.listClients)
+	mux.HandleFunc("GET /v1/tunnels/{name}/clients/{client}", s.getClient)
+	mux.HandleFunc("GET /v1/backups", s.listBackups)
+	mux.HandleFunc("POST /v1/backups/restore", s.restoreBackup)
+	return s.authenticate(mux)
+}
+
+// Start listens according to opts and serves in the background
+func (s *Server) Start(opts Options) error {
+	var ln net.Listener
+	var err error
+	if opts.Address != "" {
+		if s.token == "" {
+			return errors.New("a token is required to listen on TCP")
+		}
+		ln, err = net.Listen("tcp", opts.Address)
+	} else {
+		ln, err = listenUnix(opts.Socket)
+		s.socket = opts.Socket
+	}
+	if err != nil {
+		return err
+	}
+
+	s.http = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go s.http.Serve(ln)
+	return nil
+}
+
+// Close stops the server and removes its socket
+func (s *Server) Close() error {
+	if s.http == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := s.http.Shutdown(ctx)
+	if s.socket != "" {
+		os.Remove(s.socket)
+	}
+	return err
+}
+
+// listenUnix replaces a stale socket and restricts it to the owner. The
+// socket is created in a private directory and moved into place once it is
+// 0600, so it is never reachable with looser permissions.
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("no socket path configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		os.Remove(path)
+	}
+
+	private, err := os.MkdirTemp(filepath.Dir(path), ".wgadmin-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(private)
+	tmp := filepath.Join(private, "api.sock")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// Close removes the socket at its final path
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	err = os.Chmod(tmp, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// authenticate checks the bearer token when one is configured
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+func (s *Server) listTunnels(w http.ResponseWriter, r *http.Request) {
+	interfaces, err := s.ctrl.ListInterfaces()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]Tunnel, 0, len(interfaces))
+	for _, iface := range interfaces {
+		cfg, err := controller.ReadConfig(s.ctrl.GetConfigPath(iface.Name))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", iface.Name, err))
+			return
+		}
+		out = append(out, tunnelJSON(iface.Name, iface.Active, cfg))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getTunnel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	cfg, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tunnelJSON(name, active, cfg))
+}
+
+func (s *Server) createTunnel(w http.ResponseWriter, r *http.Request) {
+	var t Tunnel
+	if !readJSON(w, r, &t) {
+		return
+	}
+	if !wg.ValidateName(t.Name) {
+		writeError(w, http.StatusUnprocessableEntity, invalid("Name", "must be 1-15 alphanumeric characters"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctrl.ConfigExists(t.Name) {
+		writeError(w, http.StatusConflict, fmt.Errorf("tunnel %s already exists", t.Name))
+		return
+	}
+	cfg, err := toConfig(t, nil)
+	if err == nil {
+		err = validate(cfg)
+	}
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	err = s.ctrl.WriteConfig(t.Name, *cfg)
+	s.audit.Record(audit.ActionCreate, t.Name, "", err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tunnelJSON(t.Name, false, cfg))
+}
+
+func (s *Server) updateTunnel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var t Tunnel
+	if !readJSON(w, r, &t) {
+		return
+	}
+	if t.Name != "" && t.Name != name {
+		writeError(w, http.StatusUnprocessableEntity, invalid("Name", "cannot be changed"))
+		return
+	}
+	t.Name = name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	cfg, err := toConfig(t, old)
+	if err == nil {
+		err = validate(cfg)
+	}
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	s.write(w, name, active, old, cfg, audit.ActionEdit, http.StatusOK)
+}
+
+func (s *Server) deleteTunnel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	backup := r.URL.Query().Get("backup") != "false"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	if active {
+		err = s.ctrl.ToggleInterface(name, false)
+		s.audit.Record(audit.ActionDeactivate, name, "", err)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("deactivate: %w", err))
+			return
+		}
+	}
+	err = s.ctrl.DeleteInterface(name, backup)
+	s.audit.Record(audit.ActionDelete, name, "", err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) toggle(activate bool) http.HandlerFunc {
+	action := audit.ActionDeactivate
+	if activate {
+		action = audit.ActionActivate
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, _, err := s.load(name); err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		err := s.ctrl.ToggleInterface(name, activate)
+		s.audit.Record(action, name, "", err)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		cfg, active, err := s.load(name)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tunnelJSON(name, active, cfg))
+	}
+}
+
+// addPeer appends a peer. A missing key pair is generated, and missing
+// AllowedIPs get the next free address of the tunnel. When the server
+// generated the key and the tunnel has a public endpoint, a client config
+// is written for download.
+func (s *Server) addPeer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var p Peer
+	if !readJSON(w, r, &p) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	privateKey := ""
+	if p.PublicKey == "" {
+		priv, pub, err := wg.GenerateKeyPair()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		privateKey, p.PublicKey = priv, pub
+	}
+	if findPeer(old.Peers, p.PublicKey) != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("peer %s already exists", p.PublicKey))
+		return
+	}
+	if len(p.AllowedIPs) == 0 {
+		next, err := ipam.Next(old.Interface.Address, old.Peers)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, invalid("Peer.AllowedIPs", err.Error()))
+			return
+		}
+		p.AllowedIPs = netStrings(next)
+	}
+
+	peer, err := toPeer(p, nil)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	cfg := *old
+	cfg.Peers = append(append([]config.PeerConfig(nil), old.Peers...), peer)
+	if err := validate(&cfg); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	if !s.commit(w, name, active, old, &cfg, audit.ActionEdit) {
+		return
+	}
+
+	out := peerJSON(peer)
+	if privateKey != "" {
+		out.PrivateKey = privateKey
+		// The peer is already committed, so a failed client config is
+		// reported alongside it rather than failing the request
+		if err := s.writeClientConfig(name, &cfg, peer, privateKey); err != nil {
+			out.ClientConfigError = err.Error()
+		}
+	}
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (s *Server) removePeer(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	key := r.PathValue("key")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	cfg := *old
+	cfg.Peers = nil
+	for _, p := range old.Peers {
+		if p.PublicKey.String() != key {
+			cfg.Peers = append(cfg.Peers, p)
+		}
+	}
+	if len(cfg.Peers) == len(old.Peers) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("peer %s: %w", key, errNotFound))
+		return
+	}
+	if s.commit(w, name, active, old, &cfg, audit.ActionEdit) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) listClients(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !wg.ValidateName(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tunnel name %q", name))
+		return
+	}
+	if !s.ctrl.ConfigExists(name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("tunnel %s: %w", name, errNotFound))
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(s.clientDir, name, "*.conf"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	clients := make([]string, 0, len(paths))
+	for _, p := range paths {
+		clients = append(clients, strings.TrimSuffix(filepath.Base(p), ".conf"))
+	}
+	writeJSON(w, http.StatusOK, clients)
+}
+
+func (s *Server) getClient(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	client := strings.TrimSuffix(r.PathValue("client"), ".conf")
+	if !wg.ValidateName(name) || client == "" || client != filepath.Base(client) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("client %s: %w", client, errNotFound))
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(s.clientDir, name, client+".conf"))
+	if os.IsNotExist(err) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("client %s: %w", client, errNotFound))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", client+".conf"))
+	w.Write(data)
+}
+
+func (s *Server) listBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := s.ctrl.ListBackups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tunnel := r.URL.Query().Get("tunnel")
+	out := make([]Backup, 0, len(backups))
+	for _, b := range backups {
+		if tunnel == "" || b.Name == tunnel {
+			out = append(out, backupJSON(b))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) restoreBackup(w http.ResponseWriter, r *http.Request) {
+	var req RestoreRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backups, err := s.ctrl.ListBackups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var found *controller.Backup
+	for i := range backups {
+		if backups[i].Filename == req.Filename {
+			found = &backups[i]
+		}
+	}
+	if found == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("backup %s: %w", req.Filename, errNotFound))
+		return
+	}
+
+	name := found.Name
+	if req.As != "" {
+		if !wg.ValidateName(req.As) {
+			writeError(w, http.StatusUnprocessableEntity, invalid("As", "must be 1-15 alphanumeric characters"))
+			return
+		}
+		if s.ctrl.ConfigExists(req.As) {
+			writeError(w, http.StatusConflict, fmt.Errorf("tunnel %s already exists", req.As))
+			return
+		}
+		name = req.As
+		err = controller.RestoreBackupAs(s.ctrl, req.Filename, req.As)
+	} else {
+		if s.ctrl.ConfigExists(name) && !req.Force {
+			writeError(w, http.StatusConflict, fmt.Errorf("tunnel %s exists: restore with \"as\" set to a new name, or set \"force\" to overwrite it", name))
+			return
+		}
+		err = s.ctrl.RestoreBackup(req.Filename)
+	}
+	s.audit.Record(audit.ActionRestore, name, "", err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	cfg, active, err := s.load(name)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tunnelJSON(name, active, cfg))
+}
+
+// load parses the config of name and reports whether it is active
+func (s *Server) load(name string) (*config.Config, bool, error) {
+	if !wg.ValidateName(name) || !s.ctrl.ConfigExists(name) {
+		return nil, false, fmt.Errorf("tunnel %s: %w", name, errNotFound)
+	}
+	cfg, err := controller.ReadConfig(s.ctrl.GetConfigPath(name))
+	if err != nil {
+		return nil, false, err
+	}
+	interfaces, err := s.ctrl.ListInterfaces()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, iface := range interfaces {
+		if iface.Name == name {
+			return cfg, iface.Active, nil
+		}
+	}
+	return cfg, false, nil
+}
+
+// write saves cfg, applies peer changes to an active tunnel and answers with
+// the new tunnel
+func (s *Server) write(w http.ResponseWriter, name string, active bool, old, cfg *config.Config, action string, status int) {
+	if s.commit(w, name, active, old, cfg, action) {
+		writeJSON(w, status, tunnelJSON(name, active, cfg))
+	}
+}
+
+// commit saves cfg and, for an active tunnel, applies the peer changes live
+// like the GUI does. It answers the request itself on failure.
+func (s *Server) commit(w http.ResponseWriter, name string, active bool, old, cfg *config.Config, action string) bool {
+	err := s.ctrl.WriteConfig(name, *cfg)
+	s.audit.Record(action, name, "", err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if active {
+		for _, res := range controller.ApplyPeerDelta(s.ctrl, name, old.Peers, cfg.Peers) {
+			s.audit.Record(audit.ActionPeerApply, name, res.Peer, res.Err)
+		}
+	}
+	return true
+}
+
+// writeClientConfig generates the client config of a new peer, if the
+// tunnel has a public endpoint. Failures leave the peer in place.
+func (s *Server) writeClientConfig(name string, cfg *config.Config, peer config.PeerConfig, privateKey string) error {
+	peerName := peermeta.Name(peer.Name)
+	if cfg.PublicEndpoint == "" || peerName == "" {
+		return nil
+	}
+	dir := filepath.Join(s.clientDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create client config directory: %w", err)
+	}
+	opts := wg.PeerOpts{
+		Name:                peerName,
+		PublicKey:           peer.PublicKey.String(),
+		AllowedIPs:          netStrings(peer.AllowedIPs),
+		Endpoint:            cfg.PublicEndpoint,
+		PersistentKeepalive: peer.PersistentKeepalive,
+	}
+	clientCtrl := wg.New(dir)
+	if _, err := clientCtrl.NewClientConfig(*cfg, opts, privateKey, true); err != nil {
+		return fmt.Errorf("client config for '%s': %w", peerName, err)
+	}
+	return nil
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	body := errorBody{Error: err.Error()}
+	var verr validationError
+	if errors.As(err, &verr) {
+		body.Details = verr.details()
+	}
+	writeJSON(w, status, body)
+}
+
+func statusFor(err error) int {
+	var verr validationError
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &verr):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
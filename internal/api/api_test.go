@@ -0,0 +1,412 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/history"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const testToken = "s3cret"
+
+type testServer struct {
+	*httptest.Server
+	fake      *controller.Fake
+	clientDir string
+	auditPath string
+}
+
+func newTestServer(t *testing.T, token string) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	fake, err := controller.NewFake(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := controller.NewVersioned(fake, history.NewStore(history.DefaultDir(dir), history.Policy{KeepLast: 10}))
+	clientDir := t.TempDir()
+	auditPath := filepath.Join(dir, "audit.jsonl")
+
+	srv := httptest.NewServer(New(ctrl, clientDir, audit.New(auditPath, "api"), token).Handler())
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, fake: fake, clientDir: clientDir, auditPath: auditPath}
+}
+
+// do sends a request with the test token and decodes a JSON response into out
+func (ts *testServer) do(t *testing.T, method, path string, body any, out any) int {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func createTunnel(t *testing.T, ts *testServer, name string) Tunnel {
+	t.Helper()
+	port := 51820
+	var created Tunnel
+	status := ts.do(t, http.MethodPost, "/v1/tunnels", Tunnel{
+		Name:           name,
+		Address:        []string{"10.8.0.1/24"},
+		ListenPort:     &port,
+		PublicEndpoint: "vpn.example.com:51820",
+	}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("create %s: status %d", name, status)
+	}
+	return created
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t, testToken)
+
+	resp, err := http.Get(ts.URL + "/v1/tunnels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without token: status %d, want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/tunnels", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d, want 401", resp.StatusCode)
+	}
+
+	var tunnels []Tunnel
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels", nil, &tunnels); status != http.StatusOK {
+		t.Errorf("with token: status %d, want 200", status)
+	}
+}
+
+func TestStartRequiresTokenForTCP(t *testing.T) {
+	s := New(nil, "", nil, "")
+	if err := s.Start(Options{Address: "127.0.0.1:0"}); err == nil {
+		s.Close()
+		t.Fatal("expected TCP without a token to be refused")
+	}
+}
+
+func TestUnixSocketIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.sock")
+	s := New(nil, "", nil, "")
+	if err := s.Start(Options{Socket: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want a 0600 socket", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("socket directory holds %d entries, want the socket only", len(entries))
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://wgadmin/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	if err := New(nil, "", nil, "").Start(Options{Socket: path}); err == nil {
+		t.Error("a second server took over the socket in use")
+	}
+	s.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left after Close: %v", err)
+	}
+}
+
+func TestTunnelLifecycle(t *testing.T) {
+	ts := newTestServer(t, testToken)
+
+	created := createTunnel(t, ts, "wg0")
+	if created.PublicKey == "" || created.PrivateKey != "" {
+		t.Errorf("created tunnel: public key %q, private key %q; want a public key only", created.PublicKey, created.PrivateKey)
+	}
+	if !ts.fake.ConfigExists("wg0") {
+		t.Fatal("wg0.conf not written")
+	}
+
+	var errBody errorBody
+	if status := ts.do(t, http.MethodPost, "/v1/tunnels", Tunnel{Name: "wg0", Address: []string{"10.9.0.1/24"}}, &errBody); status != http.StatusConflict {
+		t.Errorf("duplicate create: status %d, want 409", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/v1/tunnels", Tunnel{Name: "wg1", Address: []string{"not-a-cidr"}}, &errBody); status != http.StatusUnprocessableEntity {
+		t.Errorf("invalid address: status %d, want 422", status)
+	}
+	if len(errBody.Details) == 0 {
+		t.Error("validation error has no details")
+	}
+
+	var got Tunnel
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels/wg0", nil, &got); status != http.StatusOK {
+		t.Fatalf("get: status %d", status)
+	}
+	if got.PublicKey != created.PublicKey || len(got.Address) != 1 || got.Address[0] != "10.8.0.1/24" {
+		t.Errorf("get returned %+v", got)
+	}
+
+	// Update without a private key keeps the existing one
+	got.MTU = 1380
+	got.PublicKey = ""
+	var updated Tunnel
+	if status := ts.do(t, http.MethodPut, "/v1/tunnels/wg0", got, &updated); status != http.StatusOK {
+		t.Fatalf("update: status %d", status)
+	}
+	if updated.MTU != 1380 || updated.PublicKey != created.PublicKey {
+		t.Errorf("update returned MTU %d key %s, want 1380 and the original key", updated.MTU, updated.PublicKey)
+	}
+
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels/missing", nil, &errBody); status != http.StatusNotFound {
+		t.Errorf("get missing: status %d, want 404", status)
+	}
+
+	if status := ts.do(t, http.MethodPost, "/v1/tunnels/wg0/up", nil, &got); status != http.StatusOK || !got.Active {
+		t.Errorf("up: status %d active %v", status, got.Active)
+	}
+	if status := ts.do(t, http.MethodDelete, "/v1/tunnels/wg0", nil, nil); status != http.StatusNoContent {
+		t.Errorf("delete: status %d, want 204", status)
+	}
+	if ts.fake.ConfigExists("wg0") || ts.fake.IsActive("wg0") {
+		t.Error("wg0 still exists or is active after delete")
+	}
+
+	entries, err := audit.Read(ts.auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		if e.Source != "api" {
+			t.Errorf("audit entry source %q, want api", e.Source)
+		}
+		actions = append(actions, e.Action)
+	}
+	want := "create edit activate deactivate delete"
+	if strings.Join(actions, " ") != want {
+		t.Errorf("audit actions = %v, want %s", actions, want)
+	}
+}
+
+func TestPeers(t *testing.T) {
+	ts := newTestServer(t, testToken)
+	createTunnel(t, ts, "wg0")
+	var tunnel Tunnel
+	ts.do(t, http.MethodPost, "/v1/tunnels/wg0/up", nil, &tunnel)
+
+	// No keys and no addresses: both are generated, and a client config is written
+	var added Peer
+	if status := ts.do(t, http.MethodPost, "/v1/tunnels/wg0/peers", Peer{Name: "phone"}, &added); status != http.StatusCreated {
+		t.Fatalf("add peer: status %d", status)
+	}
+	if added.PrivateKey == "" || added.PublicKey == "" {
+		t.Errorf("generated peer is missing keys: %+v", added)
+	}
+	if added.ClientConfigError != "" {
+		t.Errorf("client config: %s", added.ClientConfigError)
+	}
+	if len(added.AllowedIPs) != 1 || added.AllowedIPs[0] != "10.8.0.2/32" {
+		t.Errorf("allowed IPs = %v, want [10.8.0.2/32]", added.AllowedIPs)
+	}
+
+	// The peer is applied to the running device without a restart
+	dev, err := ts.fake.Device("wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.Peers) != 1 || dev.Peers[0].PublicKey.String() != added.PublicKey {
+		t.Errorf("device peers = %v, want the added peer", dev.Peers)
+	}
+
+	key, _ := wgtypes.GeneratePrivateKey()
+	var errBody errorBody
+	status := ts.do(t, http.MethodPost, "/v1/tunnels/wg0/peers", Peer{
+		Name: "laptop", PublicKey: key.PublicKey().String(), AllowedIPs: []string{"10.8.0.2/32"},
+	}, &errBody)
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("overlapping AllowedIPs: status %d, want 422", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/v1/tunnels/wg0/peers", Peer{PublicKey: added.PublicKey}, &errBody); status != http.StatusConflict {
+		t.Errorf("duplicate peer: status %d, want 409", status)
+	}
+
+	path := "/v1/tunnels/wg0/peers/" + url.PathEscape(added.PublicKey)
+	if status := ts.do(t, http.MethodDelete, path, nil, nil); status != http.StatusNoContent {
+		t.Errorf("remove peer: status %d, want 204", status)
+	}
+	if status := ts.do(t, http.MethodDelete, path, nil, &errBody); status != http.StatusNotFound {
+		t.Errorf("remove missing peer: status %d, want 404", status)
+	}
+	ts.do(t, http.MethodGet, "/v1/tunnels/wg0", nil, &tunnel)
+	if len(tunnel.Peers) != 0 {
+		t.Errorf("peers after removal = %v", tunnel.Peers)
+	}
+}
+
+func TestWriteClientConfigReportsErrors(t *testing.T) {
+	clientDir := t.TempDir()
+	s := New(nil, clientDir, nil, "")
+	key, _ := wgtypes.GeneratePrivateKey()
+	cfg := &config.Config{PublicEndpoint: "vpn.example.com:51820"}
+	peer := config.PeerConfig{Name: "phone", PublicKey: key.PublicKey()}
+
+	// A file where the client directory belongs makes the config unwritable
+	if err := os.WriteFile(filepath.Join(clientDir, "wg0"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.writeClientConfig("wg0", cfg, peer, key.String()); err == nil {
+		t.Error("expected an error when the client directory cannot be created")
+	}
+
+	// Without a public endpoint there is nothing to write
+	cfg.PublicEndpoint = ""
+	if err := s.writeClientConfig("wg0", cfg, peer, key.String()); err != nil {
+		t.Errorf("without endpoint: %v", err)
+	}
+}
+
+func TestClientDownload(t *testing.T) {
+	ts := newTestServer(t, testToken)
+	createTunnel(t, ts, "wg0")
+	dir := filepath.Join(ts.clientDir, "wg0")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "phone.conf"), []byte("[Interface]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var clients []string
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels/wg0/clients", nil, &clients); status != http.StatusOK || len(clients) != 1 || clients[0] != "phone" {
+		t.Errorf("clients: status %d, %v", status, clients)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/tunnels/wg0/clients/phone", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("download: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var errBody errorBody
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels/a-name-that-is-too-long/clients", nil, &errBody); status != http.StatusBadRequest {
+		t.Errorf("list with an invalid tunnel name: status %d, want 400", status)
+	}
+	if status := ts.do(t, http.MethodGet, "/v1/tunnels/wg0/clients/..%2F..%2Fwg0", nil, &errBody); status != http.StatusNotFound {
+		t.Errorf("path traversal: status %d, want 404", status)
+	}
+}
+
+func TestRestoreBackupAs(t *testing.T) {
+	ts := newTestServer(t, testToken)
+	createTunnel(t, ts, "wg0")
+
+	var backups []Backup
+	if status := ts.do(t, http.MethodGet, "/v1/backups?tunnel=wg0", nil, &backups); status != http.StatusOK || len(backups) == 0 {
+		t.Fatalf("backups: status %d, %v", status, backups)
+	}
+
+	var errBody errorBody
+	if status := ts.do(t, http.MethodPost, "/v1/backups/restore", RestoreRequest{Filename: backups[0].Filename, As: "wg0"}, &errBody); status != http.StatusConflict {
+		t.Errorf("restore over an existing tunnel: status %d, want 409", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/v1/backups/restore", RestoreRequest{Filename: backups[0].Filename}, &errBody); status != http.StatusConflict {
+		t.Errorf("restore in place without force: status %d, want 409", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/v1/backups/restore", RestoreRequest{Filename: backups[0].Filename, Force: true}, nil); status != http.StatusOK {
+		t.Errorf("restore in place with force: status %d, want 200", status)
+	}
+
+	var restored Tunnel
+	if status := ts.do(t, http.MethodPost, "/v1/backups/restore", RestoreRequest{Filename: backups[0].Filename, As: "wg9"}, &restored); status != http.StatusOK {
+		t.Fatalf("restore as wg9: status %d", status)
+	}
+	if restored.Name != "wg9" || !ts.fake.ConfigExists("wg9") {
+		t.Errorf("restored %+v, want wg9 on disk", restored)
+	}
+}
+
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(OpenAPI, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+
+	routes := []string{
+		"GET /v1/tunnels", "POST /v1/tunnels",
+		"GET /v1/tunnels/{name}", "PUT /v1/tunnels/{name}", "DELETE /v1/tunnels/{name}",
+		"POST /v1/tunnels/{name}/up", "POST /v1/tunnels/{name}/down",
+		"POST /v1/tunnels/{name}/peers", "DELETE /v1/tunnels/{name}/peers/{key}",
+		"GET /v1/tunnels/{name}/clients", "GET /v1/tunnels/{name}/clients/{client}",
+		"GET /v1/backups", "POST /v1/backups/restore",
+	}
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("%s is not documented", route)
+		}
+	}
+}
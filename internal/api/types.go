@@ -0,0 +1,301 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/ipam"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Tunnel is the JSON form of a tunnel config. PrivateKey is accepted on
+// create and update but never returned.
+type Tunnel struct {
+	Name           string   `json:"name"`
+	Active         bool     `json:"active"`
+	PublicKey      string   `json:"public_key,omitempty"`
+	PrivateKey     string   `json:"private_key,omitempty"`
+	Address        []string `json:"address"`
+	ListenPort     *int     `json:"listen_port,omitempty"`
+	DNS            []string `json:"dns,omitempty"`
+	MTU            int      `json:"mtu,omitempty"`
+	Table          string   `json:"table,omitempty"`
+	PostUp         string   `json:"post_up,omitempty"`
+	PostDown       string   `json:"post_down,omitempty"`
+	PublicEndpoint string   `json:"public_endpoint,omitempty"`
+	Peers          []Peer   `json:"peers"`
+}
+
+// Peer is the JSON form of a peer. PresharedKey is accepted but never
+// returned; HasPresharedKey reports whether one is set. PrivateKey is only
+// returned when the server generated the peer's key pair.
+type Peer struct {
+	Name                string     `json:"name,omitempty"`
+	PublicKey           string     `json:"public_key"`
+	PrivateKey          string     `json:"private_key,omitempty"`
+	PresharedKey        string     `json:"preshared_key,omitempty"`
+	HasPresharedKey     bool       `json:"has_preshared_key"`
+	AllowedIPs          []string   `json:"allowed_ips"`
+	Endpoint            string     `json:"endpoint,omitempty"`
+	PersistentKeepalive int        `json:"persistent_keepalive,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	Disabled            bool       `json:"disabled,omitempty"`
+	ClientConfigError   string     `json:"client_config_error,omitempty"` // set when the peer was added but its client config was not written
+}
+
+// Backup is the JSON form of a backup or config version
+type Backup struct {
+	Name      string    `json:"name"`
+	Filename  string    `json:"filename"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// RestoreRequest restores a backup in place, or as a new tunnel when As is set
+type RestoreRequest struct {
+	Filename string `json:"filename"`
+	As       string `json:"as,omitempty"`
+	Force    bool   `json:"force,omitempty"` // overwrite an existing tunnel when restoring in place
+}
+
+// errorBody is returned with every error status
+type errorBody struct {
+	Error   string   `json:"error"`
+	Details []string `json:"details,omitempty"`
+}
+
+// validationError carries the errors of wg.ValidateConfig and is answered
+// with 422 Unprocessable Entity
+type validationError struct {
+	errs []error
+}
+
+func (e validationError) Error() string {
+	return "invalid config"
+}
+
+func (e validationError) details() []string {
+	out := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		out[i] = err.Error()
+	}
+	return out
+}
+
+func invalid(field, msg string) error {
+	return validationError{[]error{wg.ValidationError{Field: field, Message: msg}}}
+}
+
+// tunnelJSON converts a parsed config for output
+func tunnelJSON(name string, active bool, cfg *config.Config) Tunnel {
+	t := Tunnel{
+		Name:           name,
+		Active:         active,
+		PublicKey:      cfg.Interface.PrivateKey.PublicKey().String(),
+		Address:        netStrings(cfg.Interface.Address),
+		ListenPort:     cfg.Interface.ListenPort,
+		MTU:            cfg.Interface.MTU,
+		Table:          cfg.Interface.Table,
+		PostUp:         cfg.Interface.PostUp,
+		PostDown:       cfg.Interface.PostDown,
+		PublicEndpoint: cfg.PublicEndpoint,
+		Peers:          make([]Peer, 0, len(cfg.Peers)),
+	}
+	for _, ip := range cfg.Interface.DNS {
+		t.DNS = append(t.DNS, ip.String())
+	}
+	for _, p := range cfg.Peers {
+		t.Peers = append(t.Peers, peerJSON(p))
+	}
+	return t
+}
+
+func peerJSON(p config.PeerConfig) Peer {
+	meta := peermeta.Parse(p.Name)
+	out := Peer{
+		Name:                meta.Name,
+		PublicKey:           p.PublicKey.String(),
+		HasPresharedKey:     p.PresharedKey != nil,
+		AllowedIPs:          netStrings(p.AllowedIPs),
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: p.PersistentKeepalive,
+		Disabled:            meta.Disabled,
+	}
+	if !meta.Expires.IsZero() {
+		out.ExpiresAt = &meta.Expires
+	}
+	return out
+}
+
+// toConfig builds a config from t. Keys left empty are kept from existing,
+// which is nil on create; a missing private key is then generated.
+func toConfig(t Tunnel, existing *config.Config) (*config.Config, error) {
+	cfg := &config.Config{
+		Name:           t.Name,
+		PublicEndpoint: t.PublicEndpoint,
+		Interface: config.InterfaceConfig{
+			ListenPort: t.ListenPort,
+			MTU:        t.MTU,
+			Table:      t.Table,
+			PostUp:     strings.TrimSpace(t.PostUp),
+			PostDown:   strings.TrimSpace(t.PostDown),
+		},
+	}
+	if cfg.Interface.MTU == 0 {
+		cfg.Interface.MTU = 1420
+	}
+	if cfg.Interface.Table == "" {
+		cfg.Interface.Table = "auto"
+	}
+
+	switch {
+	case t.PrivateKey != "":
+		key, err := wgtypes.ParseKey(t.PrivateKey)
+		if err != nil {
+			return nil, invalid("PrivateKey", "invalid key")
+		}
+		cfg.Interface.PrivateKey = key
+	case existing != nil:
+		cfg.Interface.PrivateKey = existing.Interface.PrivateKey
+	default:
+		key, err := wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Interface.PrivateKey = key
+	}
+
+	if len(t.Address) == 0 {
+		return nil, invalid("Address", "required")
+	}
+	addrs, err := ipam.ParseCIDRList(strings.Join(t.Address, ","))
+	if err != nil {
+		return nil, invalid("Address", "invalid CIDR format")
+	}
+	cfg.Interface.Address = addrs
+
+	for _, dns := range t.DNS {
+		ip := net.ParseIP(strings.TrimSpace(dns))
+		if ip == nil {
+			return nil, invalid("DNS", fmt.Sprintf("invalid DNS address: %s", dns))
+		}
+		cfg.Interface.DNS = append(cfg.Interface.DNS, ip)
+	}
+
+	if t.ListenPort != nil && (*t.ListenPort < 0 || *t.ListenPort > 65535) {
+		return nil, invalid("ListenPort", "must be 0-65535")
+	}
+	if t.PublicEndpoint != "" && !wg.ValidateEndpoint(t.PublicEndpoint) {
+		return nil, invalid("PublicEndpoint", "invalid format (host:port)")
+	}
+
+	var oldPeers []config.PeerConfig
+	if existing != nil {
+		oldPeers = existing.Peers
+	}
+	for _, p := range t.Peers {
+		peer, err := toPeer(p, findPeer(oldPeers, p.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Peers = append(cfg.Peers, peer)
+	}
+	return cfg, nil
+}
+
+// toPeer builds a peer from p, keeping the preshared key and name metadata
+// of existing when p leaves them empty
+func toPeer(p Peer, existing *config.PeerConfig) (config.PeerConfig, error) {
+	peer := config.PeerConfig{
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: p.PersistentKeepalive,
+	}
+
+	var meta peermeta.Meta
+	if existing != nil {
+		meta = peermeta.Parse(existing.Name)
+	}
+	if strings.Contains(p.Name, "|") {
+		return peer, invalid("Peer.Name", "cannot contain '|'")
+	}
+	meta.Name = p.Name
+	meta.Expires = time.Time{}
+	if p.ExpiresAt != nil {
+		meta.Expires = *p.ExpiresAt
+	}
+	meta.Disabled = p.Disabled
+	peer.Name = meta.String()
+
+	key, err := wgtypes.ParseKey(p.PublicKey)
+	if err != nil {
+		return peer, invalid("Peer.PublicKey", fmt.Sprintf("invalid key %q", p.PublicKey))
+	}
+	peer.PublicKey = key
+
+	switch {
+	case p.PresharedKey != "":
+		psk, err := wgtypes.ParseKey(p.PresharedKey)
+		if err != nil {
+			return peer, invalid("Peer.PresharedKey", "invalid key")
+		}
+		peer.PresharedKey = &psk
+	case existing != nil:
+		peer.PresharedKey = existing.PresharedKey
+	}
+
+	if len(p.AllowedIPs) == 0 {
+		return peer, invalid("Peer.AllowedIPs", "required")
+	}
+	ips, err := ipam.ParseCIDRList(strings.Join(p.AllowedIPs, ","))
+	if err != nil {
+		return peer, invalid("Peer.AllowedIPs", "invalid CIDR format")
+	}
+	peer.AllowedIPs = ips
+
+	if p.Endpoint != "" && !wg.ValidateEndpoint(p.Endpoint) {
+		return peer, invalid("Peer.Endpoint", "invalid format (host:port)")
+	}
+	if p.PersistentKeepalive < 0 || p.PersistentKeepalive > 65535 {
+		return peer, invalid("Peer.PersistentKeepalive", "must be 0-65535")
+	}
+	return peer, nil
+}
+
+// validate runs the same checks as the tunnel form before a write
+func validate(cfg *config.Config) error {
+	if err := ipam.CheckPeers(cfg.Peers); err != nil {
+		return invalid("Peer.AllowedIPs", err.Error())
+	}
+	if errs := wg.ValidateConfig(cfg); len(errs) > 0 {
+		return validationError{errs}
+	}
+	return nil
+}
+
+func findPeer(peers []config.PeerConfig, publicKey string) *config.PeerConfig {
+	for i := range peers {
+		if peers[i].PublicKey.String() == publicKey {
+			return &peers[i]
+		}
+	}
+	return nil
+}
+
+func backupJSON(b controller.Backup) Backup {
+	return Backup{Name: b.Name, Filename: b.Filename, Timestamp: b.Timestamp, Reason: b.Reason}
+}
+
+func netStrings(nets []net.IPNet) []string {
+	out := make([]string, len(nets))
+	for i, n := range nets {
+		out[i] = n.String()
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionActivate    = "activate"
+	ActionDeactivate  = "deactivate"
+	ActionCreate      = "create"
+	ActionEdit        = "edit"
+	ActionImport      = "import"
+	ActionDelete      = "delete"
+	ActionRestore     = "restore"
+	ActionCleanBackup = "clean-backups"
+	ActionPeerApply   = "peer-apply"
+	ActionDriftApply  = "drift-apply-file"
+	ActionDriftSave   = "drift-save-device"
+	ActionExport      = "export-bundle"
+	ActionSettings    = "restore-settings"
+	ActionExpire      = "expire-peer"
+)
+
+// Actions lists every action, in the order shown by the viewer filter
+var Actions = []string{
+	ActionActivate, ActionDeactivate, ActionCreate, ActionEdit, ActionImport,
+	ActionDelete, ActionRestore, ActionCleanBackup, ActionPeerApply,
+	ActionDriftApply, ActionDriftSave, ActionExport, ActionSettings, ActionExpire,
+}
+
+// Results recorded in Entry.Result
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Entry is one line of the audit log
+type Entry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Source string    `json:"source"`
+	Action string    `json:"action"`
+	Tunnel string    `json:"tunnel,omitempty"`
+	Peer   string    `json:"peer,omitempty"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Logger appends entries to a JSON lines file. The file is only ever opened
+// for appending; entries are never rewritten.
+type Logger struct {
+	mu     sync.Mutex
+	path   string
+	source string
+	user   string
+}
+
+// New creates a logger writing to path. source tells the GUI and the CLI apart.
+// An empty path disables logging.
+func New(path, source string) *Logger {
+	return &Logger{
+		path:   path,
+		source: source,
+		user:   InvokingUser(),
+	}
+}
+
+// Path returns the file the logger writes to
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Record logs the outcome of action on tunnel (and peer, if any). err is the
+// result of the action; failures to write the log are only printed since
+// they must not fail the action itself.
+func (l *Logger) Record(action, tunnel, peer string, err error) {
+	e := Entry{
+		Action: action,
+		Tunnel: tunnel,
+		Peer:   peer,
+		Result: ResultOK,
+	}
+	if err != nil {
+		e.Result = ResultError
+		e.Error = err.Error()
+	}
+	if werr := l.Write(e); werr != nil {
+		log.Printf("audit log: %v", werr)
+	}
+}
+
+// Write appends e, filling in the time, user and source when empty
+func (l *Logger) Write(e Entry) error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.User == "" {
+		e.User = l.user
+	}
+	if e.Source == "" {
+		e.Source = l.source
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns all entries in the log at path, oldest first. Lines that are
+// not valid JSON are skipped. A missing file yields no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// InvokingUser returns the user who started the program. Under sudo or
+// pkexec that is the original user rather than root.
+func InvokingUser() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name
+	}
+	if uid := os.Getenv("PKEXEC_UID"); uid != "" {
+		if u, err := user.LookupId(uid); err == nil {
+			return u.Username
+		}
+		return fmt.Sprintf("uid %s", uid)
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
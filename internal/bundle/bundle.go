@@ -0,0 +1,233 @@
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/settings"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Extension is the file extension of exported bundles
+const Extension = ".wgbundle"
+
+// magic starts every bundle file and is authenticated with the payload
+const magic = "WGADMIN-BUNDLE-1"
+
+// Key derivation parameters (argon2id)
+const (
+	saltSize    = 16
+	kdfTime     = 3
+	kdfMemoryKB = 64 * 1024
+	kdfThreads  = 4
+)
+
+// ErrPassphrase is returned when a bundle cannot be decrypted
+var ErrPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// File is one config file in a bundle. Client file names are relative to the
+// client config directory (<tunnel>/<client>.conf).
+type File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// Bundle holds everything needed to move wgAdmin to another machine
+type Bundle struct {
+	Created  time.Time             `json:"created"`
+	Host     string                `json:"host"`
+	Tunnels  []File                `json:"tunnels"`
+	Clients  []File                `json:"clients"`
+	Settings *settings.AppSettings `json:"settings,omitempty"`
+}
+
+// Collect reads every tunnel config, the generated client configs in
+// clientDir and the settings into a bundle
+func Collect(ctrl controller.Controller, clientDir string, cfg *settings.AppSettings) (*Bundle, error) {
+	host, _ := os.Hostname()
+	b := &Bundle{Created: time.Now(), Host: host, Settings: cfg}
+
+	interfaces, err := ctrl.ListInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range interfaces {
+		data, err := os.ReadFile(ctrl.GetConfigPath(iface.Name))
+		if err != nil {
+			return nil, err
+		}
+		b.Tunnels = append(b.Tunnels, File{Name: iface.Name, Data: data})
+	}
+
+	paths, err := filepath.Glob(filepath.Join(clientDir, "*", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(clientDir, path)
+		if err != nil {
+			return nil, err
+		}
+		b.Clients = append(b.Clients, File{Name: filepath.ToSlash(rel), Data: data})
+	}
+	return b, nil
+}
+
+// Encrypt serializes b and seals it with a key derived from passphrase
+func Encrypt(b *Bundle, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte(magic), salt...), nonce...)
+	return aead.Seal(header, nonce, plain.Bytes(), header), nil
+}
+
+// Decrypt opens a bundle produced by Encrypt
+func Decrypt(data []byte, passphrase string) (*Bundle, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("not a wgAdmin bundle")
+	}
+	rest := data[len(magic):]
+	if len(rest) < saltSize+chacha20poly1305.NonceSizeX {
+		return nil, ErrPassphrase
+	}
+	salt := rest[:saltSize]
+	nonce := rest[saltSize : saltSize+chacha20poly1305.NonceSizeX]
+	headerLen := len(magic) + saltSize + len(nonce)
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, data[headerLen:], data[:headerLen])
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var b Bundle
+	if err := json.NewDecoder(io.LimitReader(zr, 256<<20)).Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle contents: %w", err)
+	}
+	return &b, nil
+}
+
+// newAEAD derives the bundle key from passphrase and salt
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, kdfTime, kdfMemoryKB, kdfThreads, chacha20poly1305.KeySize)
+	return chacha20poly1305.NewX(key)
+}
+
+// Item is a tunnel of a bundle checked against the local machine
+type Item struct {
+	Name     string
+	Config   *config.Config
+	Err      error // parse or validation error; the tunnel cannot be restored
+	Conflict bool  // a tunnel with the same name exists
+	Clients  int   // number of client configs for this tunnel
+}
+
+// Plan parses and validates every tunnel in b and marks name conflicts
+func Plan(b *Bundle, ctrl controller.Controller) []Item {
+	items := make([]Item, 0, len(b.Tunnels))
+	for _, f := range b.Tunnels {
+		item := Item{Name: f.Name}
+		var cfg *config.Config
+		err := fmt.Errorf("invalid tunnel name %q", f.Name)
+		if wg.ValidateName(f.Name) {
+			item.Conflict = ctrl.ConfigExists(f.Name)
+			cfg, err = controller.ParseBytes(f.Data)
+		}
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			item.Err = err
+		} else {
+			item.Config = cfg
+		}
+		for _, c := range b.Clients {
+			if strings.HasPrefix(c.Name, f.Name+"/") {
+				item.Clients++
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// RestoreTunnel writes a planned tunnel through the controller
+func RestoreTunnel(ctrl controller.Controller, item Item) error {
+	if item.Err != nil {
+		return item.Err
+	}
+	return ctrl.WriteConfig(item.Name, *item.Config)
+}
+
+// RestoreClients writes the client configs of tunnel into clientDir
+func RestoreClients(b *Bundle, tunnel, clientDir string) (int, error) {
+	written := 0
+	for _, c := range b.Clients {
+		dir, file := filepath.Split(filepath.FromSlash(c.Name))
+		if filepath.Clean(dir) != tunnel || file == "" || file != filepath.Base(file) {
+			continue
+		}
+		target := filepath.Join(clientDir, tunnel)
+		if err := os.MkdirAll(target, 0700); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(filepath.Join(target, file), c.Data, 0600); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,619 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"wgAdmin/internal/api"
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/expiry"
+	"wgAdmin/internal/metrics"
+	"wgAdmin/internal/settings"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+)
+
+// Commands lists the subcommands understood by Run. main uses it to decide
+// whether to start the GUI or run headless.
+var Commands = []string{"list", "up", "down", "import", "delete", "backups", "expire", "serve", "metrics", "help"}
+
+// IsCommand reports whether arg names a CLI subcommand
+func IsCommand(arg string) bool {
+	for _, c := range Commands {
+		if c == arg {
+			return true
+		}
+	}
+	switch arg {
+	case "-h", "--help", "-json", "--json":
+		return true
+	}
+	return false
+}
+
+// CLI runs headless subcommands against the same controller and settings as the GUI
+type CLI struct {
+	ctrl     controller.Controller
+	settings *settings.AppSettings
+	stdout   io.Writer
+	stderr   io.Writer
+	audit    *audit.Logger
+	json     bool
+}
+
+// New creates a new CLI
+func New(ctrl controller.Controller, cfg *settings.AppSettings, stdout, stderr io.Writer) *CLI {
+	return &CLI{
+		ctrl:     ctrl,
+		settings: cfg,
+		stdout:   stdout,
+		stderr:   stderr,
+		audit:    audit.New(cfg.AuditLogFile, "cli"),
+	}
+}
+
+// errNotFound marks errors caused by a missing tunnel or backup
+var errNotFound = errors.New("not found")
+
+// usageError marks errors caused by bad arguments
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// Run executes the subcommand in args and returns the process exit code
+func (c *CLI) Run(args []string) int {
+	global := flag.NewFlagSet("wgAdmin", flag.ContinueOnError)
+	global.SetOutput(c.stderr)
+	global.BoolVar(&c.json, "json", false, "print machine readable JSON output")
+	global.Usage = c.usage
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		c.usage()
+		return ExitUsage
+	}
+
+	var err error
+	switch rest[0] {
+	case "list":
+		err = c.list(rest[1:])
+	case "up":
+		err = c.toggle(rest[1:], true)
+	case "down":
+		err = c.toggle(rest[1:], false)
+	case "import":
+		err = c.importConfig(rest[1:])
+	case "delete":
+		err = c.delete(rest[1:])
+	case "backups":
+		err = c.backups(rest[1:])
+	case "expire":
+		err = c.expire(rest[1:])
+	case "serve":
+		err = c.serve(rest[1:])
+	case "metrics":
+		err = c.metrics(rest[1:])
+	case "help":
+		c.usage()
+		return ExitOK
+	default:
+		err = usageError{fmt.Sprintf("unknown command %q", rest[0])}
+	}
+
+	return c.exitCode(err)
+}
+
+func (c *CLI) exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var uerr usageError
+	switch {
+	case errors.As(err, &uerr):
+		fmt.Fprintf(c.stderr, "Error: %v\n\n", err)
+		c.usage()
+		return ExitUsage
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	}
+
+	if c.json {
+		c.writeJSON(map[string]string{"error": err.Error()})
+	} else {
+		fmt.Fprintf(c.stderr, "Error: %v\n", err)
+	}
+	if errors.Is(err, errNotFound) {
+		return ExitNotFound
+	}
+	return ExitError
+}
+
+func (c *CLI) usage() {
+	fmt.Fprint(c.stderr, `Usage: wgAdmin [--json] <command> [arguments]
+
+Without a command the graphical interface is started.
+
+Commands:
+  list                          List tunnels and their state
+  up <name>                     Activate a tunnel
+  down <name>                   Deactivate a tunnel
+  import [--name N] [--force] <file.conf>
+                                Import a tunnel config
+  delete [--no-backup] <name>   Delete a tunnel (deactivates it first)
+  backups list                  List config backups and versions
+  backups restore [--as N | --force] <file>
+                                Restore a backup as a new tunnel N, or in place
+                                (--force to overwrite an existing tunnel)
+  backups clean [--older-than D]
+                                Delete backups older than D (default 720h)
+  expire                        Remove peers whose access expired
+  serve [--socket P] [--listen ADDR] [--token T]
+                                Run the REST API in the foreground
+  metrics [--listen ADDR]       Serve Prometheus metrics in the foreground
+
+Exit codes: 0 success, 1 failure, 2 usage error, 3 tunnel or backup not found
+`)
+}
+
+func (c *CLI) writeJSON(v any) {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// result prints a status line for a successful action
+func (c *CLI) result(action, name, msg string) {
+	if c.json {
+		c.writeJSON(map[string]string{"action": action, "name": name, "status": "ok"})
+		return
+	}
+	fmt.Fprintln(c.stdout, msg)
+}
+
+// newFlagSet creates a subcommand flag set that reports errors to stderr
+func (c *CLI) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parseFlags parses subcommand flags, reporting bad flags as usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return usageError{err.Error()}
+}
+
+type interfaceJSON struct {
+	Name      string `json:"name"`
+	Active    bool   `json:"active"`
+	IP        string `json:"ip"`
+	PublicKey string `json:"public_key"`
+}
+
+func (c *CLI) list(args []string) error {
+	if len(args) != 0 {
+		return usageError{"list takes no arguments"}
+	}
+
+	interfaces, err := c.ctrl.ListInterfaces()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		out := make([]interfaceJSON, 0, len(interfaces))
+		for _, iface := range interfaces {
+			out = append(out, interfaceJSON{
+				Name:      iface.Name,
+				Active:    iface.Active,
+				IP:        iface.IP,
+				PublicKey: iface.PublicKey,
+			})
+		}
+		c.writeJSON(out)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tIP\tPUBLIC KEY")
+	for _, iface := range interfaces {
+		state := "inactive"
+		if iface.Active {
+			state = "active"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", iface.Name, state, iface.IP, iface.PublicKey)
+	}
+	return tw.Flush()
+}
+
+func (c *CLI) findInterface(name string) (*config.Interface, error) {
+	interfaces, err := c.ctrl.ListInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range interfaces {
+		if iface.Name == name {
+			return &iface, nil
+		}
+	}
+	return nil, fmt.Errorf("tunnel '%s': %w", name, errNotFound)
+}
+
+func (c *CLI) toggle(args []string, activate bool) error {
+	cmd := "down"
+	if activate {
+		cmd = "up"
+	}
+	if len(args) != 1 {
+		return usageError{fmt.Sprintf("%s requires exactly one tunnel name", cmd)}
+	}
+	name := args[0]
+
+	if _, err := c.findInterface(name); err != nil {
+		return err
+	}
+
+	err := c.ctrl.ToggleInterface(name, activate)
+	if activate {
+		c.audit.Record(audit.ActionActivate, name, "", err)
+	} else {
+		c.audit.Record(audit.ActionDeactivate, name, "", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	action := "deactivated"
+	if activate {
+		action = "activated"
+	}
+	c.result(cmd, name, fmt.Sprintf("%s %s successfully", name, action))
+	return nil
+}
+
+func (c *CLI) importConfig(args []string) error {
+	fs := c.newFlagSet("import")
+	name := fs.String("name", "", "tunnel name (defaults to the name in the config or the file name)")
+	force := fs.Bool("force", false, "overwrite an existing tunnel with the same name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError{"import requires exactly one config file"}
+	}
+
+	filePath := fs.Arg(0)
+	if filepath.Ext(filePath) != ".conf" {
+		return errors.New("Wireguard config must end with '.conf'")
+	}
+	cfg, err := controller.ReadConfig(filePath)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	tunnelName := *name
+	if tunnelName == "" {
+		tunnelName = cfg.Name
+		if tunnelName == "" || tunnelName == "Unknown" {
+			tunnelName = filepath.Base(filePath)
+		}
+	}
+	tunnelName = strings.TrimSuffix(tunnelName, ".conf")
+	if !wg.ValidateName(tunnelName) {
+		return fmt.Errorf("invalid tunnel name '%s': must be 1-15 alphanumeric characters", tunnelName)
+	}
+
+	if c.ctrl.ConfigExists(tunnelName) && !*force {
+		return fmt.Errorf("tunnel '%s' already exists (use --force to overwrite)", tunnelName)
+	}
+
+	err = c.ctrl.WriteConfig(tunnelName, *cfg)
+	c.audit.Record(audit.ActionImport, tunnelName, "", err)
+	if err != nil {
+		return fmt.Errorf("error saving: %w", err)
+	}
+	c.result("import", tunnelName, "Imported: "+tunnelName)
+	return nil
+}
+
+func (c *CLI) delete(args []string) error {
+	fs := c.newFlagSet("delete")
+	noBackup := fs.Bool("no-backup", false, "do not create a backup before deleting")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError{"delete requires exactly one tunnel name"}
+	}
+	name := fs.Arg(0)
+
+	iface, err := c.findInterface(name)
+	if err != nil {
+		return err
+	}
+	if iface.Active {
+		err := c.ctrl.ToggleInterface(name, false)
+		c.audit.Record(audit.ActionDeactivate, name, "", err)
+		if err != nil {
+			return fmt.Errorf("failed to deactivate %s: %w", name, err)
+		}
+	}
+
+	err = c.ctrl.DeleteInterface(name, !*noBackup)
+	c.audit.Record(audit.ActionDelete, name, "", err)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Deleted %s (backup created)", name)
+	if *noBackup {
+		msg = fmt.Sprintf("Deleted %s", name)
+	}
+	c.result("delete", name, msg)
+	return nil
+}
+
+type backupJSON struct {
+	Name      string    `json:"name"`
+	Filename  string    `json:"filename"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+func (c *CLI) backups(args []string) error {
+	if len(args) == 0 {
+		return usageError{"backups requires a subcommand: list, restore or clean"}
+	}
+
+	switch args[0] {
+	case "list":
+		return c.backupsList(args[1:])
+	case "restore":
+		return c.backupsRestore(args[1:])
+	case "clean":
+		return c.backupsClean(args[1:])
+	default:
+		return usageError{fmt.Sprintf("unknown backups subcommand %q", args[0])}
+	}
+}
+
+func (c *CLI) backupsList(args []string) error {
+	if len(args) != 0 {
+		return usageError{"backups list takes no arguments"}
+	}
+
+	backups, err := c.ctrl.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		out := make([]backupJSON, 0, len(backups))
+		for _, b := range backups {
+			out = append(out, backupJSON{Name: b.Name, Filename: b.Filename, Timestamp: b.Timestamp, Reason: b.Reason})
+		}
+		c.writeJSON(out)
+		return nil
+	}
+
+	if len(backups) == 0 {
+		fmt.Fprintln(c.stdout, "No backups found.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCREATED\tREASON\tFILE")
+	for _, b := range backups {
+		reason := b.Reason
+		if reason == "" {
+			reason = "backup"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Name, b.Timestamp.Format("2006-01-02 15:04:05"), reason, b.Filename)
+	}
+	return tw.Flush()
+}
+
+func (c *CLI) backupsRestore(args []string) error {
+	fs := c.newFlagSet("backups restore")
+	as := fs.String("as", "", "restore as a new tunnel with this name instead of overwriting")
+	force := fs.Bool("force", false, "overwrite the tunnel when restoring in place")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError{"backups restore requires exactly one backup file name"}
+	}
+	filename := fs.Arg(0)
+	if *as != "" && !wg.ValidateName(*as) {
+		return fmt.Errorf("invalid tunnel name '%s': must be 1-15 alphanumeric characters", *as)
+	}
+
+	backups, err := c.ctrl.ListBackups()
+	if err != nil {
+		return err
+	}
+	found := false
+	backupTunnel := ""
+	for _, b := range backups {
+		if b.Filename == filename || filepath.Base(b.Filename) == filename {
+			filename = b.Filename
+			backupTunnel = b.Name
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("backup '%s': %w", filename, errNotFound)
+	}
+
+	if *as != "" {
+		err = controller.RestoreBackupAs(c.ctrl, filename, *as)
+		c.audit.Record(audit.ActionRestore, *as, "", err)
+		if err != nil {
+			return fmt.Errorf("restore failed: %w", err)
+		}
+		c.result("restore", *as, fmt.Sprintf("Restored %s as %s", filename, *as))
+		return nil
+	}
+
+	if c.ctrl.ConfigExists(backupTunnel) && !*force {
+		return fmt.Errorf("tunnel '%s' exists (use --as NAME to restore as a new tunnel, or --force to overwrite it)", backupTunnel)
+	}
+	err = c.ctrl.RestoreBackup(filename)
+	c.audit.Record(audit.ActionRestore, backupTunnel, "", err)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	c.result("restore", filename, "Successfully restored "+filename)
+	return nil
+}
+
+func (c *CLI) backupsClean(args []string) error {
+	fs := c.newFlagSet("backups clean")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "delete backups older than this duration")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError{"backups clean takes no positional arguments"}
+	}
+	if *olderThan <= 0 {
+		return usageError{"--older-than must be positive"}
+	}
+
+	removed, err := c.ctrl.CleanOldBackups(*olderThan)
+	c.audit.Record(audit.ActionCleanBackup, "", "", err)
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+
+	if c.json {
+		c.writeJSON(map[string]any{"action": "clean", "removed": removed, "status": "ok"})
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "Removed %d old backup(s).\n", removed)
+	return nil
+}
+
+// Main is a convenience wrapper used by main to run the CLI on the process streams
+func Main(ctrl controller.Controller, cfg *settings.AppSettings, args []string) int {
+	return New(ctrl, cfg, os.Stdout, os.Stderr).Run(args)
+}
+
+type expiredJSON struct {
+	Tunnel  string    `json:"tunnel"`
+	Peer    string    `json:"peer"`
+	Expires time.Time `json:"expires"`
+}
+
+// expire runs the expired peer job once, for use from cron or a timer
+func (c *CLI) expire(args []string) error {
+	if len(args) != 0 {
+		return usageError{"expire takes no arguments"}
+	}
+
+	removed, err := expiry.Run(c.ctrl, c.audit, time.Now())
+	if c.json {
+		out := make([]expiredJSON, 0, len(removed))
+		for _, r := range removed {
+			out = append(out, expiredJSON{Tunnel: r.Tunnel, Peer: r.Peer, Expires: r.Expires})
+		}
+		if err == nil {
+			c.writeJSON(out)
+		}
+	} else {
+		for _, r := range removed {
+			fmt.Fprintf(c.stdout, "Removed %s from %s (expired %s)\n", r.Peer, r.Tunnel, r.Expires.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return err
+}
+
+// serve runs the REST API until interrupted. Flags override the API settings.
+func (c *CLI) serve(args []string) error {
+	fs := c.newFlagSet("serve")
+	socket := fs.String("socket", c.settings.APISocket, "path of the Unix socket")
+	listen := fs.String("listen", c.settings.APIAddress, "host:port to listen on instead of the socket")
+	token := fs.String("token", c.settings.APIToken, "bearer token required from clients")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError{"serve takes no arguments"}
+	}
+
+	srv := api.New(c.ctrl, c.settings.ClientConfigDir, audit.New(c.settings.AuditLogFile, "api"), *token)
+	if err := srv.Start(api.Options{Socket: *socket, Address: *listen}); err != nil {
+		return err
+	}
+	where := *socket
+	if *listen != "" {
+		where = *listen
+	}
+	fmt.Fprintf(c.stderr, "Serving API on %s\n", where)
+
+	waitForSignal()
+	return srv.Close()
+}
+
+// metrics serves /metrics until interrupted
+func (c *CLI) metrics(args []string) error {
+	fs := c.newFlagSet("metrics")
+	listen := fs.String("listen", c.settings.MetricsAddress, "host:port to serve /metrics on")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError{"metrics takes no arguments"}
+	}
+
+	exp := metrics.New(c.ctrl, time.Duration(c.settings.PeerStaleMinutes)*time.Minute)
+	if err := exp.Start(*listen); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Serving metrics on %s/metrics\n", *listen)
+
+	waitForSignal()
+	return exp.Close()
+}
+
+// waitForSignal blocks until SIGINT or SIGTERM
+func waitForSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	signal.Stop(sig)
+}
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/peermeta"
+	"wgAdmin/internal/settings"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// newTestCLI returns a fake controller holding tunnel wg0 and a function
+// running the CLI against it
+func newTestCLI(t *testing.T, peers ...config.PeerConfig) (*controller.Fake, func(args ...string) (int, string, string)) {
+	t.Helper()
+	dir := t.TempDir()
+	fake, err := controller.NewFake(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := 51820
+	cfg := config.Config{
+		Name: "wg0",
+		Interface: config.InterfaceConfig{
+			PrivateKey: key,
+			Address:    []net.IPNet{{IP: net.IPv4(10, 8, 0, 1), Mask: net.CIDRMask(24, 32)}},
+			ListenPort: &port,
+		},
+		Peers: peers,
+	}
+	if err := fake.WriteConfig("wg0", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &settings.AppSettings{AuditLogFile: filepath.Join(dir, "audit.jsonl")}
+	return fake, func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := New(fake, s, &stdout, &stderr).Run(args)
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func testPeer(t *testing.T, name, ip string) config.PeerConfig {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, n, _ := net.ParseCIDR(ip + "/32")
+	return config.PeerConfig{Name: name, PublicKey: key.PublicKey(), AllowedIPs: []net.IPNet{*n}}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"list"}, ExitOK},
+		{[]string{"--help"}, ExitOK},
+		{[]string{"up", "wg0"}, ExitOK},
+		{[]string{"import", "office.txt"}, ExitError},
+		{[]string{"import", "missing.conf"}, ExitError},
+		{[]string{}, ExitUsage},
+		{[]string{"bogus"}, ExitUsage},
+		{[]string{"up"}, ExitUsage},
+		{[]string{"list", "extra"}, ExitUsage},
+		{[]string{"backups", "clean", "--older-than", "-1h"}, ExitUsage},
+		{[]string{"up", "wg9"}, ExitNotFound},
+		{[]string{"delete", "wg9"}, ExitNotFound},
+		{[]string{"backups", "restore", "wg0_missing.conf"}, ExitNotFound},
+	}
+	for _, tt := range tests {
+		_, run := newTestCLI(t)
+		if code, _, stderr := run(tt.args...); code != tt.want {
+			t.Errorf("%q: exit code %d, want %d (stderr %q)", tt.args, code, tt.want, stderr)
+		}
+	}
+}
+
+func TestMainUsesProcessStreams(t *testing.T) {
+	fake, _ := newTestCLI(t)
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, out
+	code := Main(fake, &settings.AppSettings{AuditLogFile: filepath.Join(t.TempDir(), "audit.jsonl")}, []string{"--json", "up", "wg9"})
+	os.Stdout, os.Stderr = stdout, stderr
+
+	data, _ := os.ReadFile(out.Name())
+	if code != ExitNotFound || !bytes.Contains(data, []byte(`"error"`)) {
+		t.Errorf("exit code %d, output %q", code, data)
+	}
+}
+
+func TestJSONNotFound(t *testing.T) {
+	_, run := newTestCLI(t)
+	code, stdout, _ := run("--json", "down", "wg9")
+	var out map[string]string
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitNotFound || out["error"] == "" {
+		t.Errorf("exit code %d, output %v", code, out)
+	}
+}
+
+func TestJSONList(t *testing.T) {
+	fake, run := newTestCLI(t)
+	if code, _, stderr := run("up", "wg0"); code != ExitOK {
+		t.Fatalf("up: exit code %d: %s", code, stderr)
+	}
+	if !fake.IsActive("wg0") {
+		t.Fatal("wg0 not activated")
+	}
+
+	code, stdout, _ := run("--json", "list")
+	var out []interfaceJSON
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitOK || len(out) != 1 || out[0].Name != "wg0" || !out[0].Active {
+		t.Errorf("exit code %d, list %+v", code, out)
+	}
+}
+
+func TestJSONBackups(t *testing.T) {
+	_, run := newTestCLI(t)
+	if code, _, stderr := run("delete", "wg0"); code != ExitOK {
+		t.Fatalf("delete: exit code %d: %s", code, stderr)
+	}
+
+	code, stdout, _ := run("--json", "backups", "list")
+	var backups []backupJSON
+	if err := json.Unmarshal([]byte(stdout), &backups); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitOK || len(backups) != 1 || backups[0].Name != "wg0" {
+		t.Fatalf("exit code %d, backups %+v", code, backups)
+	}
+
+	code, stdout, _ = run("--json", "backups", "clean")
+	var clean struct {
+		Action  string `json:"action"`
+		Removed int    `json:"removed"`
+		Status  string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &clean); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitOK || clean.Status != "ok" || clean.Removed != 0 {
+		t.Errorf("exit code %d, clean %+v; want the recent backup kept", code, clean)
+	}
+
+	code, stdout, _ = run("--json", "backups", "restore", backups[0].Filename)
+	var restore map[string]string
+	if err := json.Unmarshal([]byte(stdout), &restore); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitOK || restore["action"] != "restore" || restore["status"] != "ok" {
+		t.Errorf("exit code %d, restore %v", code, restore)
+	}
+}
+
+func TestJSONExpire(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).Truncate(time.Second)
+	alice := testPeer(t, peermeta.Meta{Name: "alice", Expires: expired}.String(), "10.8.0.2")
+	bob := testPeer(t, peermeta.Meta{Name: "bob", Expires: time.Now().Add(time.Hour)}.String(), "10.8.0.3")
+	fake, run := newTestCLI(t, alice, bob)
+
+	code, stdout, _ := run("--json", "expire")
+	var out []expiredJSON
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+	if code != ExitOK || len(out) != 1 || out[0].Tunnel != "wg0" || out[0].Peer != "alice" || !out[0].Expires.Equal(expired) {
+		t.Fatalf("exit code %d, expired %+v", code, out)
+	}
+
+	cfg, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Peers) != 1 || peermeta.Name(cfg.Peers[0].Name) != "bob" {
+		t.Errorf("peers left = %+v, want bob", cfg.Peers)
+	}
+
+	// Nothing left to expire gives an empty list, not null
+	if _, stdout, _ := run("--json", "expire"); stdout != "[]\n" {
+		t.Errorf("second run output %q, want []", stdout)
+	}
+}
@@ -0,0 +1,166 @@
+package confdiff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Op tells how a row of a side-by-side diff changed
+type Op int
+
+const (
+	Equal Op = iota
+	Changed
+	Added
+	Removed
+)
+
+// Row is one line of a side-by-side diff. Left is empty for added lines and
+// Right is empty for removed lines.
+type Row struct {
+	Op    Op
+	Left  string
+	Right string
+}
+
+// secretFields are redacted by Redact
+var secretFields = []string{"PrivateKey", "PresharedKey"}
+
+// Lines renders cfg in wg-quick format, one setting per line. A nil config
+// renders as no lines, so creating a tunnel diffs against nothing.
+func Lines(cfg *config.Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	lines := []string{"[Interface]"}
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, key+" = "+value)
+		}
+	}
+
+	iface := cfg.Interface
+	add("PrivateKey", iface.PrivateKey.String())
+	add("Address", joinNets(iface.Address))
+	dns := make([]string, len(iface.DNS))
+	for i, ip := range iface.DNS {
+		dns[i] = ip.String()
+	}
+	add("DNS", strings.Join(dns, ", "))
+	if iface.ListenPort != nil {
+		add("ListenPort", fmt.Sprint(*iface.ListenPort))
+	}
+	if iface.MTU > 0 {
+		add("MTU", fmt.Sprint(iface.MTU))
+	}
+	add("Table", iface.Table)
+	add("PostUp", iface.PostUp)
+	add("PostDown", iface.PostDown)
+
+	for _, p := range cfg.Peers {
+		lines = append(lines, "", "[Peer]")
+		add("# Name", p.Name)
+		add("PublicKey", p.PublicKey.String())
+		if p.PresharedKey != nil {
+			add("PresharedKey", p.PresharedKey.String())
+		}
+		add("AllowedIPs", joinNets(p.AllowedIPs))
+		add("Endpoint", p.Endpoint)
+		if p.PersistentKeepalive > 0 {
+			add("PersistentKeepalive", fmt.Sprint(p.PersistentKeepalive))
+		}
+	}
+	return lines
+}
+
+// Redact hides the value of private and preshared key lines
+func Redact(line string) string {
+	for _, field := range secretFields {
+		if strings.HasPrefix(line, field+" = ") {
+			return field + " = (redacted)"
+		}
+	}
+	return line
+}
+
+// Compare diffs two configs line by line
+func Compare(oldCfg, newCfg *config.Config) []Row {
+	return Diff(Lines(oldCfg), Lines(newCfg))
+}
+
+// Diff aligns a and b on their longest common subsequence. Runs of removed
+// lines directly followed by added lines are paired into Changed rows so the
+// two sides line up.
+func Diff(a, b []string) []Row {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows []Row
+	var removed, added []string
+	flush := func() {
+		n := max(len(removed), len(added))
+		for k := 0; k < n; k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				rows = append(rows, Row{Op: Changed, Left: removed[k], Right: added[k]})
+			case k < len(removed):
+				rows = append(rows, Row{Op: Removed, Left: removed[k]})
+			default:
+				rows = append(rows, Row{Op: Added, Right: added[k]})
+			}
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			rows = append(rows, Row{Op: Equal, Left: a[i], Right: b[j]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, b[j])
+			j++
+		default:
+			removed = append(removed, a[i])
+			i++
+		}
+	}
+	flush()
+	return rows
+}
+
+// HasChanges reports whether any row differs
+func HasChanges(rows []Row) bool {
+	for _, r := range rows {
+		if r.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func joinNets(nets []net.IPNet) string {
+	s := make([]string, len(nets))
+	for i, n := range nets {
+		s[i] = n.String()
+	}
+	return strings.Join(s, ", ")
+}
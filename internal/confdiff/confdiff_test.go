@@ -0,0 +1,157 @@
+package confdiff
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := 51820
+	return &config.Config{
+		Name: "wg0",
+		Interface: config.InterfaceConfig{
+			PrivateKey: key,
+			Address:    []net.IPNet{{IP: net.IPv4(10, 8, 0, 1).To4(), Mask: net.CIDRMask(24, 32)}},
+			ListenPort: &port,
+		},
+		Peers: []config.PeerConfig{
+			{Name: "alice", PublicKey: key.PublicKey(), AllowedIPs: []net.IPNet{{IP: net.IPv4(10, 8, 0, 2).To4(), Mask: net.CIDRMask(32, 32)}}},
+		},
+	}
+}
+
+// changes returns the rows that differ
+func changes(rows []Row) []Row {
+	var out []Row
+	for _, r := range rows {
+		if r.Op != Equal {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config)
+		want   []Row
+	}{
+		{"unchanged", func(*config.Config) {}, nil},
+		{"listen port changed", func(cfg *config.Config) {
+			port := 51821
+			cfg.Interface.ListenPort = &port
+		}, []Row{{Op: Changed, Left: "ListenPort = 51820", Right: "ListenPort = 51821"}}},
+		{"MTU added", func(cfg *config.Config) {
+			cfg.Interface.MTU = 1420
+		}, []Row{{Op: Added, Right: "MTU = 1420"}}},
+		{"listen port removed", func(cfg *config.Config) {
+			cfg.Interface.ListenPort = nil
+		}, []Row{{Op: Removed, Left: "ListenPort = 51820"}}},
+		{"DNS added", func(cfg *config.Config) {
+			cfg.Interface.DNS = []net.IP{net.ParseIP("1.1.1.1")}
+		}, []Row{{Op: Added, Right: "DNS = 1.1.1.1"}}},
+		{"peer endpoint changed", func(cfg *config.Config) {
+			cfg.Peers[0].Endpoint = "203.0.113.5:51820"
+		}, []Row{{Op: Added, Right: "Endpoint = 203.0.113.5:51820"}}},
+		{"peer renamed", func(cfg *config.Config) {
+			cfg.Peers[0].Name = "alicia"
+		}, []Row{{Op: Changed, Left: "# Name = alice", Right: "# Name = alicia"}}},
+	}
+	for _, tt := range tests {
+		oldCfg := testConfig(t)
+		newCfg := *oldCfg
+		newCfg.Peers = append([]config.PeerConfig(nil), oldCfg.Peers...)
+		tt.change(&newCfg)
+
+		got := changes(Compare(oldCfg, &newCfg))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: rows %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: row %+v, want %+v", tt.name, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestComparePeerAdded(t *testing.T) {
+	oldCfg := testConfig(t)
+	newCfg := *oldCfg
+	bob := oldCfg.Peers[0]
+	bob.Name = "bob"
+	bob.AllowedIPs = []net.IPNet{{IP: net.IPv4(10, 8, 0, 3).To4(), Mask: net.CIDRMask(32, 32)}}
+	newCfg.Peers = append(append([]config.PeerConfig(nil), oldCfg.Peers...), bob)
+
+	rows := Compare(oldCfg, &newCfg)
+	if !HasChanges(rows) {
+		t.Fatal("no changes")
+	}
+	want := []string{"", "[Peer]", "# Name = bob", "PublicKey = " + bob.PublicKey.String(), "AllowedIPs = 10.8.0.3/32"}
+	got := changes(rows)
+	if len(got) != len(want) {
+		t.Fatalf("rows %+v, want %d added", got, len(want))
+	}
+	for i, r := range got {
+		if r.Op != Added || r.Right != want[i] {
+			t.Errorf("row %+v, want added %q", r, want[i])
+		}
+	}
+}
+
+func TestComparePeerRemoved(t *testing.T) {
+	oldCfg := testConfig(t)
+	newCfg := *oldCfg
+	newCfg.Peers = nil
+
+	got := changes(Compare(oldCfg, &newCfg))
+	if want := len(Lines(oldCfg)) - len(Lines(&newCfg)); len(got) != want {
+		t.Fatalf("rows %+v, want %d removed", got, want)
+	}
+	for _, r := range got {
+		if r.Op != Removed || r.Right != "" {
+			t.Errorf("row %+v, want removed", r)
+		}
+	}
+}
+
+func TestCompareNew(t *testing.T) {
+	cfg := testConfig(t)
+	rows := Compare(nil, cfg)
+	if len(rows) != len(Lines(cfg)) {
+		t.Fatalf("rows = %d, want one per line", len(rows))
+	}
+	for _, r := range rows {
+		if r.Op != Added || r.Left != "" {
+			t.Errorf("row %+v, want added", r)
+		}
+	}
+	if HasChanges(Compare(cfg, cfg)) {
+		t.Error("a config differs from itself")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cfg := testConfig(t)
+	psk := cfg.Interface.PrivateKey
+	cfg.Peers[0].PresharedKey = &psk
+	for _, line := range Lines(cfg) {
+		if strings.Contains(Redact(line), psk.String()) {
+			t.Errorf("Redact(%q) kept the key", line)
+		}
+	}
+	if line := "Endpoint = 203.0.113.5:51820"; Redact(line) != line {
+		t.Errorf("Redact(%q) = %q", line, Redact(line))
+	}
+}
@@ -0,0 +1,335 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Configs are read and written in memory in the wg-quick format go-wg uses,
+// with names as "# Name = ..." comments, since go-wg only reads and writes
+// files. That keeps private keys out of temporary files.
+
+// unnamed is the name go-wg gives a config without a "# Name" comment
+const unnamed = "Unknown"
+
+// publicEndpointComment keeps the endpoint client configs connect to
+const publicEndpointComment = "# PublicEndpoint = "
+
+// FormatConfig renders cfg as a .conf file named name. Disabled peers go
+// into the commented-out block at the end.
+func FormatConfig(name string, cfg config.Config) []byte {
+	var b bytes.Buffer
+	iface := cfg.Interface
+	fmt.Fprintf(&b, "[Interface]\n# Name = %s\n", name)
+	if cfg.PublicEndpoint != "" {
+		b.WriteString(publicEndpointComment + cfg.PublicEndpoint + "\n")
+	}
+	fmt.Fprintf(&b, "PrivateKey = %s\n", iface.PrivateKey)
+	if len(iface.Address) > 0 {
+		addrs := make([]string, len(iface.Address))
+		for i, a := range iface.Address {
+			addrs[i] = a.String()
+		}
+		fmt.Fprintf(&b, "Address = %s\n", strings.Join(addrs, ", "))
+	}
+	if iface.ListenPort != nil {
+		fmt.Fprintf(&b, "ListenPort = %d\n", *iface.ListenPort)
+	}
+	if len(iface.DNS) > 0 {
+		dns := make([]string, len(iface.DNS))
+		for i, ip := range iface.DNS {
+			dns[i] = ip.String()
+		}
+		fmt.Fprintf(&b, "DNS = %s\n", strings.Join(dns, ", "))
+	}
+	if iface.MTU > 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", iface.MTU)
+	}
+	for _, kv := range [][2]string{{"Table", iface.Table}, {"PostUp", iface.PostUp}, {"PostDown", iface.PostDown}} {
+		if kv[1] != "" {
+			fmt.Fprintf(&b, "%s = %s\n", kv[0], kv[1])
+		}
+	}
+
+	var disabled []config.PeerConfig
+	for _, p := range cfg.Peers {
+		meta := peermeta.Parse(p.Name)
+		if meta.Disabled {
+			// The position in the file marks the peer as disabled
+			meta.Disabled = false
+			p.Name = meta.String()
+			disabled = append(disabled, p)
+			continue
+		}
+		b.WriteString("\n")
+		formatPeer(&b, "", p)
+	}
+	if len(disabled) > 0 {
+		b.WriteString("\n" + disabledHeader + "\n")
+		for i, p := range disabled {
+			if i > 0 {
+				b.WriteString("#!\n")
+			}
+			formatPeer(&b, disabledPrefix, p)
+		}
+	}
+	return b.Bytes()
+}
+
+// formatPeer renders one [Peer] section with every line prefixed
+func formatPeer(b *bytes.Buffer, prefix string, p config.PeerConfig) {
+	line := func(format string, args ...any) {
+		b.WriteString(prefix)
+		fmt.Fprintf(b, format, args...)
+		b.WriteString("\n")
+	}
+	line("[Peer]")
+	line("# Name = %s", p.Name)
+	line("PublicKey = %s", p.PublicKey)
+	if p.PresharedKey != nil {
+		line("PresharedKey = %s", p.PresharedKey)
+	}
+	ips := make([]string, len(p.AllowedIPs))
+	for i, n := range p.AllowedIPs {
+		ips[i] = n.String()
+	}
+	line("AllowedIPs = %s", strings.Join(ips, ", "))
+	if p.Endpoint != "" {
+		line("Endpoint = %s", p.Endpoint)
+	}
+	if p.PersistentKeepalive > 0 {
+		line("PersistentKeepalive = %d", p.PersistentKeepalive)
+	}
+}
+
+// ParseBytes parses a .conf file held in memory, appending its disabled
+// peers to Peers with the disabled flag in their name metadata
+func ParseBytes(data []byte) (*config.Config, error) {
+	cfg := &config.Config{Name: unnamed}
+	var (
+		section  string
+		peer     *config.PeerConfig
+		disabled []config.PeerConfig
+		inBlock  bool
+	)
+	flush := func() {
+		if peer == nil {
+			return
+		}
+		if inBlock {
+			meta := peermeta.Parse(peer.Name)
+			meta.Disabled = true
+			peer.Name = meta.String()
+			disabled = append(disabled, *peer)
+		} else {
+			cfg.Peers = append(cfg.Peers, *peer)
+		}
+		peer = nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(line, "#!"); ok {
+			// A disabled peer, commented out
+			line = strings.TrimSpace(rest)
+			if line == "[Peer]" {
+				flush()
+				inBlock = true
+			}
+		}
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "# Name = ") || line == "# Name =":
+			name := strings.TrimSpace(strings.TrimPrefix(line, "# Name ="))
+			if peer != nil {
+				peer.Name = name
+			} else if name != "" {
+				cfg.Name = name
+			}
+			continue
+		case strings.HasPrefix(line, publicEndpointComment) && section == "interface":
+			cfg.PublicEndpoint = strings.TrimSpace(strings.TrimPrefix(line, publicEndpointComment))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.EqualFold(line, "[Interface]"):
+			flush()
+			section = "interface"
+			continue
+		case strings.EqualFold(line, "[Peer]"):
+			flush()
+			section = "peer"
+			peer = &config.PeerConfig{}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		var err error
+		switch section {
+		case "interface":
+			err = parseInterfaceKey(&cfg.Interface, key, value)
+		case "peer":
+			err = parsePeerKey(peer, key, value)
+		default:
+			err = fmt.Errorf("%s outside of a section", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	cfg.Peers = append(cfg.Peers, disabled...)
+	return cfg, nil
+}
+
+// parseInterfaceKey sets one [Interface] key; keys go-wg does not keep
+// (PreUp, SaveConfig, FwMark, ...) are skipped
+func parseInterfaceKey(iface *config.InterfaceConfig, key, value string) error {
+	var err error
+	switch key {
+	case "privatekey":
+		iface.PrivateKey, err = wgtypes.ParseKey(value)
+	case "address":
+		for _, a := range splitList(value) {
+			if !strings.Contains(a, "/") {
+				a += hostBits(a)
+			}
+			ip, n, perr := net.ParseCIDR(a)
+			if perr != nil {
+				return fmt.Errorf("address: %w", perr)
+			}
+			n.IP = ip
+			iface.Address = append(iface.Address, *n)
+		}
+	case "listenport":
+		var port int
+		if port, err = strconv.Atoi(value); err == nil {
+			iface.ListenPort = &port
+		}
+	case "dns":
+		// Search domains are not kept
+		for _, d := range splitList(value) {
+			if ip := net.ParseIP(d); ip != nil {
+				iface.DNS = append(iface.DNS, ip)
+			}
+		}
+	case "mtu":
+		iface.MTU, err = strconv.Atoi(value)
+	case "table":
+		iface.Table = value
+	case "postup":
+		iface.PostUp = joinCommands(iface.PostUp, value)
+	case "postdown":
+		iface.PostDown = joinCommands(iface.PostDown, value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// parsePeerKey sets one [Peer] key
+func parsePeerKey(p *config.PeerConfig, key, value string) error {
+	var err error
+	switch key {
+	case "publickey":
+		p.PublicKey, err = wgtypes.ParseKey(value)
+	case "presharedkey":
+		var psk wgtypes.Key
+		if psk, err = wgtypes.ParseKey(value); err == nil {
+			p.PresharedKey = &psk
+		}
+	case "allowedips":
+		for _, a := range splitList(value) {
+			if !strings.Contains(a, "/") {
+				a += hostBits(a)
+			}
+			_, n, perr := net.ParseCIDR(a)
+			if perr != nil {
+				return fmt.Errorf("allowedips: %w", perr)
+			}
+			p.AllowedIPs = append(p.AllowedIPs, *n)
+		}
+	case "endpoint":
+		p.Endpoint = value
+	case "persistentkeepalive":
+		if value != "off" {
+			p.PersistentKeepalive, err = strconv.Atoi(value)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// hostBits is the prefix length of a single address of the family of addr
+func hostBits(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "/128"
+	}
+	return "/32"
+}
+
+// joinCommands keeps repeated PostUp/PostDown lines as one shell command
+func joinCommands(prev, cmd string) string {
+	if prev == "" {
+		return cmd
+	}
+	return prev + "; " + cmd
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so a failed write leaves the old file in place
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
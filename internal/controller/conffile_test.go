@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+func TestFormatConfigRoundTrip(t *testing.T) {
+	cfg, _ := testTunnel(t)
+	psk := mustKey(t)
+	cfg.PublicEndpoint = "vpn.example.com:51820"
+	cfg.Interface.Address = append(cfg.Interface.Address, net.IPNet{IP: net.ParseIP("fd00::1"), Mask: net.CIDRMask(64, 128)})
+	cfg.Interface.DNS = []net.IP{net.ParseIP("1.1.1.1").To4()}
+	cfg.Interface.MTU = 1420
+	cfg.Interface.PostUp = "iptables -A FORWARD -i wg0 -j ACCEPT"
+	cfg.Peers[0].PresharedKey = &psk
+	cfg.Peers[1].Endpoint = "203.0.113.5:51820"
+	cfg.Peers = append(cfg.Peers,
+		config.PeerConfig{Name: "carol | disabled", PublicKey: mustKey(t).PublicKey(), AllowedIPs: mustNets(t, "10.8.0.4/32")},
+		config.PeerConfig{Name: "dave | disabled", PublicKey: mustKey(t).PublicKey(), AllowedIPs: mustNets(t, "10.8.0.5/32", "192.168.5.0/24")},
+	)
+
+	data := FormatConfig("wg0", *cfg)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Contains(line, "carol") && !strings.HasPrefix(line, disabledPrefix) {
+			t.Errorf("disabled peer outside the commented-out block: %q", line)
+		}
+	}
+
+	got, err := ParseBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// net.IP keeps no single byte form, so the configs are compared as rendered
+	if again := FormatConfig("wg0", *got); string(again) != string(data) {
+		t.Errorf("round trip changed the config\ngot:\n%s\nwant:\n%s", again, data)
+	}
+	var names []string
+	for _, p := range got.Peers {
+		names = append(names, p.Name)
+	}
+	if want := []string{"alice", "bob", "carol | disabled", "dave | disabled"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("peers = %q, want %q", names, want)
+	}
+	if got.PublicEndpoint != cfg.PublicEndpoint || got.Peers[0].PresharedKey == nil || *got.Peers[0].PresharedKey != psk {
+		t.Errorf("public endpoint or preshared key lost: %+v", got)
+	}
+}
+
+func TestParseBytesWgQuick(t *testing.T) {
+	priv, pub := mustKey(t), mustKey(t).PublicKey()
+	data := "[interface]\n" +
+		"privatekey = " + priv.String() + "\n" +
+		"Address = 10.9.0.1, fd00::1/64\n" +
+		"DNS = 9.9.9.9, corp.example\n" +
+		"SaveConfig = true\n" +
+		"PostUp = ip rule add a\n" +
+		"PostUp = ip rule add b\n" +
+		"\n" +
+		"# a comment\n" +
+		"[Peer]\n" +
+		"PublicKey = " + pub.String() + "\n" +
+		"AllowedIPs = 10.9.0.2\n" +
+		"PersistentKeepalive = off\n"
+
+	cfg, err := ParseBytes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != unnamed {
+		t.Errorf("name = %q, want %q", cfg.Name, unnamed)
+	}
+	if cfg.Interface.PrivateKey != priv {
+		t.Error("private key not parsed")
+	}
+	if len(cfg.Interface.Address) != 2 || cfg.Interface.Address[0].String() != "10.9.0.1/32" || cfg.Interface.Address[1].String() != "fd00::1/64" {
+		t.Errorf("address = %v", cfg.Interface.Address)
+	}
+	if len(cfg.Interface.DNS) != 1 {
+		t.Errorf("dns = %v, want the address only", cfg.Interface.DNS)
+	}
+	if cfg.Interface.PostUp != "ip rule add a; ip rule add b" {
+		t.Errorf("postup = %q", cfg.Interface.PostUp)
+	}
+	if len(cfg.Peers) != 1 || cfg.Peers[0].PublicKey != pub || cfg.Peers[0].AllowedIPs[0].String() != "10.9.0.2/32" {
+		t.Errorf("peers = %+v", cfg.Peers)
+	}
+
+	for _, bad := range []string{
+		"[Interface]\nPrivateKey = nope\n",
+		"[Interface]\nListenPort\n",
+		"PrivateKey = " + priv.String() + "\n",
+	} {
+		if _, err := ParseBytes([]byte(bad)); err == nil {
+			t.Errorf("ParseBytes(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestWriteConfigReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	fake, err := NewFake(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := testTunnel(t)
+	for range 2 {
+		if err := fake.WriteConfig("wg0", *cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*tmp-*")); len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+
+	// A failed rename returns the error and cleans up the temporary file
+	if err := os.Mkdir(fake.GetConfigPath("wg1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fake.GetConfigPath("wg1"), "keep"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.WriteConfig("wg1", *cfg); err == nil {
+		t.Error("WriteConfig over a directory succeeded")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*tmp-*")); len(matches) > 0 {
+		t.Errorf("temporary files left after a failure: %v", matches)
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Controller is the set of WireGuard operations used by the views and the CLI.
+// WG implements it on top of go-wg; Fake implements it on a temp directory.
+type Controller interface {
+	ListInterfaces() ([]config.Interface, error)
+	ToggleInterface(name string, activate bool) error
+	WriteConfig(name string, cfg config.Config) error
+	DeleteInterface(name string, backup bool) error
+	GetConfigPath(name string) string
+	ConfigExists(name string) bool
+	ListBackups() ([]Backup, error)
+	RestoreBackup(filename string) error
+	ReadBackup(filename string) (*config.Config, error)
+	CleanOldBackups(maxAge time.Duration) (int, error)
+	Device(name string) (*wgtypes.Device, error)
+	ConfigureDevice(name string, cfg wgtypes.Config) error
+}
+
+// Backup describes a saved copy of a tunnel config
+type Backup struct {
+	Name      string
+	Filename  string
+	Timestamp time.Time
+	Reason    string // why a version was taken; empty for plain backups
+}
+
+// WG adapts *wg.WG to the Controller interface
+type WG struct {
+	*wg.WG
+	configPath string
+}
+
+var _ Controller = (*WG)(nil)
+
+// New creates a controller for the WireGuard configs in configPath
+func New(configPath string) *WG {
+	ctrl := wg.New(configPath)
+	return &WG{WG: &ctrl, configPath: configPath}
+}
+
+// ListBackups returns the backups known to go-wg
+func (c *WG) ListBackups() ([]Backup, error) {
+	backups, err := c.WG.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Backup, 0, len(backups))
+	for _, b := range backups {
+		out = append(out, Backup{Name: b.Name, Filename: b.Filename, Timestamp: b.Timestamp})
+	}
+	return out, nil
+}
+
+// WriteConfig renders cfg in memory, keeping its disabled peers as a
+// commented-out block, and replaces the tunnel's .conf in one step
+func (c *WG) WriteConfig(name string, cfg config.Config) error {
+	return writeConfigFile(c.GetConfigPath(name), name, cfg)
+}
+
+// ReadBackup parses a backup listed by ListBackups. go-wg keeps backups in a
+// backups directory next to the configs.
+func (c *WG) ReadBackup(filename string) (*config.Config, error) {
+	path := filename
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.configPath, "backups", filename)
+	}
+	return ReadConfig(path)
+}
+
+// Device returns the live kernel state of the named interface
+func (c *WG) Device(name string) (*wgtypes.Device, error) {
+	client, err := wgctrl.New()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.Device(name)
+}
+
+// ConfigureDevice applies cfg to the running kernel device
+func (c *WG) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	client, err := wgctrl.New()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.ConfigureDevice(name, cfg)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Peer change actions reported in PeerResult
+const (
+	PeerAdded   = "added"
+	PeerUpdated = "updated"
+	PeerRemoved = "removed"
+)
+
+// PeerResult is the outcome of applying one peer change to a running device
+type PeerResult struct {
+	Peer   string
+	Action string
+	Err    error
+}
+
+func (r PeerResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s not %s: %v", r.Peer, r.Action, r.Err)
+	}
+	return fmt.Sprintf("%s %s", r.Peer, r.Action)
+}
+
+// ApplyPeerDelta applies the difference between the old and new peers of a
+// tunnel to its running device, one peer at a time so a failing peer does
+// not block the others. Unchanged peers are left alone and keep their session.
+func ApplyPeerDelta(ctrl Controller, name string, oldPeers, newPeers []config.PeerConfig) []PeerResult {
+	oldPeers, newPeers = EnabledPeers(oldPeers), EnabledPeers(newPeers)
+	old := make(map[wgtypes.Key]config.PeerConfig, len(oldPeers))
+	for _, p := range oldPeers {
+		old[p.PublicKey] = p
+	}
+
+	var results []PeerResult
+	seen := make(map[wgtypes.Key]bool, len(newPeers))
+	for _, p := range newPeers {
+		seen[p.PublicKey] = true
+
+		action := PeerAdded
+		if prev, ok := old[p.PublicKey]; ok {
+			if peersEqual(prev, p) {
+				continue
+			}
+			action = PeerUpdated
+		}
+
+		result := PeerResult{Peer: peerLabel(p.Name, p.PublicKey), Action: action}
+		pc, err := PeerDeviceConfig(p)
+		if err == nil {
+			err = ctrl.ConfigureDevice(name, wgtypes.Config{Peers: []wgtypes.PeerConfig{pc}})
+		}
+		result.Err = err
+		results = append(results, result)
+	}
+
+	for _, p := range oldPeers {
+		if seen[p.PublicKey] {
+			continue
+		}
+		err := ctrl.ConfigureDevice(name, wgtypes.Config{
+			Peers: []wgtypes.PeerConfig{{PublicKey: p.PublicKey, Remove: true}},
+		})
+		results = append(results, PeerResult{Peer: peerLabel(p.Name, p.PublicKey), Action: PeerRemoved, Err: err})
+	}
+	return results
+}
+
+// peersEqual reports whether two peers would configure the device the same way
+func peersEqual(a, b config.PeerConfig) bool {
+	var pskA, pskB wgtypes.Key
+	if a.PresharedKey != nil {
+		pskA = *a.PresharedKey
+	}
+	if b.PresharedKey != nil {
+		pskB = *b.PresharedKey
+	}
+	return pskA == pskB &&
+		a.Endpoint == b.Endpoint &&
+		a.PersistentKeepalive == b.PersistentKeepalive &&
+		joinIPNets(a.AllowedIPs) == joinIPNets(b.AllowedIPs)
+}
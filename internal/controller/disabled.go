@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"os"
+
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Disabled peers stay in the tunnel's .conf as a commented-out block at the
+// end of the file, so wg-quick and go-wg never load them:
+//
+//	# Disabled peers: kept by wgAdmin, not loaded into the device
+//	#! [Peer]
+//	#! # Name = alice
+//	#! PublicKey = ...
+//
+// In memory they are ordinary peers with the disabled flag in their name
+// metadata. ReadConfig and WriteConfig convert between the two.
+const (
+	disabledPrefix = "#! "
+	disabledHeader = "# Disabled peers: kept by wgAdmin, not loaded into the device"
+)
+
+// ReadConfig parses the config at path, appending its disabled peers to
+// Peers. Use it wherever the config is edited; config.ParseConfig only
+// returns the peers loaded into the device.
+func ReadConfig(path string) (*config.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(data)
+}
+
+// EnabledPeers returns the peers that are loaded into the device
+func EnabledPeers(peers []config.PeerConfig) []config.PeerConfig {
+	out := make([]config.PeerConfig, 0, len(peers))
+	for _, p := range peers {
+		if !peermeta.Parse(p.Name).Disabled {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// writeConfigFile renders cfg with its disabled peers and replaces the file
+// at path in one step, so a failed write loses neither the enabled nor the
+// disabled peers
+func writeConfigFile(path, name string, cfg config.Config) error {
+	return writeFileAtomic(path, FormatConfig(name, cfg))
+}
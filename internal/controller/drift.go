@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Difference is one setting that differs between a tunnel's .conf file and
+// its running device. Keys are never included, only whether they match.
+type Difference struct {
+	// Peer is the peer name (or public key); empty for interface settings
+	Peer   string
+	Field  string
+	File   string
+	Device string
+}
+
+func (d Difference) String() string {
+	if d.Peer == "" {
+		return fmt.Sprintf("%s: file %s, device %s", d.Field, d.File, d.Device)
+	}
+	return fmt.Sprintf("peer %s %s: file %s, device %s", d.Peer, d.Field, d.File, d.Device)
+}
+
+// CheckDrift compares the config file of name with its running device
+func CheckDrift(ctrl Controller, name string) ([]Difference, error) {
+	cfg, err := config.ParseConfig(ctrl.GetConfigPath(name))
+	if err != nil {
+		return nil, err
+	}
+	dev, err := ctrl.Device(name)
+	if err != nil {
+		return nil, err
+	}
+	return CompareDevice(cfg, dev), nil
+}
+
+// CompareDevice lists the differences between cfg and dev: the interface key
+// and listen port, and each peer's presence, allowed IPs, preshared key and
+// keepalive. Endpoints are left out because the kernel updates them as peers roam.
+func CompareDevice(cfg *config.Config, dev *wgtypes.Device) []Difference {
+	var diffs []Difference
+
+	if cfg.Interface.PrivateKey != dev.PrivateKey {
+		diffs = append(diffs, Difference{
+			Field:  "Public Key",
+			File:   cfg.Interface.PrivateKey.PublicKey().String(),
+			Device: dev.PublicKey.String(),
+		})
+	}
+	if cfg.Interface.ListenPort != nil && *cfg.Interface.ListenPort != dev.ListenPort {
+		diffs = append(diffs, Difference{
+			Field:  "Listen Port",
+			File:   strconv.Itoa(*cfg.Interface.ListenPort),
+			Device: strconv.Itoa(dev.ListenPort),
+		})
+	}
+
+	devPeers := make(map[wgtypes.Key]wgtypes.Peer, len(dev.Peers))
+	for _, p := range dev.Peers {
+		devPeers[p.PublicKey] = p
+	}
+	filePeers := make(map[wgtypes.Key]bool, len(cfg.Peers))
+
+	for _, fp := range cfg.Peers {
+		filePeers[fp.PublicKey] = true
+		label := peerLabel(fp.Name, fp.PublicKey)
+
+		dp, ok := devPeers[fp.PublicKey]
+		if !ok {
+			diffs = append(diffs, Difference{Peer: label, Field: "Peer", File: "present", Device: "missing"})
+			continue
+		}
+
+		fileIPs, devIPs := joinIPNets(fp.AllowedIPs), joinIPNets(dp.AllowedIPs)
+		if fileIPs != devIPs {
+			diffs = append(diffs, Difference{Peer: label, Field: "Allowed IPs", File: fileIPs, Device: devIPs})
+		}
+
+		var filePSK wgtypes.Key
+		if fp.PresharedKey != nil {
+			filePSK = *fp.PresharedKey
+		}
+		if filePSK != dp.PresharedKey {
+			devState := keyState(dp.PresharedKey)
+			if filePSK != (wgtypes.Key{}) && dp.PresharedKey != (wgtypes.Key{}) {
+				devState = "set, different key"
+			}
+			diffs = append(diffs, Difference{Peer: label, Field: "Preshared Key",
+				File: keyState(filePSK), Device: devState})
+		}
+
+		fileKeepalive := time.Duration(fp.PersistentKeepalive) * time.Second
+		if fileKeepalive != dp.PersistentKeepaliveInterval {
+			diffs = append(diffs, Difference{Peer: label, Field: "Persistent Keepalive",
+				File: fileKeepalive.String(), Device: dp.PersistentKeepaliveInterval.String()})
+		}
+	}
+
+	for _, dp := range dev.Peers {
+		if !filePeers[dp.PublicKey] {
+			diffs = append(diffs, Difference{Peer: peerLabel("", dp.PublicKey), Field: "Peer", File: "missing", Device: "present"})
+		}
+	}
+	return diffs
+}
+
+// DeviceDelta returns the device configuration that brings dev in line with
+// cfg the way wg syncconf does: the private key and listen port only when
+// they changed, and only the peers that were added, changed or removed.
+// Unchanged peers are left out and keep their session.
+func DeviceDelta(cfg *config.Config, dev *wgtypes.Device) (wgtypes.Config, error) {
+	var wgCfg wgtypes.Config
+	if cfg.Interface.PrivateKey != dev.PrivateKey {
+		privateKey := cfg.Interface.PrivateKey
+		wgCfg.PrivateKey = &privateKey
+	}
+	if cfg.Interface.ListenPort != nil && *cfg.Interface.ListenPort != dev.ListenPort {
+		port := *cfg.Interface.ListenPort
+		wgCfg.ListenPort = &port
+	}
+
+	devPeers := make(map[wgtypes.Key]config.PeerConfig, len(dev.Peers))
+	for _, dp := range dev.Peers {
+		devPeers[dp.PublicKey] = devicePeer(dp)
+	}
+	seen := make(map[wgtypes.Key]bool, len(cfg.Peers))
+	for _, p := range EnabledPeers(cfg.Peers) {
+		seen[p.PublicKey] = true
+		if current, ok := devPeers[p.PublicKey]; ok {
+			// The kernel updates endpoints as peers roam, so only the other
+			// settings decide whether a peer changed
+			current.Endpoint = p.Endpoint
+			if peersEqual(current, p) {
+				continue
+			}
+		}
+		pc, err := PeerDeviceConfig(p)
+		if err != nil {
+			return wgtypes.Config{}, err
+		}
+		wgCfg.Peers = append(wgCfg.Peers, pc)
+	}
+	for _, dp := range dev.Peers {
+		if !seen[dp.PublicKey] {
+			wgCfg.Peers = append(wgCfg.Peers, wgtypes.PeerConfig{PublicKey: dp.PublicKey, Remove: true})
+		}
+	}
+	return wgCfg, nil
+}
+
+// devicePeer converts a device peer to the form of a config file peer,
+// without its endpoint
+func devicePeer(dp wgtypes.Peer) config.PeerConfig {
+	p := config.PeerConfig{
+		PublicKey:           dp.PublicKey,
+		AllowedIPs:          dp.AllowedIPs,
+		PersistentKeepalive: int(dp.PersistentKeepaliveInterval / time.Second),
+	}
+	if dp.PresharedKey != (wgtypes.Key{}) {
+		psk := dp.PresharedKey
+		p.PresharedKey = &psk
+	}
+	return p
+}
+
+// PeerDeviceConfig converts a peer of a config file to a device peer
+// configuration that replaces its allowed IPs
+func PeerDeviceConfig(p config.PeerConfig) (wgtypes.PeerConfig, error) {
+	keepalive := time.Duration(p.PersistentKeepalive) * time.Second
+	pc := wgtypes.PeerConfig{
+		PublicKey:                   p.PublicKey,
+		PersistentKeepaliveInterval: &keepalive,
+		ReplaceAllowedIPs:           true,
+		AllowedIPs:                  p.AllowedIPs,
+	}
+	if p.PresharedKey != nil {
+		psk := *p.PresharedKey
+		pc.PresharedKey = &psk
+	} else {
+		// The zero key clears a preshared key set on the device
+		pc.PresharedKey = &wgtypes.Key{}
+	}
+	if p.Endpoint != "" {
+		addr, err := net.ResolveUDPAddr("udp", p.Endpoint)
+		if err != nil {
+			return wgtypes.PeerConfig{}, fmt.Errorf("peer %s endpoint: %w", peerLabel(p.Name, p.PublicKey), err)
+		}
+		pc.Endpoint = addr
+	}
+	return pc, nil
+}
+
+// ApplyFileToDevice configures the running device of name from its config file
+func ApplyFileToDevice(ctrl Controller, name string) error {
+	cfg, err := config.ParseConfig(ctrl.GetConfigPath(name))
+	if err != nil {
+		return err
+	}
+	dev, err := ctrl.Device(name)
+	if err != nil {
+		return err
+	}
+	wgCfg, err := DeviceDelta(cfg, dev)
+	if err != nil {
+		return err
+	}
+	return ctrl.ConfigureDevice(name, wgCfg)
+}
+
+// SaveDeviceToFile rewrites the config file of name with the keys, listen port
+// and peers of the running device. Addresses, DNS, hooks and peer names are
+// kept from the file, and disabled peers are written back unchanged.
+func SaveDeviceToFile(ctrl Controller, name string) error {
+	cfg, err := ReadConfig(ctrl.GetConfigPath(name))
+	if err != nil {
+		return err
+	}
+	dev, err := ctrl.Device(name)
+	if err != nil {
+		return err
+	}
+
+	filePeers := make(map[wgtypes.Key]config.PeerConfig, len(cfg.Peers))
+	var disabled []config.PeerConfig
+	for _, p := range cfg.Peers {
+		if peermeta.Parse(p.Name).Disabled {
+			disabled = append(disabled, p)
+			continue
+		}
+		filePeers[p.PublicKey] = p
+	}
+
+	cfg.Interface.PrivateKey = dev.PrivateKey
+	if cfg.Interface.ListenPort != nil {
+		port := dev.ListenPort
+		cfg.Interface.ListenPort = &port
+	}
+	peers := make([]config.PeerConfig, 0, len(dev.Peers))
+	for _, dp := range dev.Peers {
+		p := devicePeer(dp)
+		p.Name = filePeers[dp.PublicKey].Name
+		p.Endpoint = filePeers[dp.PublicKey].Endpoint
+		peers = append(peers, p)
+	}
+	cfg.Peers = append(peers, disabled...)
+
+	return ctrl.WriteConfig(name, *cfg)
+}
+
+func peerLabel(name string, key wgtypes.Key) string {
+	if name := peermeta.Name(name); name != "" {
+		return name
+	}
+	s := key.String()
+	if len(s) > 12 {
+		s = s[:12] + "..."
+	}
+	return s
+}
+
+func joinIPNets(nets []net.IPNet) string {
+	s := make([]string, len(nets))
+	for i, n := range nets {
+		s[i] = n.String()
+	}
+	sort.Strings(s)
+	if len(s) == 0 {
+		return "(none)"
+	}
+	return strings.Join(s, ", ")
+}
+
+func keyState(k wgtypes.Key) string {
+	if k == (wgtypes.Key{}) {
+		return "not set"
+	}
+	return "set"
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func mustKey(t *testing.T) wgtypes.Key {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustNets(t *testing.T, cidrs ...string) []net.IPNet {
+	t.Helper()
+	var nets []net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets = append(nets, *n)
+	}
+	return nets
+}
+
+// testTunnel returns a config with two peers and a device running it
+func testTunnel(t *testing.T) (*config.Config, *wgtypes.Device) {
+	t.Helper()
+	port := 51820
+	cfg := &config.Config{
+		Name: "wg0",
+		Interface: config.InterfaceConfig{
+			PrivateKey: mustKey(t),
+			Address:    mustNets(t, "10.8.0.1/24"),
+			ListenPort: &port,
+		},
+		Peers: []config.PeerConfig{
+			{Name: "alice", PublicKey: mustKey(t).PublicKey(), AllowedIPs: mustNets(t, "10.8.0.2/32"), PersistentKeepalive: 25},
+			{Name: "bob", PublicKey: mustKey(t).PublicKey(), AllowedIPs: mustNets(t, "10.8.0.3/32")},
+		},
+	}
+	dev := &wgtypes.Device{
+		Name:       "wg0",
+		PrivateKey: cfg.Interface.PrivateKey,
+		PublicKey:  cfg.Interface.PrivateKey.PublicKey(),
+		ListenPort: port,
+	}
+	for _, p := range cfg.Peers {
+		dev.Peers = append(dev.Peers, wgtypes.Peer{
+			PublicKey:                   p.PublicKey,
+			AllowedIPs:                  p.AllowedIPs,
+			PersistentKeepaliveInterval: time.Duration(p.PersistentKeepalive) * time.Second,
+			LastHandshakeTime:           time.Now(),
+		})
+	}
+	return cfg, dev
+}
+
+func TestCompareDevice(t *testing.T) {
+	// Each change is applied to a fresh tunnel and returns the differences
+	// it should cause
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config, dev *wgtypes.Device) []Difference
+	}{
+		{"in sync", func(*config.Config, *wgtypes.Device) []Difference {
+			return nil
+		}},
+		{"missing peer", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			dev.Peers = dev.Peers[:1]
+			return []Difference{{Peer: "bob", Field: "Peer", File: "present", Device: "missing"}}
+		}},
+		{"extra peer", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			cfg.Peers = cfg.Peers[:1]
+			return []Difference{{Peer: peerLabel("", dev.Peers[1].PublicKey), Field: "Peer", File: "missing", Device: "present"}}
+		}},
+		{"changed allowed IPs", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			dev.Peers[0].AllowedIPs = mustNets(t, "192.168.1.0/24", "10.8.0.2/32")
+			return []Difference{{Peer: "alice", Field: "Allowed IPs", File: "10.8.0.2/32", Device: "10.8.0.2/32, 192.168.1.0/24"}}
+		}},
+		{"changed key", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			key := mustKey(t)
+			dev.PrivateKey, dev.PublicKey = key, key.PublicKey()
+			return []Difference{{Field: "Public Key", File: cfg.Interface.PrivateKey.PublicKey().String(), Device: key.PublicKey().String()}}
+		}},
+		{"changed port", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			dev.ListenPort = 51821
+			return []Difference{{Field: "Listen Port", File: "51820", Device: "51821"}}
+		}},
+		{"roamed endpoint", func(cfg *config.Config, dev *wgtypes.Device) []Difference {
+			dev.Peers[0].Endpoint = &net.UDPAddr{IP: net.ParseIP("203.0.113.7"), Port: 40000}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		cfg, dev := testTunnel(t)
+		want := tt.change(cfg, dev)
+		got := CompareDevice(cfg, dev)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestDeviceDelta(t *testing.T) {
+	cfg, dev := testTunnel(t)
+	if delta, err := DeviceDelta(cfg, dev); err != nil || delta.PrivateKey != nil || delta.ListenPort != nil || len(delta.Peers) != 0 {
+		t.Errorf("delta of a device in sync = %+v, %v; want empty", delta, err)
+	}
+
+	// Change alice, remove bob and add carol; the key and port stay
+	cfg.Peers[0].AllowedIPs = mustNets(t, "10.8.0.2/32", "192.168.1.0/24")
+	bob := cfg.Peers[1].PublicKey
+	carol := mustKey(t).PublicKey()
+	cfg.Peers[1] = config.PeerConfig{Name: "carol", PublicKey: carol, AllowedIPs: mustNets(t, "10.8.0.4/32")}
+	delta, err := DeviceDelta(cfg, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta.ReplacePeers || delta.PrivateKey != nil || delta.ListenPort != nil {
+		t.Errorf("delta replaces the peers or resets the interface: %+v", delta)
+	}
+	got := make(map[wgtypes.Key]bool)
+	for _, pc := range delta.Peers {
+		got[pc.PublicKey] = pc.Remove
+	}
+	want := map[wgtypes.Key]bool{cfg.Peers[0].PublicKey: false, carol: false, bob: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delta peers = %v, want alice and carol updated and bob removed", got)
+	}
+
+	port := 51821
+	cfg.Interface.ListenPort = &port
+	if delta, _ := DeviceDelta(cfg, dev); delta.ListenPort == nil || *delta.ListenPort != port || delta.PrivateKey != nil {
+		t.Errorf("delta after a port change = %+v, want only the port", delta)
+	}
+}
+
+func TestApplyFileToDeviceKeepsUnchangedPeers(t *testing.T) {
+	fake, err := NewFake(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, dev := testTunnel(t)
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+	fake.SetDevice("wg0", dev)
+	handshake := dev.Peers[0].LastHandshakeTime
+
+	// Only bob changes in the file
+	cfg.Peers[1].AllowedIPs = mustNets(t, "10.8.0.3/32", "10.9.0.0/24")
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyFileToDevice(fake, "wg0"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := fake.Device("wg0")
+	if len(got.Peers) != 2 || !got.Peers[0].LastHandshakeTime.Equal(handshake) {
+		t.Errorf("alice lost her session: %+v", got.Peers)
+	}
+	if diffs := CompareDevice(cfg, got); len(diffs) != 0 {
+		t.Errorf("device differs from the file after apply: %v", diffs)
+	}
+}
+
+func TestSaveDeviceToFileKeepsDisabledPeers(t *testing.T) {
+	fake, err := NewFake(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, dev := testTunnel(t)
+	carol := config.PeerConfig{Name: "carol | disabled", PublicKey: mustKey(t).PublicKey(), AllowedIPs: mustNets(t, "10.8.0.4/32")}
+	cfg.Peers = append(cfg.Peers, carol)
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+	dev.Peers = dev.Peers[:1]
+	fake.SetDevice("wg0", dev)
+
+	if err := SaveDeviceToFile(fake, "wg0"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range saved.Peers {
+		names = append(names, p.Name)
+	}
+	if want := []string{"alice", "carol | disabled"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("saved peers = %q, want %q", names, want)
+	}
+}
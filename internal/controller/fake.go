@@ -0,0 +1,307 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// backupTimeFormat is used in fake backup file names: <name>_<timestamp>.conf
+const backupTimeFormat = "20060102-150405.000000000"
+
+// Fake is a Controller that keeps configs in a directory and interface state
+// in memory. It needs neither root nor the WireGuard kernel module.
+type Fake struct {
+	mu        sync.Mutex
+	dir       string
+	backupDir string
+	active    map[string]bool
+	devices   map[string]*wgtypes.Device
+
+	// ToggleErr, when set, is returned by ToggleInterface
+	ToggleErr error
+}
+
+var _ Controller = (*Fake)(nil)
+
+// NewFake creates a fake controller storing configs in dir
+func NewFake(dir string) (*Fake, error) {
+	backupDir := filepath.Join(dir, "backups")
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return nil, err
+	}
+	return &Fake{
+		dir:       dir,
+		backupDir: backupDir,
+		active:    make(map[string]bool),
+		devices:   make(map[string]*wgtypes.Device),
+	}, nil
+}
+
+// IsActive reports whether name has been activated
+func (f *Fake) IsActive(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active[name]
+}
+
+// ListInterfaces returns one interface per config file
+func (f *Fake) ListInterfaces() ([]config.Interface, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	interfaces := make([]config.Interface, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".conf")
+		iface := config.Interface{Name: name, Active: f.active[name], IP: "unknown"}
+		if cfg, err := config.ParseConfig(path); err == nil {
+			if len(cfg.Interface.Address) > 0 {
+				iface.IP = cfg.Interface.Address[0].IP.String()
+			}
+			iface.PublicKey = cfg.Interface.PrivateKey.PublicKey().String()
+		}
+		interfaces = append(interfaces, iface)
+	}
+	return interfaces, nil
+}
+
+// ToggleInterface records the interface as active or inactive. Activating
+// loads the config file into the device state like wg-quick would.
+func (f *Fake) ToggleInterface(name string, activate bool) error {
+	if f.ToggleErr != nil {
+		return f.ToggleErr
+	}
+	if !f.ConfigExists(name) {
+		return fmt.Errorf("interface %s not found", name)
+	}
+	var dev *wgtypes.Device
+	if activate {
+		cfg, err := config.ParseConfig(f.GetConfigPath(name))
+		if err != nil {
+			return err
+		}
+		dev = &wgtypes.Device{Name: name, Type: wgtypes.LinuxKernel}
+		wgCfg, err := DeviceDelta(cfg, dev)
+		if err != nil {
+			return err
+		}
+		applyDeviceConfig(dev, wgCfg)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active[name] = activate
+	if activate {
+		f.devices[name] = dev
+	} else {
+		delete(f.devices, name)
+	}
+	return nil
+}
+
+// WriteConfig writes cfg to <dir>/<name>.conf
+func (f *Fake) WriteConfig(name string, cfg config.Config) error {
+	return writeConfigFile(f.GetConfigPath(name), name, cfg)
+}
+
+// DeleteInterface removes the config, optionally copying it to the backup directory first
+func (f *Fake) DeleteInterface(name string, backup bool) error {
+	path := f.GetConfigPath(name)
+	if backup {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		filename := fmt.Sprintf("%s_%s.conf", name, time.Now().Format(backupTimeFormat))
+		if err := os.WriteFile(filepath.Join(f.backupDir, filename), data, 0600); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	delete(f.active, name)
+	delete(f.devices, name)
+	f.mu.Unlock()
+	return os.Remove(path)
+}
+
+// GetConfigPath returns the path of the config file for name
+func (f *Fake) GetConfigPath(name string) string {
+	return filepath.Join(f.dir, name+".conf")
+}
+
+// ConfigExists reports whether a config file exists for name
+func (f *Fake) ConfigExists(name string) bool {
+	_, err := os.Stat(f.GetConfigPath(name))
+	return err == nil
+}
+
+// ListBackups returns the backups in the backup directory, newest first
+func (f *Fake) ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(f.backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		base := strings.TrimSuffix(e.Name(), ".conf")
+		i := strings.LastIndex(base, "_")
+		if e.IsDir() || i < 0 {
+			continue
+		}
+		ts, err := time.ParseInLocation(backupTimeFormat, base[i+1:], time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Name: base[:i], Filename: e.Name(), Timestamp: ts})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+	return backups, nil
+}
+
+// RestoreBackup copies a backup back to <dir>/<name>.conf
+func (f *Fake) RestoreBackup(filename string) error {
+	backups, err := f.ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if b.Filename != filename {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(f.backupDir, filename))
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(f.GetConfigPath(b.Name), data, 0600)
+	}
+	return fmt.Errorf("backup %s not found", filename)
+}
+
+// ReadBackup parses a backup in the backup directory
+func (f *Fake) ReadBackup(filename string) (*config.Config, error) {
+	return ReadConfig(filepath.Join(f.backupDir, filepath.Base(filename)))
+}
+
+// CleanOldBackups removes backups older than maxAge
+func (f *Fake) CleanOldBackups(maxAge time.Duration) (int, error) {
+	backups, err := f.ListBackups()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, b := range backups {
+		if time.Since(b.Timestamp) <= maxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(f.backupDir, b.Filename)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// SetDevice sets the device state returned by Device for name
+func (f *Fake) SetDevice(name string, dev *wgtypes.Device) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices[name] = dev
+}
+
+// Device returns the state set with SetDevice, or an empty device for active interfaces
+func (f *Fake) Device(name string) (*wgtypes.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if dev, ok := f.devices[name]; ok {
+		return dev, nil
+	}
+	if !f.active[name] {
+		return nil, fmt.Errorf("device %s not found", name)
+	}
+	return &wgtypes.Device{Name: name, Type: wgtypes.LinuxKernel}, nil
+}
+
+// ConfigureDevice applies cfg to the device state of an active interface
+func (f *Fake) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dev, ok := f.devices[name]
+	if !ok {
+		if !f.active[name] {
+			return fmt.Errorf("device %s not found", name)
+		}
+		dev = &wgtypes.Device{Name: name, Type: wgtypes.LinuxKernel}
+		f.devices[name] = dev
+	}
+	applyDeviceConfig(dev, cfg)
+	return nil
+}
+
+// applyDeviceConfig mimics how the kernel applies a configuration to a device
+func applyDeviceConfig(dev *wgtypes.Device, cfg wgtypes.Config) {
+	if cfg.PrivateKey != nil {
+		dev.PrivateKey = *cfg.PrivateKey
+		dev.PublicKey = cfg.PrivateKey.PublicKey()
+	}
+	if cfg.ListenPort != nil {
+		dev.ListenPort = *cfg.ListenPort
+	}
+	if cfg.ReplacePeers {
+		dev.Peers = nil
+	}
+
+	for _, pc := range cfg.Peers {
+		i := -1
+		for j := range dev.Peers {
+			if dev.Peers[j].PublicKey == pc.PublicKey {
+				i = j
+				break
+			}
+		}
+		if pc.Remove {
+			if i >= 0 {
+				dev.Peers = append(dev.Peers[:i], dev.Peers[i+1:]...)
+			}
+			continue
+		}
+		if i < 0 {
+			if pc.UpdateOnly {
+				continue
+			}
+			dev.Peers = append(dev.Peers, wgtypes.Peer{PublicKey: pc.PublicKey})
+			i = len(dev.Peers) - 1
+		}
+
+		peer := &dev.Peers[i]
+		if pc.PresharedKey != nil {
+			peer.PresharedKey = *pc.PresharedKey
+		}
+		if pc.Endpoint != nil {
+			peer.Endpoint = pc.Endpoint
+		}
+		if pc.PersistentKeepaliveInterval != nil {
+			peer.PersistentKeepaliveInterval = *pc.PersistentKeepaliveInterval
+		}
+		if pc.ReplaceAllowedIPs {
+			peer.AllowedIPs = nil
+		}
+		peer.AllowedIPs = append(peer.AllowedIPs, pc.AllowedIPs...)
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"time"
+
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// PeerStats is the live state of one peer as reported by the kernel
+type PeerStats struct {
+	Name          string
+	PublicKey     string
+	Endpoint      string
+	AllowedIPs    []string
+	LastHandshake time.Time
+	RxBytes       int64
+	TxBytes       int64
+}
+
+// IsStale reports whether the peer has not completed a handshake within maxAge
+func (p PeerStats) IsStale(maxAge time.Duration) bool {
+	return p.LastHandshake.IsZero() || time.Since(p.LastHandshake) > maxAge
+}
+
+// GetPeerStats reads the device state of name and labels each peer with the
+// Name from the tunnel's .conf file.
+func GetPeerStats(ctrl Controller, name string) ([]PeerStats, error) {
+	dev, err := ctrl.Device(name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[wgtypes.Key]string)
+	if cfg, err := config.ParseConfig(ctrl.GetConfigPath(name)); err == nil {
+		for _, p := range cfg.Peers {
+			names[p.PublicKey] = peermeta.Name(p.Name)
+		}
+	}
+
+	stats := make([]PeerStats, 0, len(dev.Peers))
+	for _, p := range dev.Peers {
+		s := PeerStats{
+			Name:          names[p.PublicKey],
+			PublicKey:     p.PublicKey.String(),
+			LastHandshake: p.LastHandshakeTime,
+			RxBytes:       p.ReceiveBytes,
+			TxBytes:       p.TransmitBytes,
+		}
+		if p.Endpoint != nil {
+			s.Endpoint = p.Endpoint.String()
+		}
+		for _, ip := range p.AllowedIPs {
+			s.AllowedIPs = append(s.AllowedIPs, ip.String())
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
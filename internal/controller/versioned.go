@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"wgAdmin/internal/history"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Versioned wraps a Controller and keeps a history.Store version of a config
+// before and after every write, delete and restore. Versions are listed,
+// read and restored through the backup methods alongside the wrapped
+// controller's own backups.
+type Versioned struct {
+	Controller
+	history *history.Store
+}
+
+var _ Controller = (*Versioned)(nil)
+
+// NewVersioned wraps ctrl with a version history kept in store
+func NewVersioned(ctrl Controller, store *history.Store) *Versioned {
+	return &Versioned{Controller: ctrl, history: store}
+}
+
+// Open creates a versioned controller for the configs in configPath, keeping
+// the history next to them
+func Open(configPath string, policy history.Policy) *Versioned {
+	return NewVersioned(New(configPath), history.NewStore(history.DefaultDir(configPath), policy))
+}
+
+// History returns the version store
+func (v *Versioned) History() *history.Store {
+	return v.history
+}
+
+// WriteConfig saves the current file as a version, writes cfg and saves the
+// result as the newest version
+func (v *Versioned) WriteConfig(name string, cfg config.Config) error {
+	if err := v.snapshot(name, history.ReasonBefore); err != nil {
+		return err
+	}
+	if err := v.Controller.WriteConfig(name, cfg); err != nil {
+		return err
+	}
+	return v.snapshot(name, history.ReasonWrite)
+}
+
+// DeleteInterface saves the config as a version before deleting it
+func (v *Versioned) DeleteInterface(name string, backup bool) error {
+	if err := v.snapshot(name, history.ReasonDelete); err != nil {
+		return err
+	}
+	return v.Controller.DeleteInterface(name, backup)
+}
+
+// ListBackups returns the wrapped controller's backups and every version,
+// newest first
+func (v *Versioned) ListBackups() ([]Backup, error) {
+	backups, err := v.Controller.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	versions, err := v.history.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, ver := range versions {
+		backups = append(backups, Backup{Name: ver.Tunnel, Filename: ver.ID, Timestamp: ver.Timestamp, Reason: ver.Reason})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+	return backups, nil
+}
+
+// RestoreBackup writes a version or backup back in place, saving the file it
+// replaces first
+func (v *Versioned) RestoreBackup(filename string) error {
+	if !v.history.Has(filename) {
+		name, err := v.backupName(filename)
+		if err != nil {
+			return err
+		}
+		if err := v.snapshot(name, history.ReasonBefore); err != nil {
+			return err
+		}
+		if err := v.Controller.RestoreBackup(filename); err != nil {
+			return err
+		}
+		return v.snapshot(name, history.ReasonRestore)
+	}
+
+	name, data, err := v.readVersion(filename)
+	if err != nil {
+		return err
+	}
+	if err := v.snapshot(name, history.ReasonBefore); err != nil {
+		return err
+	}
+	if err := os.WriteFile(v.GetConfigPath(name), data, 0600); err != nil {
+		return err
+	}
+	return v.snapshot(name, history.ReasonRestore)
+}
+
+// ReadBackup parses a version or a backup of the wrapped controller
+func (v *Versioned) ReadBackup(filename string) (*config.Config, error) {
+	if !v.history.Has(filename) {
+		return v.Controller.ReadBackup(filename)
+	}
+	path, err := v.history.Path(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadConfig(path)
+}
+
+// CleanOldBackups removes backups and versions older than maxAge. The
+// newest versions of each tunnel kept by the policy's KeepLast stay.
+func (v *Versioned) CleanOldBackups(maxAge time.Duration) (int, error) {
+	removed, err := v.Controller.CleanOldBackups(maxAge)
+	if err != nil {
+		return removed, err
+	}
+	n, err := v.history.RemoveOlderThan(maxAge)
+	return removed + n, err
+}
+
+// ApplyRetention prunes the history with its policy and removes backups of
+// the wrapped controller older than the daily retention window. Without a
+// window the backups are all kept, since they are needed to undo deletions.
+func (v *Versioned) ApplyRetention() (int, error) {
+	removed, err := v.history.PruneAll()
+	if err != nil {
+		return removed, err
+	}
+	days := v.history.Policy().KeepDailyDays
+	if days <= 0 {
+		return removed, nil
+	}
+	n, err := v.Controller.CleanOldBackups(time.Duration(days) * 24 * time.Hour)
+	return removed + n, err
+}
+
+// snapshot stores the current file of name, if there is one
+func (v *Versioned) snapshot(name, reason string) error {
+	data, err := os.ReadFile(v.GetConfigPath(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.history.Snapshot(name, data, reason); err != nil {
+		return fmt.Errorf("saving version of %s: %w", name, err)
+	}
+	return nil
+}
+
+func (v *Versioned) readVersion(id string) (string, []byte, error) {
+	versions, err := v.history.All()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, ver := range versions {
+		if ver.ID == id {
+			data, err := v.history.Read(id)
+			return ver.Tunnel, data, err
+		}
+	}
+	return "", nil, fmt.Errorf("version %s not found", id)
+}
+
+func (v *Versioned) backupName(filename string) (string, error) {
+	backups, err := v.Controller.ListBackups()
+	if err != nil {
+		return "", err
+	}
+	for _, b := range backups {
+		if b.Filename == filename {
+			return b.Name, nil
+		}
+	}
+	return "", fmt.Errorf("backup %s not found", filename)
+}
+
+// RestoreBackupAs writes a backup to a new tunnel name. It refuses to
+// overwrite an existing config.
+func RestoreBackupAs(ctrl Controller, filename, name string) error {
+	if ctrl.ConfigExists(name) {
+		return fmt.Errorf("a tunnel named %s already exists", name)
+	}
+	cfg, err := ctrl.ReadBackup(filename)
+	if err != nil {
+		return err
+	}
+	cfg.Name = name
+	return ctrl.WriteConfig(name, *cfg)
+}
@@ -0,0 +1,74 @@
+package expiry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Removed is a peer taken out of a tunnel because its access expired
+type Removed struct {
+	Tunnel  string
+	Peer    string
+	Expires time.Time
+}
+
+// Run removes every peer whose expiry is at or before now. Each changed
+// tunnel is written once through ctrl, so a versioned controller keeps the
+// previous config in its history; active tunnels get the change applied
+// live. Every removal is recorded in auditLog.
+func Run(ctrl controller.Controller, auditLog *audit.Logger, now time.Time) ([]Removed, error) {
+	interfaces, err := ctrl.ListInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Removed
+	var errs []error
+	for _, iface := range interfaces {
+		cfg, err := controller.ReadConfig(ctrl.GetConfigPath(iface.Name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+			continue
+		}
+
+		var keep []config.PeerConfig
+		var expired []Removed
+		for _, p := range cfg.Peers {
+			meta := peermeta.Parse(p.Name)
+			if meta.Expired(now) {
+				expired = append(expired, Removed{Tunnel: iface.Name, Peer: meta.Name, Expires: meta.Expires})
+				continue
+			}
+			keep = append(keep, p)
+		}
+		if len(expired) == 0 {
+			continue
+		}
+
+		updated := *cfg
+		updated.Peers = keep
+		err = ctrl.WriteConfig(iface.Name, updated)
+		for _, r := range expired {
+			auditLog.Record(audit.ActionExpire, r.Tunnel, r.Peer, err)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+			continue
+		}
+		removed = append(removed, expired...)
+
+		if iface.Active {
+			for _, res := range controller.ApplyPeerDelta(ctrl, iface.Name, cfg.Peers, updated.Peers) {
+				auditLog.Record(audit.ActionPeerApply, iface.Name, res.Peer, res.Err)
+			}
+		}
+	}
+	return removed, errors.Join(errs...)
+}
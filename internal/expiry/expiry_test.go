@@ -0,0 +1,111 @@
+package expiry
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/history"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func peer(t *testing.T, name, ip string, expires time.Time) config.PeerConfig {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, allowed, err := net.ParseCIDR(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.PeerConfig{
+		Name:       peermeta.Meta{Name: name, Expires: expires}.String(),
+		PublicKey:  key.PublicKey(),
+		AllowedIPs: []net.IPNet{*allowed},
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	fake, err := controller.NewFake(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := history.NewStore(history.DefaultDir(dir), history.Policy{KeepLast: 10})
+	ctrl := controller.NewVersioned(fake, store)
+	auditPath := filepath.Join(dir, "audit.jsonl")
+	auditLog := audit.New(auditPath, "test")
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	key, _ := wgtypes.GeneratePrivateKey()
+	_, addr, _ := net.ParseCIDR("10.8.0.1/24")
+	cfg := config.Config{
+		Interface: config.InterfaceConfig{PrivateKey: key, Address: []net.IPNet{*addr}},
+		Peers: []config.PeerConfig{
+			peer(t, "contractor", "10.8.0.2/32", now.Add(-time.Hour)),
+			peer(t, "laptop", "10.8.0.3/32", time.Time{}),
+			peer(t, "intern", "10.8.0.4/32", now.Add(time.Hour)),
+		},
+	}
+	if err := ctrl.WriteConfig("wg0", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctrl.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+	before := len(mustVersions(t, store))
+
+	removed, err := Run(ctrl, auditLog, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Tunnel != "wg0" || removed[0].Peer != "contractor" {
+		t.Fatalf("removed = %+v, want wg0/contractor", removed)
+	}
+
+	saved, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range saved.Peers {
+		names = append(names, peermeta.Name(p.Name))
+	}
+	if strings.Join(names, ",") != "laptop,intern" {
+		t.Errorf("remaining peers = %v", names)
+	}
+	if after := len(mustVersions(t, store)); after <= before {
+		t.Errorf("no history version was taken before removing the peer")
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"action":"expire-peer"`) || !strings.Contains(string(data), `"peer":"contractor"`) {
+		t.Errorf("audit log does not record the removal:\n%s", data)
+	}
+
+	// Nothing left to do on the next run
+	if removed, err := Run(ctrl, auditLog, now); err != nil || len(removed) != 0 {
+		t.Errorf("second run = %v, %v", removed, err)
+	}
+}
+
+func mustVersions(t *testing.T, store *history.Store) []history.Version {
+	t.Helper()
+	versions, err := store.Versions("wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return versions
+}
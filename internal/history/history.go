@@ -0,0 +1,275 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeFormat is used in version file names: <tunnel>/<timestamp>_<reason>.conf
+const timeFormat = "20060102-150405.000000000"
+
+// Reasons recorded with each version
+const (
+	ReasonWrite   = "write"
+	ReasonBefore  = "before-write"
+	ReasonDelete  = "delete"
+	ReasonRestore = "restore"
+)
+
+// Version is one saved copy of a tunnel config
+type Version struct {
+	Tunnel    string
+	ID        string // path relative to the store directory
+	Timestamp time.Time
+	Reason    string
+}
+
+// Policy decides which versions Prune keeps: the newest KeepLast of each
+// tunnel, plus the newest version of every day within the last KeepDailyDays days
+type Policy struct {
+	KeepLast      int
+	KeepDailyDays int
+}
+
+// Store keeps config versions in one directory per tunnel
+type Store struct {
+	dir    string
+	policy Policy
+}
+
+// DefaultDir returns the history directory for the configs in configPath
+func DefaultDir(configPath string) string {
+	return filepath.Join(configPath, ".history")
+}
+
+// NewStore creates a store in dir pruned with policy. The newest version of
+// every tunnel is always kept, whatever policy.KeepLast says.
+func NewStore(dir string, policy Policy) *Store {
+	policy.KeepLast = max(policy.KeepLast, 1)
+	return &Store{dir: dir, policy: policy}
+}
+
+// Policy returns the retention policy of the store
+func (s *Store) Policy() Policy {
+	return s.policy
+}
+
+// Snapshot saves data as a new version of tunnel unless it equals the latest
+// version, then prunes the tunnel's history
+func (s *Store) Snapshot(tunnel string, data []byte, reason string) error {
+	versions, err := s.Versions(tunnel)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		latest, err := s.Read(versions[0].ID)
+		if err == nil && bytes.Equal(latest, data) {
+			return nil
+		}
+	}
+
+	dir := filepath.Join(s.dir, tunnel)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.conf", time.Now().Format(timeFormat), reason)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return err
+	}
+	_, err = s.Prune(tunnel)
+	return err
+}
+
+// Versions returns the versions of tunnel, newest first
+func (s *Store) Versions(tunnel string) ([]Version, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, tunnel))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, e := range entries {
+		base := strings.TrimSuffix(e.Name(), ".conf")
+		ts, reason, ok := strings.Cut(base, "_")
+		if e.IsDir() || !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(timeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{
+			Tunnel:    tunnel,
+			ID:        filepath.Join(tunnel, e.Name()),
+			Timestamp: t,
+			Reason:    reason,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Timestamp.After(versions[j].Timestamp)
+	})
+	return versions, nil
+}
+
+// All returns the versions of every tunnel, newest first
+func (s *Store) All() ([]Version, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Version
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		versions, err := s.Versions(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, versions...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Timestamp.After(all[j].Timestamp)
+	})
+	return all, nil
+}
+
+// Has reports whether id names a version in the store
+func (s *Store) Has(id string) bool {
+	path, err := s.path(id)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Read returns the content of a version
+func (s *Store) Read(id string) ([]byte, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Path returns the file of a version
+func (s *Store) Path(id string) (string, error) {
+	return s.path(id)
+}
+
+// Prune removes the versions of tunnel not kept by the policy
+func (s *Store) Prune(tunnel string) (int, error) {
+	versions, err := s.Versions(tunnel)
+	if err != nil {
+		return 0, err
+	}
+
+	keep := s.policy.keep(versions, time.Now())
+	removed := 0
+	for i, v := range versions {
+		if keep[i] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, v.ID)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// PruneAll applies the policy to every tunnel
+func (s *Store) PruneAll() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		n, err := s.Prune(e.Name())
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// RemoveOlderThan removes the versions older than maxAge, except the newest
+// KeepLast of each tunnel, which stay whatever their age
+func (s *Store) RemoveOlderThan(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	keepLast := max(s.policy.KeepLast, 1)
+	removed := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		versions, err := s.Versions(e.Name())
+		if err != nil {
+			return removed, err
+		}
+		for i, v := range versions {
+			if i < keepLast || time.Since(v.Timestamp) <= maxAge {
+				continue
+			}
+			if err := os.Remove(filepath.Join(s.dir, v.ID)); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// keep marks which of versions (newest first) the policy retains
+func (p Policy) keep(versions []Version, now time.Time) []bool {
+	keep := make([]bool, len(versions))
+	cutoff := now.AddDate(0, 0, -p.KeepDailyDays)
+	days := make(map[string]bool)
+	for i, v := range versions {
+		if i < p.KeepLast {
+			keep[i] = true
+		}
+		day := v.Timestamp.Format("2006-01-02")
+		if v.Timestamp.After(cutoff) && !days[day] {
+			days[day] = true
+			keep[i] = true
+		}
+	}
+	return keep
+}
+
+// path resolves id inside the store, rejecting ids that escape it
+func (s *Store) path(id string) (string, error) {
+	clean := filepath.Clean(id)
+	if filepath.IsAbs(clean) || clean == "." || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid version %q", id)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
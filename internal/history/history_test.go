@@ -0,0 +1,110 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeVersion stores a version of tunnel taken at t
+func writeVersion(t *testing.T, dir, tunnel string, at time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, tunnel), 0700); err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("%s_%s.conf", at.Format(timeFormat), ReasonWrite)
+	if err := os.WriteFile(filepath.Join(dir, tunnel, name), []byte(at.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func daysAgo(n int) time.Time {
+	return time.Now().AddDate(0, 0, -n)
+}
+
+func TestSnapshotSkipsUnchanged(t *testing.T) {
+	s := NewStore(t.TempDir(), Policy{KeepLast: 10})
+	for _, data := range []string{"a", "a", "b", "b", "a"} {
+		if err := s.Snapshot("wg0", []byte(data), ReasonWrite); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions, err := s.Versions("wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("versions = %d, want 3 for a, b, a", len(versions))
+	}
+	if data, _ := s.Read(versions[0].ID); string(data) != "a" {
+		t.Errorf("newest version = %q, want a", data)
+	}
+}
+
+func TestPruneKeepsNewestVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []int{30, 20, 10} {
+		writeVersion(t, dir, "wg0", daysAgo(n))
+	}
+
+	// A policy keeping nothing still keeps the newest version
+	s := NewStore(dir, Policy{})
+	if removed, err := s.Prune("wg0"); err != nil || removed != 2 {
+		t.Fatalf("Prune = %d, %v; want 2 removed", removed, err)
+	}
+	versions, _ := s.Versions("wg0")
+	if len(versions) != 1 || !versions[0].Timestamp.After(daysAgo(11)) {
+		t.Errorf("kept %+v, want the version of 10 days ago", versions)
+	}
+}
+
+func TestPolicyKeepsDaily(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	versions := []Version{
+		{Timestamp: now.Add(-time.Hour)},
+		{Timestamp: now.Add(-2 * time.Hour)},
+		{Timestamp: now.AddDate(0, 0, -1)},
+		{Timestamp: now.AddDate(0, 0, -5)},
+	}
+	keep := Policy{KeepLast: 1, KeepDailyDays: 3}.keep(versions, now)
+	// The two versions of today count once; five days ago is outside the window
+	want := []bool{true, false, true, false}
+	for i := range want {
+		if keep[i] != want[i] {
+			t.Errorf("keep = %v, want %v", keep, want)
+			break
+		}
+	}
+}
+
+func TestRemoveOlderThanKeepsNewestVersions(t *testing.T) {
+	tests := []struct {
+		keepLast int
+		want     map[string]int // versions left per tunnel
+	}{
+		{0, map[string]int{"old": 1, "mixed": 1}},
+		{1, map[string]int{"old": 1, "mixed": 1}},
+		{2, map[string]int{"old": 2, "mixed": 2}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, n := range []int{90, 60, 45} {
+			writeVersion(t, dir, "old", daysAgo(n))
+		}
+		writeVersion(t, dir, "mixed", daysAgo(60))
+		writeVersion(t, dir, "mixed", time.Now())
+
+		s := NewStore(dir, Policy{KeepLast: tt.keepLast})
+		if _, err := s.RemoveOlderThan(30 * 24 * time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		for tunnel, want := range tt.want {
+			versions, _ := s.Versions(tunnel)
+			if len(versions) != want {
+				t.Errorf("KeepLast %d: %s has %d versions, want %d", tt.keepLast, tunnel, len(versions), want)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package ipam
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// maxProbe bounds the search for a free address in large (IPv6) subnets
+const maxProbe = 1 << 16
+
+// ParseCIDRList parses a comma separated list of addresses, keeping the host
+// part (10.0.0.1/24 stays 10.0.0.1/24). Bare addresses get a /32 or /128 mask.
+func ParseCIDRList(text string) ([]net.IPNet, error) {
+	var nets []net.IPNet
+	for _, addr := range strings.Split(text, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "/") {
+			if strings.Contains(addr, ":") {
+				addr += "/128"
+			} else {
+				addr += "/32"
+			}
+		}
+		ip, ipNet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %s", addr)
+		}
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		nets = append(nets, net.IPNet{IP: ip, Mask: ipNet.Mask})
+	}
+	return nets, nil
+}
+
+// Next returns the next free host address in each interface subnet, as a
+// /32 or /128. Addresses of the interface itself and anything covered by the
+// AllowedIPs of peers are taken; for IPv4 the network and broadcast
+// addresses are skipped too, except in /31 point-to-point subnets (RFC 3021).
+func Next(subnets []net.IPNet, peers []config.PeerConfig) ([]net.IPNet, error) {
+	var used []netip.Prefix
+	for _, s := range subnets {
+		if p, ok := toPrefix(s); ok {
+			used = append(used, netip.PrefixFrom(p.Addr(), p.Addr().BitLen()))
+		}
+	}
+	for _, peer := range peers {
+		for _, allowed := range peer.AllowedIPs {
+			if p, ok := toPrefix(allowed); ok {
+				used = append(used, p.Masked())
+			}
+		}
+	}
+
+	var free []net.IPNet
+	for _, s := range subnets {
+		subnet, ok := toPrefix(s)
+		if !ok {
+			continue
+		}
+		subnet = subnet.Masked()
+		addr, err := nextFree(subnet, used)
+		if err != nil {
+			return nil, err
+		}
+		bits := addr.BitLen()
+		free = append(free, net.IPNet{IP: net.IP(addr.AsSlice()), Mask: net.CIDRMask(bits, bits)})
+	}
+	return free, nil
+}
+
+func nextFree(subnet netip.Prefix, used []netip.Prefix) (netip.Addr, error) {
+	last := lastAddr(subnet)
+	addr := subnet.Addr().Next()
+	pointToPoint := subnet.Addr().Is4() && subnet.Bits() == 31
+	if pointToPoint {
+		addr = subnet.Addr()
+	}
+	for i := 0; i < maxProbe && addr.IsValid() && subnet.Contains(addr); i++ {
+		if addr.Is4() && addr == last && !pointToPoint {
+			break
+		}
+		if !isUsed(addr, used) {
+			return addr, nil
+		}
+		addr = addr.Next()
+	}
+	return netip.Addr{}, fmt.Errorf("no free address in %s", subnet)
+}
+
+func isUsed(addr netip.Addr, used []netip.Prefix) bool {
+	for _, p := range used {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastAddr returns the highest address of a masked prefix
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// CheckOverlap returns an error when an AllowedIPs entry of peer overlaps one
+// of the other peers. skip is the index of peer in others, or -1.
+func CheckOverlap(peer config.PeerConfig, others []config.PeerConfig, skip int) error {
+	for i, other := range others {
+		if i == skip {
+			continue
+		}
+		for _, a := range peer.AllowedIPs {
+			for _, b := range other.AllowedIPs {
+				if overlaps(a, b) {
+					return fmt.Errorf("allowed IP %s overlaps %s of peer '%s'", a.String(), b.String(), peermeta.Name(other.Name))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckPeers returns an error for the first pair of peers with overlapping AllowedIPs
+func CheckPeers(peers []config.PeerConfig) error {
+	for i, peer := range peers {
+		if err := CheckOverlap(peer, peers[i+1:], -1); err != nil {
+			return fmt.Errorf("peer '%s': %w", peermeta.Name(peer.Name), err)
+		}
+	}
+	return nil
+}
+
+func overlaps(a, b net.IPNet) bool {
+	pa, okA := toPrefix(a)
+	pb, okB := toPrefix(b)
+	if !okA || !okB {
+		return false
+	}
+	return pa.Masked().Overlaps(pb.Masked())
+}
+
+func toPrefix(n net.IPNet) (netip.Prefix, bool) {
+	addr, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	ones, bits := n.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	if addr.Is4() && bits == 128 {
+		ones -= 96
+	}
+	return netip.PrefixFrom(addr, ones), true
+}
@@ -0,0 +1,161 @@
+package ipam
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+func mustCIDRs(t *testing.T, text string) []net.IPNet {
+	t.Helper()
+	nets, err := ParseCIDRList(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nets
+}
+
+func peer(t *testing.T, name, allowed string) config.PeerConfig {
+	t.Helper()
+	return config.PeerConfig{Name: name, AllowedIPs: mustCIDRs(t, allowed)}
+}
+
+func TestParseCIDRList(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"10.0.0.1/24", []string{"10.0.0.1/24"}},
+		{" 10.0.0.2 , fd00::2 ", []string{"10.0.0.2/32", "fd00::2/128"}},
+		{"10.0.0.0/8,,fd00::/64", []string{"10.0.0.0/8", "fd00::/64"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		nets, err := ParseCIDRList(tt.text)
+		if err != nil {
+			t.Errorf("ParseCIDRList(%q): %v", tt.text, err)
+			continue
+		}
+		var got []string
+		for _, n := range nets {
+			got = append(got, n.String())
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ParseCIDRList(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+	if nets := mustCIDRs(t, "10.0.0.1"); len(nets[0].IP) != net.IPv4len {
+		t.Errorf("IPv4 address kept in %d bytes", len(nets[0].IP))
+	}
+
+	for _, bad := range []string{"10.0.0.300", "10.0.0.1/33", "host.example", "10.0.0.1, nope"} {
+		if _, err := ParseCIDRList(bad); err == nil {
+			t.Errorf("ParseCIDRList(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets string
+		peers   []config.PeerConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "first host", subnets: "10.0.0.1/24", want: "10.0.0.2/32"},
+		{name: "interface not first", subnets: "10.0.0.5/24", want: "10.0.0.1/32"},
+		{name: "skips peers", subnets: "10.0.0.1/24",
+			peers: []config.PeerConfig{peer(t, "a", "10.0.0.2/32"), peer(t, "b", "10.0.0.3")},
+			want:  "10.0.0.4/32"},
+		{name: "peer routing a range", subnets: "10.0.0.1/24",
+			peers: []config.PeerConfig{peer(t, "a", "10.0.0.0/30")},
+			want:  "10.0.0.4/32"},
+		{name: "skips network and broadcast", subnets: "10.0.0.1/30",
+			peers:   []config.PeerConfig{peer(t, "a", "10.0.0.2")},
+			wantErr: true},
+		{name: "full subnet", subnets: "10.0.0.1/29",
+			peers:   []config.PeerConfig{peer(t, "a", "10.0.0.2, 10.0.0.3, 10.0.0.4"), peer(t, "b", "10.0.0.5/32, 10.0.0.6")},
+			wantErr: true},
+		{name: "point to point /31", subnets: "10.0.0.0/31", want: "10.0.0.1/32"},
+		{name: "point to point /31, second address", subnets: "10.0.0.1/31", want: "10.0.0.0/32"},
+		{name: "single address /32", subnets: "10.0.0.1/32", wantErr: true},
+		{name: "IPv6", subnets: "fd00::1/64",
+			peers: []config.PeerConfig{peer(t, "a", "fd00::2")},
+			want:  "fd00::3/128"},
+		{name: "IPv6 single address /128", subnets: "fd00::1/128", wantErr: true},
+		{name: "dual stack", subnets: "10.0.0.1/24, fd00::1/64", want: "10.0.0.2/32,fd00::2/128"},
+	}
+	for _, tt := range tests {
+		free, err := Next(mustCIDRs(t, tt.subnets), tt.peers)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Next = %v, want an error", tt.name, free)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, n := range free {
+			got = append(got, n.String())
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: Next = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	others := []config.PeerConfig{
+		peer(t, "alice", "10.0.0.2/32, 192.168.1.0/24"),
+		peer(t, "bob | disabled", "fd00::/64"),
+	}
+	tests := []struct {
+		allowed string
+		skip    int
+		overlap string // name in the error, empty for none
+	}{
+		{"10.0.0.3/32", -1, ""},
+		{"10.0.0.2/32", -1, "alice"},
+		{"10.0.0.0/24", -1, "alice"},
+		{"192.168.1.7", -1, "alice"},
+		{"192.168.1.7", 0, ""},
+		{"fd00::5", -1, "bob"},
+		{"fd01::5", -1, ""},
+		// An IPv4 and an IPv6 network never overlap
+		{"0.0.0.0/0", 1, "alice"},
+		{"::/0", 0, "bob"},
+	}
+	for _, tt := range tests {
+		err := CheckOverlap(peer(t, "new", tt.allowed), others, tt.skip)
+		switch {
+		case tt.overlap == "" && err != nil:
+			t.Errorf("%s: %v", tt.allowed, err)
+		case tt.overlap != "" && err == nil:
+			t.Errorf("%s: no overlap found, want %s", tt.allowed, tt.overlap)
+		case err != nil && !strings.Contains(err.Error(), "'"+tt.overlap+"'"):
+			t.Errorf("%s: %v, want peer %s named", tt.allowed, err, tt.overlap)
+		}
+	}
+}
+
+func TestCheckPeers(t *testing.T) {
+	peers := []config.PeerConfig{
+		peer(t, "alice", "10.0.0.2"),
+		peer(t, "bob", "10.0.0.3, fd00::3"),
+	}
+	if err := CheckPeers(peers); err != nil {
+		t.Fatal(err)
+	}
+
+	peers = append(peers, peer(t, "carol", "10.0.0.0/30"))
+	err := CheckPeers(peers)
+	if err == nil || !strings.Contains(err.Error(), "peer 'alice'") || !strings.Contains(err.Error(), "'carol'") {
+		t.Errorf("CheckPeers = %v, want alice overlapping carol", err)
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/controller"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter serves interface and peer metrics in the Prometheus text format
+type Exporter struct {
+	ctrl       controller.Controller
+	staleAfter time.Duration
+	http       *http.Server
+}
+
+// New creates an exporter. Peers without a handshake within staleAfter are
+// not counted as connected.
+func New(ctrl controller.Controller, staleAfter time.Duration) *Exporter {
+	return &Exporter{ctrl: ctrl, staleAfter: staleAfter}
+}
+
+// Handler returns the handler for /metrics
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if err := e.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+// Start listens on address and serves in the background
+func (e *Exporter) Start(address string) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	e.http = &http.Server{Handler: e.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go e.http.Serve(ln)
+	return nil
+}
+
+// Close stops the listener
+func (e *Exporter) Close() error {
+	if e.http == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return e.http.Shutdown(ctx)
+}
+
+type sample struct {
+	labels []string // name, value pairs
+	value  float64
+}
+
+type family struct {
+	name, help, kind string
+	samples          []sample
+}
+
+// Write collects the current state and writes it to w
+func (e *Exporter) Write(w io.Writer) error {
+	interfaces, err := e.ctrl.ListInterfaces()
+	if err != nil {
+		return err
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+
+	up := family{name: "wgadmin_interface_up", help: "Whether the tunnel is active.", kind: "gauge"}
+	peers := family{name: "wgadmin_interface_peers", help: "Number of peers in the tunnel config.", kind: "gauge"}
+	connected := family{name: "wgadmin_interface_peers_connected", help: "Number of peers with a recent handshake.", kind: "gauge"}
+	rx := family{name: "wgadmin_peer_receive_bytes_total", help: "Bytes received from the peer.", kind: "counter"}
+	tx := family{name: "wgadmin_peer_transmit_bytes_total", help: "Bytes sent to the peer.", kind: "counter"}
+	handshake := family{name: "wgadmin_peer_last_handshake_seconds", help: "Unix time of the last handshake, 0 if none.", kind: "gauge"}
+
+	for _, iface := range interfaces {
+		tunnel := []string{"tunnel", iface.Name}
+		up.add(tunnel, boolValue(iface.Active))
+
+		configured := 0
+		if cfg, err := config.ParseConfig(e.ctrl.GetConfigPath(iface.Name)); err == nil {
+			configured = len(cfg.Peers)
+		}
+		peers.add(tunnel, float64(configured))
+
+		if !iface.Active {
+			connected.add(tunnel, 0)
+			continue
+		}
+		stats, err := controller.GetPeerStats(e.ctrl, iface.Name)
+		if err != nil {
+			connected.add(tunnel, 0)
+			continue
+		}
+		n := 0
+		for _, p := range stats {
+			if !p.IsStale(e.staleAfter) {
+				n++
+			}
+			labels := []string{"tunnel", iface.Name, "peer", p.Name, "public_key", p.PublicKey}
+			rx.add(labels, float64(p.RxBytes))
+			tx.add(labels, float64(p.TxBytes))
+			var last float64
+			if !p.LastHandshake.IsZero() {
+				last = float64(p.LastHandshake.Unix())
+			}
+			handshake.add(labels, last)
+		}
+		connected.add(tunnel, float64(n))
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, f := range []family{up, peers, connected, rx, tx, handshake} {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) add(labels []string, value float64) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+func (f family) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, s := range f.samples {
+		pairs := make([]string, 0, len(s.labels)/2)
+		for i := 0; i+1 < len(s.labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", s.labels[i], escape(s.labels[i+1])))
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", f.name, strings.Join(pairs, ","), strconv.FormatFloat(s.value, 'f', -1, 64))
+	}
+}
+
+// escape prepares a label value for %q, which already escapes backslashes,
+// quotes and newlines the way the exposition format expects. Other control
+// characters are dropped since %q would emit Go-only escapes for them.
+func escape(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
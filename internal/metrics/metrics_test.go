@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/controller"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func mustKey(t *testing.T) wgtypes.Key {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustCIDR(t *testing.T, s string) net.IPNet {
+	t.Helper()
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *n
+}
+
+func TestMetrics(t *testing.T) {
+	fake, err := controller.NewFake(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice, bob := mustKey(t).PublicKey(), mustKey(t).PublicKey()
+	cfg := config.Config{
+		Name: "wg0",
+		Interface: config.InterfaceConfig{
+			PrivateKey: mustKey(t),
+			Address:    []net.IPNet{mustCIDR(t, "10.0.0.1/24")},
+		},
+		Peers: []config.PeerConfig{
+			{Name: "alice", PublicKey: alice, AllowedIPs: []net.IPNet{mustCIDR(t, "10.0.0.2/32")}},
+			{Name: "bob", PublicKey: bob, AllowedIPs: []net.IPNet{mustCIDR(t, "10.0.0.3/32")}},
+		},
+	}
+	if err := fake.WriteConfig("wg0", cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Name = "wg1"
+	cfg.Peers = nil
+	if err := fake.WriteConfig("wg1", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+
+	handshake := time.Unix(1700000000, 0)
+	fake.SetDevice("wg0", &wgtypes.Device{Name: "wg0", Peers: []wgtypes.Peer{
+		{PublicKey: alice, ReceiveBytes: 1234567890, TransmitBytes: 42, LastHandshakeTime: time.Now()},
+		{PublicKey: bob, LastHandshakeTime: handshake},
+	}})
+
+	srv := httptest.NewServer(New(fake, 3*time.Minute).Handler())
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+
+	want := []string{
+		`# TYPE wgadmin_interface_up gauge`,
+		`wgadmin_interface_up{tunnel="wg0"} 1`,
+		`wgadmin_interface_up{tunnel="wg1"} 0`,
+		`wgadmin_interface_peers{tunnel="wg0"} 2`,
+		`wgadmin_interface_peers{tunnel="wg1"} 0`,
+		`wgadmin_interface_peers_connected{tunnel="wg0"} 1`,
+		`# TYPE wgadmin_peer_receive_bytes_total counter`,
+		`wgadmin_peer_receive_bytes_total{tunnel="wg0",peer="alice",public_key="` + alice.String() + `"} 1234567890`,
+		`wgadmin_peer_transmit_bytes_total{tunnel="wg0",peer="alice",public_key="` + alice.String() + `"} 42`,
+		`wgadmin_peer_last_handshake_seconds{tunnel="wg0",peer="bob",public_key="` + bob.String() + `"} 1700000000`,
+	}
+	for _, line := range want {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	var b strings.Builder
+	f := family{name: "m", help: "h", kind: "gauge"}
+	f.add([]string{"peer", "a \"b\"\\c\nd\te"}, 1)
+	f.write(&b)
+	if want := `m{peer="a \"b\"\\c\nde"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
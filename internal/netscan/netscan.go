@@ -0,0 +1,248 @@
+// Package netscan finds hosts on a tunnel network by connecting to a list of
+// TCP ports. Where ICMP sockets are permitted, hosts are also pinged, so
+// hosts that filter every scanned port still show up.
+package netscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"wgAdmin/internal/reachability"
+)
+
+// MaxHosts is the largest network a scan accepts, a /16 for IPv4
+const MaxHosts = 1 << 16
+
+// Result is a host that answered a ping or on at least one port
+type Result struct {
+	IP        string        `json:"ip"`
+	Hostnames []string      `json:"hostnames,omitempty"`
+	PortsOpen []int         `json:"ports_open"`
+	Latency   time.Duration `json:"latency_ns"` // fastest answer to the ping or on any port, open or refused
+}
+
+// Scanner probes every host address of a network
+type Scanner struct {
+	Results chan Result // closed when the scan ends
+
+	hosts   []netip.Addr
+	ports   []int
+	workers int
+	timeout time.Duration
+	ping    reachability.ProbeFunc // host discovery, run alongside the ports
+	scanned atomic.Uint32
+
+	mu   sync.Mutex
+	gate chan struct{} // non-nil while paused, closed on resume
+}
+
+// New creates a scanner for cidr. Network and broadcast addresses of IPv4
+// networks larger than a /31 are skipped.
+func New(cidr string, ports []int, workers int, timeout time.Duration) (*Scanner, error) {
+	hosts, err := Hosts(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) == 0 {
+		return nil, errors.New("no ports to scan")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return &Scanner{
+		Results: make(chan Result, workers),
+		hosts:   hosts,
+		ports:   ports,
+		workers: workers,
+		timeout: timeout,
+		ping:    reachability.ICMP,
+	}, nil
+}
+
+// Hosts returns the host addresses of cidr
+func Hosts(cidr string) ([]netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q", cidr)
+	}
+	prefix = prefix.Masked()
+	bits := prefix.Addr().BitLen() - prefix.Bits()
+	if bits > 16 {
+		return nil, fmt.Errorf("network %s is larger than %d addresses", prefix, MaxHosts)
+	}
+
+	hosts := make([]netip.Addr, 0, 1<<bits)
+	for a := prefix.Addr(); prefix.Contains(a); a = a.Next() {
+		hosts = append(hosts, a)
+		if !a.Next().IsValid() {
+			break
+		}
+	}
+	if prefix.Addr().Is4() && bits > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+// Run starts the scan in the background. It stops early when ctx is done.
+func (s *Scanner) Run(ctx context.Context) {
+	jobs := make(chan netip.Addr)
+	var wg sync.WaitGroup
+	for range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				if !s.wait(ctx) {
+					continue
+				}
+				res, ok := s.probe(ctx, addr)
+				s.scanned.Add(1)
+				if ok {
+					select {
+					case s.Results <- res:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for _, addr := range s.hosts {
+			select {
+			case jobs <- addr:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(s.Results)
+	}()
+}
+
+// Progress returns the number of hosts probed and the total
+func (s *Scanner) Progress() (scanned, total uint32) {
+	return s.scanned.Load(), uint32(len(s.hosts))
+}
+
+// Pause holds the scan before the next host; probes in flight finish
+func (s *Scanner) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gate == nil {
+		s.gate = make(chan struct{})
+	}
+}
+
+// Resume continues a paused scan
+func (s *Scanner) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gate != nil {
+		close(s.gate)
+		s.gate = nil
+	}
+}
+
+// Paused reports whether the scan is paused
+func (s *Scanner) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gate != nil
+}
+
+// wait blocks while the scan is paused and reports whether to go on
+func (s *Scanner) wait(ctx context.Context) bool {
+	s.mu.Lock()
+	gate := s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// probe pings addr and connects to every port of it. A ping reply or a
+// refused connection still proves the host is up.
+func (s *Scanner) probe(ctx context.Context, addr netip.Addr) (Result, bool) {
+	res := Result{IP: addr.String()}
+	alive := false
+
+	// The ping fails right away when ICMP is not permitted
+	pinged := make(chan time.Duration, 1)
+	go func() {
+		rtt, err := s.ping(ctx, addr, s.timeout)
+		if err != nil {
+			rtt = -1
+		}
+		pinged <- rtt
+	}()
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	for _, port := range s.ports {
+		if ctx.Err() != nil {
+			return res, false
+		}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, uint16(port)).String())
+		elapsed := time.Since(start)
+		switch {
+		case err == nil:
+			conn.Close()
+			res.PortsOpen = append(res.PortsOpen, port)
+		case errors.Is(err, syscall.ECONNREFUSED):
+		default:
+			continue
+		}
+		if !alive || elapsed < res.Latency {
+			res.Latency = elapsed
+		}
+		alive = true
+	}
+	if rtt := <-pinged; rtt >= 0 {
+		if !alive || rtt < res.Latency {
+			res.Latency = rtt
+		}
+		alive = true
+	}
+	if !alive {
+		return res, false
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	if names, err := net.DefaultResolver.LookupAddr(lookupCtx, res.IP); err == nil {
+		res.Hostnames = names
+	}
+	sort.Ints(res.PortsOpen)
+	return res, true
+}
+
+// Network returns the network of an interface address, e.g. 10.8.0.0/16
+// for 10.8.0.1/16
+func Network(addr net.IPNet) string {
+	ip := addr.IP
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+	ones, _ := addr.Mask.Size()
+	return netip.PrefixFrom(a, ones).Masked().String()
+}
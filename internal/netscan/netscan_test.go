@@ -0,0 +1,290 @@
+package netscan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/reachability"
+)
+
+func TestHosts(t *testing.T) {
+	tests := []struct {
+		cidr        string
+		count       int
+		first, last string
+	}{
+		{"10.8.0.1/24", 254, "10.8.0.1", "10.8.0.254"},
+		{"10.8.0.0/28", 14, "10.8.0.1", "10.8.0.14"},
+		{"10.8.0.0/31", 2, "10.8.0.0", "10.8.0.1"},
+		{"10.8.0.7/32", 1, "10.8.0.7", "10.8.0.7"},
+		{"10.8.0.0/16", 65534, "10.8.0.1", "10.8.255.254"},
+	}
+	for _, tt := range tests {
+		hosts, err := Hosts(tt.cidr)
+		if err != nil {
+			t.Errorf("Hosts(%s): %v", tt.cidr, err)
+			continue
+		}
+		if len(hosts) != tt.count || hosts[0].String() != tt.first || hosts[len(hosts)-1].String() != tt.last {
+			t.Errorf("Hosts(%s) = %d hosts %s..%s, want %d hosts %s..%s", tt.cidr,
+				len(hosts), hosts[0], hosts[len(hosts)-1], tt.count, tt.first, tt.last)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/8", "10.8.0.1", "nonsense"} {
+		if _, err := Hosts(bad); err == nil {
+			t.Errorf("Hosts(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	for addr, want := range map[string]string{
+		"10.8.0.1/16":    "10.8.0.0/16",
+		"10.8.0.17/28":   "10.8.0.16/28",
+		"fd00::1/64":     "fd00::/64",
+		"192.168.1.1/32": "192.168.1.1/32",
+	} {
+		ip, n, err := net.ParseCIDR(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		if got := Network(*n); got != want {
+			t.Errorf("Network(%s) = %s, want %s", addr, got, want)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	profiles, err := ParseProfiles("SSH=22\nWeb = 80, 443 8000-8002;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Profile{{Name: "SSH", Ports: []int{22}}, {Name: "Web", Ports: []int{80, 443, 8000, 8001, 8002}}}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Fatalf("ParseProfiles = %+v, want %+v", profiles, want)
+	}
+	if text := FormatProfiles(profiles); text != "SSH=22; Web=80,443,8000,8001,8002" {
+		t.Errorf("FormatProfiles = %q", text)
+	}
+
+	for _, bad := range []string{"SSH", "=22", "SSH=22; SSH=2222", "Custom=1", "Web=80-70", "Web=0", "Web=65536", "Web="} {
+		if _, err := ParseProfiles(bad); err == nil {
+			t.Errorf("ParseProfiles(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// A second port that refuses connections
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	s, err := New("127.0.0.1/32", []int{refused, open}, 4, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Run(context.Background())
+
+	var results []Result
+	for r := range s.Results {
+		results = append(results, r)
+	}
+	if len(results) != 1 || results[0].IP != "127.0.0.1" || !reflect.DeepEqual(results[0].PortsOpen, []int{open}) {
+		t.Fatalf("results = %+v, want 127.0.0.1 with port %d open", results, open)
+	}
+	if scanned, total := s.Progress(); scanned != 1 || total != 1 {
+		t.Errorf("Progress() = %d/%d, want 1/1", scanned, total)
+	}
+}
+
+func TestScanFindsFilteredHostsByPing(t *testing.T) {
+	// The discard prefix is never routed, so no port answers
+	scan := func(ping reachability.ProbeFunc) []Result {
+		s, err := New("100::1/128", []int{22}, 1, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.ping = ping
+		s.Run(context.Background())
+		var results []Result
+		for r := range s.Results {
+			results = append(results, r)
+		}
+		return results
+	}
+
+	replies := func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		return 3 * time.Millisecond, nil
+	}
+	if results := scan(replies); len(results) != 1 || len(results[0].PortsOpen) != 0 || results[0].Latency != 3*time.Millisecond {
+		t.Errorf("results = %+v, want 100::1 found by ping without open ports", results)
+	}
+
+	silent := func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		return 0, errors.New("timeout")
+	}
+	if results := scan(silent); len(results) != 0 {
+		t.Errorf("results = %+v, want none when neither ping nor ports answer", results)
+	}
+}
+
+func TestPauseAndCancel(t *testing.T) {
+	s, err := New("127.0.0.0/28", []int{1}, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Pause()
+	if !s.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Run(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	if scanned, _ := s.Progress(); scanned != 0 {
+		t.Fatalf("%d hosts probed while paused", scanned)
+	}
+
+	// Canceling a paused scan ends it
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range s.Results {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("results not closed after cancel")
+	}
+}
+
+func TestSortAndExport(t *testing.T) {
+	results := []Result{
+		{IP: "10.8.0.10", Hostnames: []string{"b.lan"}, PortsOpen: []int{22}, Latency: 3 * time.Millisecond},
+		{IP: "10.8.0.9", Hostnames: []string{"c.lan"}, PortsOpen: []int{22, 80}, Latency: time.Millisecond},
+		{IP: "10.8.0.100", PortsOpen: nil, Latency: 2500 * time.Microsecond},
+	}
+	ips := func() []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.IP)
+		}
+		return out
+	}
+
+	for _, tt := range []struct {
+		by   string
+		desc bool
+		want []string
+	}{
+		{ByIP, false, []string{"10.8.0.9", "10.8.0.10", "10.8.0.100"}},
+		{ByIP, true, []string{"10.8.0.100", "10.8.0.10", "10.8.0.9"}},
+		{ByHostname, false, []string{"10.8.0.100", "10.8.0.10", "10.8.0.9"}},
+		{ByPorts, true, []string{"10.8.0.9", "10.8.0.10", "10.8.0.100"}},
+		{ByLatency, false, []string{"10.8.0.9", "10.8.0.100", "10.8.0.10"}},
+	} {
+		Sort(results, tt.by, tt.desc)
+		if got := ips(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Sort(%s, desc=%v) = %v, want %v", tt.by, tt.desc, got, tt.want)
+		}
+	}
+
+	_, laptop, _ := net.ParseCIDR("10.8.0.9/32")
+	tunnel := Tunnel{Peers: []KnownPeer{{Name: "laptop", PublicKey: "bGFwdG9w", AllowedIPs: []net.IPNet{*laptop}}}}
+
+	var csvOut strings.Builder
+	if err := WriteCSV(&csvOut, results[:2], tunnel); err != nil {
+		t.Fatal(err)
+	}
+	want := "ip,hostname,ports_open,latency_ms,peer,public_key\n" +
+		"10.8.0.9,c.lan,22 80,1.0,laptop,bGFwdG9w\n" +
+		"10.8.0.100,,,2.5,,\n"
+	if csvOut.String() != want {
+		t.Errorf("CSV = %q, want %q", csvOut.String(), want)
+	}
+
+	var jsonOut strings.Builder
+	if err := WriteJSON(&jsonOut, results[:2], tunnel); err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(jsonOut.String()), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0]["peer"] != "laptop" || rows[0]["public_key"] != "bGFwdG9w" || rows[0]["latency_ns"] != float64(time.Millisecond) {
+		t.Errorf("JSON = %s", jsonOut.String())
+	}
+	if _, ok := rows[1]["peer"]; ok {
+		t.Errorf("unmatched host has a peer: %v", rows[1])
+	}
+
+	jsonOut.Reset()
+	if err := WriteJSON(&jsonOut, nil, tunnel); err != nil || jsonOut.String() != "[]\n" {
+		t.Errorf("JSON of no results = %q, %v", jsonOut.String(), err)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	cidr := func(s string) net.IPNet {
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.IP = ip
+		return *n
+	}
+	tunnel := Tunnel{
+		Addresses: []net.IPNet{cidr("10.8.0.1/24")},
+		Peers: []KnownPeer{
+			{Name: "site", AllowedIPs: []net.IPNet{cidr("10.8.0.0/25"), cidr("192.168.50.0/24")}},
+			{Name: "laptop", AllowedIPs: []net.IPNet{cidr("10.8.0.14/32")}},
+		},
+	}
+
+	tests := []struct {
+		ip      string
+		peer    string
+		local   bool
+		unknown bool
+	}{
+		{ip: "10.8.0.14", peer: "laptop"},  // the most specific entry wins
+		{ip: "10.8.0.20", peer: "site"},    // routed by the broader entry
+		{ip: "192.168.50.9", peer: "site"}, // behind a peer, outside the tunnel subnet
+		{ip: "10.8.0.1", peer: "site", local: true},
+		{ip: "10.8.0.200", unknown: true}, // in the subnet, no peer routes it
+		{ip: "172.16.0.1"},                // outside everything
+	}
+	for _, tt := range tests {
+		id := tunnel.Identify(tt.ip)
+		peer := ""
+		if id.Peer != nil {
+			peer = id.Peer.Name
+		}
+		if peer != tt.peer || id.Local != tt.local || id.Unknown != tt.unknown {
+			t.Errorf("Identify(%s) = peer %q local %v unknown %v, want %q %v %v",
+				tt.ip, peer, id.Local, id.Unknown, tt.peer, tt.local, tt.unknown)
+		}
+	}
+}
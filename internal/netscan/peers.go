@@ -0,0 +1,59 @@
+package netscan
+
+import (
+	"net"
+	"time"
+)
+
+// KnownPeer is a configured peer that scan results are matched against
+type KnownPeer struct {
+	Name          string
+	PublicKey     string
+	AllowedIPs    []net.IPNet
+	LastHandshake time.Time // zero when the peer never connected or is not loaded
+}
+
+// Tunnel is the WireGuard side of a scanned network
+type Tunnel struct {
+	Addresses []net.IPNet // the interface's own addresses
+	Peers     []KnownPeer
+}
+
+// Identity tells who a responding host is
+type Identity struct {
+	Peer    *KnownPeer // the peer routing the address, nil when none
+	Local   bool       // an address of the interface itself
+	Unknown bool       // inside a tunnel network but not a configured peer
+}
+
+// Identify matches ip against the tunnel. The peer with the most specific
+// AllowedIPs entry containing ip wins, as it does in WireGuard's routing.
+func (t Tunnel) Identify(ip string) Identity {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Identity{}
+	}
+
+	var id Identity
+	best := -1
+	for i := range t.Peers {
+		for _, n := range t.Peers[i].AllowedIPs {
+			if ones, _ := n.Mask.Size(); n.Contains(addr) && ones > best {
+				best = ones
+				id.Peer = &t.Peers[i]
+			}
+		}
+	}
+
+	inTunnel := false
+	for _, a := range t.Addresses {
+		if a.IP.Equal(addr) {
+			id.Local = true
+		}
+		if a.Contains(addr) {
+			inTunnel = true
+		}
+	}
+	id.Unknown = inTunnel && !id.Local && id.Peer == nil
+	return id
+}
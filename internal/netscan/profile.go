@@ -0,0 +1,103 @@
+package netscan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CustomProfile names the choice of an ad hoc port list
+const CustomProfile = "Custom"
+
+// Profile is a named list of ports to scan
+type Profile struct {
+	Name  string
+	Ports []int
+}
+
+// ParseProfiles parses profiles written as "Name=ports" separated by ';' or
+// newlines, e.g. "SSH=22; Web=80,443,8000-8010"
+func ParseProfiles(text string) ([]Profile, error) {
+	var profiles []Profile
+	seen := make(map[string]bool)
+	for _, entry := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, ports, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("port profile %q: want Name=ports", entry)
+		}
+		if name == CustomProfile || seen[name] {
+			return nil, fmt.Errorf("port profile %q: name is already used", name)
+		}
+		list, err := ParsePorts(ports)
+		if err != nil {
+			return nil, fmt.Errorf("port profile %s: %w", name, err)
+		}
+		seen[name] = true
+		profiles = append(profiles, Profile{Name: name, Ports: list})
+	}
+	return profiles, nil
+}
+
+// FormatProfiles is the inverse of ParseProfiles
+func FormatProfiles(profiles []Profile) string {
+	parts := make([]string, len(profiles))
+	for i, p := range profiles {
+		parts[i] = p.Name + "=" + FormatPorts(p.Ports)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParsePorts parses a list of ports and ranges separated by commas or
+// spaces, e.g. "22, 80 8000-8010". Duplicates are dropped.
+func ParsePorts(text string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+		lo, hi, isRange := strings.Cut(field, "-")
+		first, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(hi); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+		}
+		for p := first; p <= last; p++ {
+			if !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return p, nil
+}
+
+// FormatPorts joins ports with commas
+func FormatPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ",")
+}
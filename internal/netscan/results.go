@@ -0,0 +1,111 @@
+package netscan
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Columns results can be sorted by
+const (
+	ByIP       = "ip"
+	ByHostname = "hostname"
+	ByPorts    = "ports"
+	ByLatency  = "latency"
+)
+
+// Hostname returns the first name of a result, or ""
+func (r Result) Hostname() string {
+	if len(r.Hostnames) == 0 {
+		return ""
+	}
+	return r.Hostnames[0]
+}
+
+// Sort orders results by a column; addresses sort numerically and ports by
+// the number of open ports. Ties are broken by ascending address.
+func Sort(results []Result, by string, desc bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if c := compareBy(by, a, b); c != 0 {
+			return (c < 0) != desc
+		}
+		return compareIP(a.IP, b.IP) < 0
+	})
+}
+
+func compareBy(by string, a, b Result) int {
+	switch by {
+	case ByHostname:
+		return strings.Compare(a.Hostname(), b.Hostname())
+	case ByPorts:
+		return cmp.Compare(len(a.PortsOpen), len(b.PortsOpen))
+	case ByLatency:
+		return cmp.Compare(a.Latency, b.Latency)
+	}
+	return compareIP(a.IP, b.IP)
+}
+
+func compareIP(a, b string) int {
+	x, errA := netip.ParseAddr(a)
+	y, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return x.Compare(y)
+}
+
+// exported is a result as written by WriteCSV and WriteJSON, with the peer
+// routing its address
+type exported struct {
+	Result
+	Peer      string `json:"peer,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+func exportResults(results []Result, t Tunnel) []exported {
+	out := make([]exported, len(results))
+	for i, r := range results {
+		out[i].Result = r
+		if p := t.Identify(r.IP).Peer; p != nil {
+			out[i].Peer, out[i].PublicKey = p.Name, p.PublicKey
+		}
+	}
+	return out
+}
+
+// WriteCSV writes results with a header row, matched to the peers of t;
+// latency is in milliseconds
+func WriteCSV(w io.Writer, results []Result, t Tunnel) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"ip", "hostname", "ports_open", "latency_ms", "peer", "public_key"}); err != nil {
+		return err
+	}
+	for _, r := range exportResults(results, t) {
+		record := []string{
+			r.IP,
+			strings.Join(r.Hostnames, " "),
+			strings.ReplaceAll(FormatPorts(r.PortsOpen), ",", " "),
+			strconv.FormatFloat(float64(r.Latency.Microseconds())/1000, 'f', 1, 64),
+			r.Peer,
+			r.PublicKey,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes results as an indented JSON array, matched to the peers of t
+func WriteJSON(w io.Writer, results []Result, t Tunnel) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exportResults(results, t))
+}
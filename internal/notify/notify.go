@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/settings"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Kind identifies a type of event that can be notified
+type Kind string
+
+// Event kinds, each enabled by its own setting
+const (
+	InterfaceDown    Kind = "interface-down"
+	PeerConnected    Kind = "peer-connected"
+	PeerDisconnected Kind = "peer-disconnected"
+	ToggleFailed     Kind = "toggle-failed"
+	ScanChanged      Kind = "scan-changed"
+)
+
+// Event is a state change worth telling the user about
+type Event struct {
+	Kind    Kind
+	Tunnel  string
+	Peer    string
+	Title   string
+	Message string
+}
+
+// Snapshot is the state read by one refresh
+type Snapshot struct {
+	Interfaces []config.Interface
+	Peers      map[string][]controller.PeerStats
+}
+
+// Notifier diffs consecutive snapshots and sends the enabled events
+type Notifier struct {
+	mu       sync.Mutex
+	cfg      *settings.AppSettings
+	send     func(Event)
+	prev     *Snapshot
+	expected map[string]bool // tunnels deactivated on purpose since the last snapshot
+}
+
+// New creates a notifier that passes enabled events to send
+func New(cfg *settings.AppSettings, send func(Event)) *Notifier {
+	return &Notifier{cfg: cfg, send: send, expected: make(map[string]bool)}
+}
+
+// SetSettings replaces the settings that select the enabled events
+func (n *Notifier) SetSettings(cfg *settings.AppSettings) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg = cfg
+}
+
+// ExpectDown marks name as deactivated by the user, so the next snapshot
+// showing it down is not reported
+func (n *Notifier) ExpectDown(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.expected[name] = true
+}
+
+// Update compares s with the previous snapshot and sends the enabled events.
+// The first snapshot only sets the baseline.
+func (n *Notifier) Update(s Snapshot) []Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var events []Event
+	if n.prev != nil {
+		staleAfter := time.Duration(n.cfg.PeerStaleMinutes) * time.Minute
+		events = Diff(*n.prev, s, staleAfter, n.expected)
+	}
+	n.prev = &s
+	for _, iface := range s.Interfaces {
+		if !iface.Active {
+			delete(n.expected, iface.Name)
+		}
+	}
+	return n.dispatch(events)
+}
+
+// ToggleFailed sends a notification for a failed activation or deactivation
+func (n *Notifier) ToggleFailed(name string, activate bool, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	action := "deactivate"
+	if activate {
+		action = "activate"
+	} else {
+		delete(n.expected, name)
+	}
+	n.dispatch([]Event{{
+		Kind:    ToggleFailed,
+		Tunnel:  name,
+		Title:   fmt.Sprintf("Failed to %s %s", action, name),
+		Message: err.Error(),
+	}})
+}
+
+// ScanChanged sends a notification for a scheduled scan that found changes
+func (n *Notifier) ScanChanged(name, summary string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.dispatch([]Event{{
+		Kind:    ScanChanged,
+		Tunnel:  name,
+		Title:   fmt.Sprintf("Scan of %s found changes", name),
+		Message: summary,
+	}})
+}
+
+func (n *Notifier) dispatch(events []Event) []Event {
+	var sent []Event
+	for _, e := range events {
+		if Enabled(n.cfg, e.Kind) {
+			n.send(e)
+			sent = append(sent, e)
+		}
+	}
+	return sent
+}
+
+// Enabled reports whether cfg turns on notifications for kind
+func Enabled(cfg *settings.AppSettings, kind Kind) bool {
+	switch kind {
+	case InterfaceDown:
+		return cfg.NotifyInterfaceDown
+	case PeerConnected:
+		return cfg.NotifyPeerConnected
+	case PeerDisconnected:
+		return cfg.NotifyPeerDisconnected
+	case ToggleFailed:
+		return cfg.NotifyToggleFailed
+	case ScanChanged:
+		return cfg.NotifyScanChanges
+	}
+	return false
+}
+
+// Diff returns the events between two snapshots. Tunnels in expected went
+// down on purpose. Tunnels and peers missing from either snapshot are
+// ignored, so creating or deleting them is not reported.
+func Diff(prev, next Snapshot, staleAfter time.Duration, expected map[string]bool) []Event {
+	wasActive := make(map[string]bool, len(prev.Interfaces))
+	for _, iface := range prev.Interfaces {
+		wasActive[iface.Name] = iface.Active
+	}
+
+	var events []Event
+	for _, iface := range next.Interfaces {
+		active, known := wasActive[iface.Name]
+		if !known || !active {
+			continue
+		}
+		if !iface.Active {
+			if !expected[iface.Name] {
+				events = append(events, Event{
+					Kind:    InterfaceDown,
+					Tunnel:  iface.Name,
+					Title:   fmt.Sprintf("%s went down", iface.Name),
+					Message: "The tunnel was deactivated outside of wgAdmin",
+				})
+			}
+			continue
+		}
+		events = append(events, peerEvents(iface.Name, prev.Peers[iface.Name], next.Peers[iface.Name], staleAfter)...)
+	}
+	return events
+}
+
+func peerEvents(tunnel string, prev, next []controller.PeerStats, staleAfter time.Duration) []Event {
+	wasConnected := make(map[string]bool, len(prev))
+	for _, p := range prev {
+		wasConnected[p.PublicKey] = !p.IsStale(staleAfter)
+	}
+
+	var events []Event
+	for _, p := range next {
+		was, known := wasConnected[p.PublicKey]
+		now := !p.IsStale(staleAfter)
+		if !known || was == now {
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = shortKey(p.PublicKey)
+		}
+		e := Event{Tunnel: tunnel, Peer: name}
+		if now {
+			e.Kind = PeerConnected
+			e.Title = fmt.Sprintf("%s connected", name)
+			e.Message = fmt.Sprintf("Peer %s completed a handshake on %s", name, tunnel)
+		} else {
+			e.Kind = PeerDisconnected
+			e.Title = fmt.Sprintf("%s disconnected", name)
+			e.Message = fmt.Sprintf("No handshake from %s on %s for %s", name, tunnel, staleAfter)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func shortKey(key string) string {
+	if len(key) > 8 {
+		return key[:8] + "..."
+	}
+	return key
+}
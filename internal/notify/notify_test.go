@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/settings"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+func snapshot(active map[string]bool, peers map[string][]controller.PeerStats) Snapshot {
+	s := Snapshot{Peers: peers}
+	for _, name := range []string{"wg0", "wg1"} {
+		s.Interfaces = append(s.Interfaces, config.Interface{Name: name, Active: active[name]})
+	}
+	return s
+}
+
+func kinds(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, string(e.Kind)+" "+e.Tunnel+" "+e.Peer)
+	}
+	return out
+}
+
+func TestNotifier(t *testing.T) {
+	cfg := &settings.AppSettings{
+		PeerStaleMinutes:       3,
+		NotifyInterfaceDown:    true,
+		NotifyPeerConnected:    true,
+		NotifyPeerDisconnected: true,
+		NotifyToggleFailed:     true,
+		NotifyScanChanges:      true,
+	}
+	var sent []Event
+	n := New(cfg, func(e Event) { sent = append(sent, e) })
+
+	now := time.Now()
+	fresh := controller.PeerStats{Name: "alice", PublicKey: "alice-key", LastHandshake: now}
+	stale := controller.PeerStats{Name: "alice", PublicKey: "alice-key", LastHandshake: now.Add(-time.Hour)}
+	unnamed := controller.PeerStats{PublicKey: "0123456789abcdef"}
+	both := map[string]bool{"wg0": true, "wg1": true}
+
+	// The first snapshot is only a baseline
+	if got := n.Update(snapshot(both, map[string][]controller.PeerStats{"wg0": {stale, unnamed}})); got != nil {
+		t.Fatalf("baseline sent %v", kinds(got))
+	}
+
+	unnamed.LastHandshake = now
+	got := n.Update(snapshot(both, map[string][]controller.PeerStats{"wg0": {fresh, unnamed}}))
+	want := []string{"peer-connected wg0 alice", "peer-connected wg0 01234567..."}
+	if !reflect.DeepEqual(kinds(got), want) {
+		t.Fatalf("connect events = %v, want %v", kinds(got), want)
+	}
+
+	// wg1 is deactivated by the user, wg0 goes down on its own
+	n.ExpectDown("wg1")
+	got = n.Update(snapshot(nil, nil))
+	if want := []string{"interface-down wg0 "}; !reflect.DeepEqual(kinds(got), want) {
+		t.Fatalf("down events = %v, want %v", kinds(got), want)
+	}
+
+	// Disabled kinds are not sent
+	cfg.NotifyPeerDisconnected = false
+	n.Update(snapshot(both, map[string][]controller.PeerStats{"wg0": {fresh}}))
+	got = n.Update(snapshot(both, map[string][]controller.PeerStats{"wg0": {stale}}))
+	if got != nil {
+		t.Fatalf("disabled disconnect sent %v", kinds(got))
+	}
+
+	n.ToggleFailed("wg1", true, errors.New("boom"))
+	if last := sent[len(sent)-1]; last.Kind != ToggleFailed || last.Title != "Failed to activate wg1" || last.Message != "boom" {
+		t.Fatalf("toggle failure = %+v", last)
+	}
+
+	n.ScanChanged("wg0", "1 new host")
+	if last := sent[len(sent)-1]; last.Kind != ScanChanged || last.Title != "Scan of wg0 found changes" || last.Message != "1 new host" {
+		t.Fatalf("scan change = %+v", last)
+	}
+	if len(sent) != 5 {
+		t.Fatalf("sent %d notifications, want 5: %v", len(sent), kinds(sent))
+	}
+}
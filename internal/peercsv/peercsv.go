@@ -0,0 +1,182 @@
+package peercsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"wgAdmin/internal/ipam"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Columns lists the CSV columns in order. Only name is required; a header
+// row starting with "name" is skipped.
+var Columns = []string{"name", "public_key", "allowed_ips", "keepalive"}
+
+// Row is one parsed CSV line. Err is set when the row cannot be imported;
+// PrivateKey is set when the key pair was generated.
+type Row struct {
+	Line       int
+	Peer       config.PeerConfig
+	PrivateKey string
+	Err        error
+}
+
+// Plan is the dry run of an import
+type Plan struct {
+	Rows []Row
+}
+
+// Valid returns the rows that can be imported
+func (p *Plan) Valid() []Row {
+	var out []Row
+	for _, r := range p.Rows {
+		if r.Err == nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Invalid returns the number of rows with errors
+func (p *Plan) Invalid() int {
+	return len(p.Rows) - len(p.Valid())
+}
+
+// Parse reads peers from r and validates every row against the existing
+// peers and the rows before it. Missing keys are generated and missing
+// AllowedIPs take the next free address of subnets.
+func Parse(r io.Reader, subnets []net.IPNet, existing []config.PeerConfig) (*Plan, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	taken := append([]config.PeerConfig(nil), existing...)
+	plan := &Plan{}
+	first := true
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(record[0]), Columns[0]) {
+				continue
+			}
+		}
+
+		row := parseRow(record, subnets, taken)
+		row.Line = line
+		if row.Err == nil {
+			taken = append(taken, row.Peer)
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+	if len(plan.Rows) == 0 {
+		return nil, errors.New("the CSV file contains no peers")
+	}
+	return plan, nil
+}
+
+func parseRow(record []string, subnets []net.IPNet, taken []config.PeerConfig) Row {
+	field := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row := Row{Peer: config.PeerConfig{Name: field(0)}}
+	fail := func(format string, args ...any) Row {
+		row.Err = fmt.Errorf(format, args...)
+		return row
+	}
+
+	if len(record) > len(Columns) {
+		return fail("expected at most %d columns, got %d", len(Columns), len(record))
+	}
+	if row.Peer.Name == "" {
+		return fail("name is required")
+	}
+	// The name becomes the client config file name
+	if strings.ContainsAny(row.Peer.Name, `/\|`) || row.Peer.Name == "." || row.Peer.Name == ".." {
+		return fail("invalid name %q", row.Peer.Name)
+	}
+	for _, p := range taken {
+		if peermeta.Name(p.Name) == row.Peer.Name {
+			return fail("name %q is already used", row.Peer.Name)
+		}
+	}
+
+	if pub := field(1); pub != "" {
+		key, err := wgtypes.ParseKey(pub)
+		if err != nil {
+			return fail("invalid public key")
+		}
+		row.Peer.PublicKey = key
+	} else {
+		priv, pub, err := wg.GenerateKeyPair()
+		if err != nil {
+			return fail("generating keys: %v", err)
+		}
+		key, err := wgtypes.ParseKey(pub)
+		if err != nil {
+			return fail("generating keys: %v", err)
+		}
+		row.Peer.PublicKey = key
+		row.PrivateKey = priv
+	}
+	for _, p := range taken {
+		if p.PublicKey == row.Peer.PublicKey {
+			return fail("public key is already used by peer '%s'", peermeta.Name(p.Name))
+		}
+	}
+
+	if ips := field(2); ips != "" {
+		// Several addresses may be separated by spaces or semicolons so
+		// the column does not need quoting
+		ips = strings.NewReplacer(";", ",", " ", ",").Replace(ips)
+		nets, err := ipam.ParseCIDRList(ips)
+		if err != nil {
+			return fail("%v", err)
+		}
+		row.Peer.AllowedIPs = nets
+	} else {
+		if len(subnets) == 0 {
+			return fail("allowed IPs are required when the tunnel has no address")
+		}
+		free, err := ipam.Next(subnets, taken)
+		if err != nil {
+			return fail("no free address: %v", err)
+		}
+		row.Peer.AllowedIPs = free
+	}
+	if len(row.Peer.AllowedIPs) == 0 {
+		return fail("allowed IPs are required")
+	}
+	if err := ipam.CheckOverlap(row.Peer, taken, -1); err != nil {
+		return fail("%v", err)
+	}
+
+	if ka := field(3); ka != "" {
+		keepalive, err := strconv.Atoi(ka)
+		if err != nil || keepalive < 0 || keepalive > 65535 {
+			return fail("keepalive must be 0-65535")
+		}
+		row.Peer.PersistentKeepalive = keepalive
+	}
+	return row
+}
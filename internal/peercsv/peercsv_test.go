@@ -0,0 +1,99 @@
+package peercsv
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestParse(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.8.0.0/24")
+	subnet.IP = net.IPv4(10, 8, 0, 1).To4()
+	_, taken, _ := net.ParseCIDR("10.8.0.2/32")
+	existing := []config.PeerConfig{{Name: "server-b", AllowedIPs: []net.IPNet{*taken}}}
+
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.PublicKey().String()
+
+	csv := strings.Join([]string{
+		"name,public_key,allowed_ips,keepalive",
+		"laptop-1,,,25",
+		"laptop-2," + pub + ",10.8.0.50/32;10.9.0.0/24,",
+		"# comment lines are skipped",
+		",,,",
+		"laptop-1,,,",
+		"phone,not-a-key,,",
+		"tablet,,10.8.0.3/32,",
+		"desk,,,70000",
+		`"quoted, name",,,`,
+	}, "\n")
+
+	plan, err := Parse(strings.NewReader(csv), []net.IPNet{*subnet}, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line int
+		name string
+		ips  string
+		err  string
+	}{
+		{2, "laptop-1", "10.8.0.3/32", ""},
+		{3, "laptop-2", "10.8.0.50/32,10.9.0.0/24", ""},
+		{5, "", "", "name is required"},
+		{6, "laptop-1", "", "already used"},
+		{7, "phone", "", "invalid public key"},
+		{8, "tablet", "", "overlaps"},
+		{9, "desk", "", "keepalive"},
+		{10, "quoted, name", "10.8.0.4/32", ""},
+	}
+	if len(plan.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(plan.Rows), len(want), plan.Rows)
+	}
+	for i, w := range want {
+		r := plan.Rows[i]
+		if r.Line != w.line || r.Peer.Name != w.name {
+			t.Errorf("row %d = line %d %q, want line %d %q", i, r.Line, r.Peer.Name, w.line, w.name)
+		}
+		if w.err != "" {
+			if r.Err == nil || !strings.Contains(r.Err.Error(), w.err) {
+				t.Errorf("row %d error = %v, want %q", i, r.Err, w.err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("row %d: unexpected error %v", i, r.Err)
+			continue
+		}
+		var ips []string
+		for _, n := range r.Peer.AllowedIPs {
+			ips = append(ips, n.String())
+		}
+		if got := strings.Join(ips, ","); got != w.ips {
+			t.Errorf("row %d allowed IPs = %s, want %s", i, got, w.ips)
+		}
+	}
+
+	if first := plan.Rows[0]; first.PrivateKey == "" || first.Peer.PersistentKeepalive != 25 {
+		t.Errorf("laptop-1 = %+v, want generated keys and keepalive 25", first)
+	}
+	if second := plan.Rows[1]; second.PrivateKey != "" || second.Peer.PublicKey.String() != pub {
+		t.Errorf("laptop-2 should keep the given public key")
+	}
+	if n := len(plan.Valid()); n != 3 || plan.Invalid() != 5 {
+		t.Errorf("valid = %d, invalid = %d; want 3 and 5", n, plan.Invalid())
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	if _, err := Parse(strings.NewReader("name,public_key\n"), nil, nil); err == nil {
+		t.Fatal("expected an error for a CSV without peers")
+	}
+}
@@ -0,0 +1,92 @@
+package peermeta
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// go-wg only keeps a peer's Name (the "# Name = ..." comment), so wgAdmin
+// appends its own metadata to it:
+//
+//	# Name = alice | expires=2026-10-24T12:00:00Z disabled
+//
+// Unknown keys are kept so newer metadata survives an older wgAdmin.
+const separator = " | "
+
+// Meta is a peer name split into the display name and wgAdmin's metadata
+type Meta struct {
+	Name     string
+	Expires  time.Time // zero when the peer does not expire
+	Disabled bool      // kept in the config but not loaded into the device
+	extra    []string  // unknown key=value pairs, kept as is
+}
+
+// Parse splits a stored peer name
+func Parse(stored string) Meta {
+	name, rest, found := strings.Cut(stored, separator)
+	m := Meta{Name: strings.TrimSpace(name)}
+	if !found {
+		return m
+	}
+	for _, field := range strings.Fields(rest) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "disabled":
+			m.Disabled = true
+			continue
+		case "expires":
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				m.Expires = t
+				continue
+			}
+		}
+		m.extra = append(m.extra, field)
+	}
+	return m
+}
+
+// String returns the name to store in the config
+func (m Meta) String() string {
+	var fields []string
+	if !m.Expires.IsZero() {
+		fields = append(fields, "expires="+m.Expires.UTC().Format(time.RFC3339))
+	}
+	if m.Disabled {
+		fields = append(fields, "disabled")
+	}
+	fields = append(fields, m.extra...)
+	if len(fields) == 0 {
+		return m.Name
+	}
+	return m.Name + separator + strings.Join(fields, " ")
+}
+
+// Name returns the display name of a stored peer name
+func Name(stored string) string {
+	return Parse(stored).Name
+}
+
+// Expired reports whether the peer has an expiry at or before now
+func (m Meta) Expired(now time.Time) bool {
+	return !m.Expires.IsZero() && !m.Expires.After(now)
+}
+
+// Remaining describes the time left until the peer expires, or "" when it
+// does not expire
+func (m Meta) Remaining(now time.Time) string {
+	if m.Expires.IsZero() {
+		return ""
+	}
+	left := m.Expires.Sub(now)
+	switch {
+	case left <= 0:
+		return "expired"
+	case left < time.Hour:
+		return fmt.Sprintf("expires in %dm", int(left.Minutes())+1)
+	case left < 48*time.Hour:
+		return fmt.Sprintf("expires in %dh", int(left.Hours()))
+	default:
+		return fmt.Sprintf("expires in %dd", int(left.Hours()/24))
+	}
+}
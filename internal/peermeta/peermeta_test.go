@@ -0,0 +1,67 @@
+package peermeta
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	expires := time.Date(2026, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	stored := Meta{Name: "alice", Expires: expires}.String()
+	if want := "alice | expires=2026-10-24T12:00:00Z"; stored != want {
+		t.Fatalf("String() = %q, want %q", stored, want)
+	}
+	m := Parse(stored)
+	if m.Name != "alice" || !m.Expires.Equal(expires) {
+		t.Fatalf("Parse(%q) = %+v", stored, m)
+	}
+
+	if got := (Meta{Name: "bob"}).String(); got != "bob" {
+		t.Errorf("a name without metadata is stored as %q", got)
+	}
+	if got := Name("carol | expires=2026-10-24T12:00:00Z future=1"); got != "carol" {
+		t.Errorf("Name() = %q", got)
+	}
+
+	m = Parse("erin | expires=2026-10-24T12:00:00Z disabled")
+	if !m.Disabled || !m.Expires.Equal(expires) {
+		t.Errorf("disabled peer parsed as %+v", m)
+	}
+	m.Disabled = false
+	if got := m.String(); got != "erin | expires=2026-10-24T12:00:00Z" {
+		t.Errorf("after enabling = %q", got)
+	}
+
+	// Unknown metadata survives an edit of the known fields
+	m = Parse("dave | future=1 expires=2026-10-24T12:00:00Z")
+	m.Expires = time.Time{}
+	if got := m.String(); got != "dave | future=1" {
+		t.Errorf("after clearing the expiry = %q", got)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expires time.Time
+		want    string
+		expired bool
+	}{
+		{time.Time{}, "", false},
+		{now.Add(-time.Minute), "expired", true},
+		{now, "expired", true},
+		{now.Add(10 * time.Minute), "expires in 11m", false},
+		{now.Add(5 * time.Hour), "expires in 5h", false},
+		{now.Add(7 * 24 * time.Hour), "expires in 7d", false},
+	}
+	for _, tt := range tests {
+		m := Meta{Name: "x", Expires: tt.expires}
+		if got := m.Remaining(now); got != tt.want {
+			t.Errorf("Remaining(%v) = %q, want %q", tt.expires, got, tt.want)
+		}
+		if got := m.Expired(now); got != tt.expired {
+			t.Errorf("Expired(%v) = %v, want %v", tt.expires, got, tt.expired)
+		}
+	}
+}
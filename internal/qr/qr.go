@@ -0,0 +1,59 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// moduleSize is the edge length of one QR module in SVG output units
+const moduleSize = 8
+
+// Code is a QR code for a client config
+type Code struct {
+	qr *qrcode.QRCode
+}
+
+// New encodes content using medium error correction, which keeps a full
+// WireGuard client config readable by phone cameras
+func New(content string) (*Code, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	return &Code{qr: q}, nil
+}
+
+// Image renders the code as a size x size pixel image
+func (c *Code) Image(size int) image.Image {
+	return c.qr.Image(size)
+}
+
+// PNG renders the code as a size x size pixel PNG
+func (c *Code) PNG(size int) ([]byte, error) {
+	return c.qr.PNG(size)
+}
+
+// SVG renders the code as a scalable SVG document, one rect per dark module
+func (c *Code) SVG() []byte {
+	bitmap := c.qr.Bitmap()
+	size := len(bitmap) * moduleSize
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size, size, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", size, size)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#000000"/>`+"\n",
+					x*moduleSize, y*moduleSize, moduleSize, moduleSize)
+			}
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
@@ -0,0 +1,166 @@
+package reachability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// TCP probes by connecting to port. A refused connection still proves the
+// peer answered, so the port does not have to be open.
+func TCP(port int) ProbeFunc {
+	return func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		dialer := net.Dialer{Timeout: timeout}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, uint16(port)).String())
+		rtt := time.Since(start)
+		if err == nil {
+			conn.Close()
+			return rtt, nil
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return rtt, nil
+		}
+		return 0, err
+	}
+}
+
+// icmpNetworks are the sockets tried for ICMP echo, unprivileged ping
+// sockets first, per IP version
+var icmpNetworks = map[bool][]string{
+	true:  {"udp4", "ip4:icmp"},
+	false: {"udp6", "ip6:ipv6-icmp"},
+}
+
+var (
+	icmpOnce    sync.Once
+	icmpNetwork map[bool]string // the permitted network per IP version, if any
+	icmpSeq     atomic.Uint32
+)
+
+// icmpPermitted returns the ICMP socket network this process may open for
+// the IP version of addr, or "" when neither is permitted
+func icmpPermitted(addr netip.Addr) string {
+	icmpOnce.Do(func() {
+		icmpNetwork = make(map[bool]string)
+		for is4, networks := range icmpNetworks {
+			for _, network := range networks {
+				local := "0.0.0.0"
+				if !is4 {
+					local = "::"
+				}
+				if c, err := icmp.ListenPacket(network, local); err == nil {
+					c.Close()
+					icmpNetwork[is4] = network
+					break
+				}
+			}
+		}
+	})
+	return icmpNetwork[addr.Is4()]
+}
+
+// ICMPPermitted reports whether ICMP echo can be sent to addr
+func ICMPPermitted(addr netip.Addr) bool {
+	return icmpPermitted(addr) != ""
+}
+
+// ICMP probes with an echo request. It fails when ICMP sockets are not
+// permitted; Auto falls back to TCP then.
+func ICMP(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+	network := icmpPermitted(addr)
+	if network == "" {
+		return 0, fmt.Errorf("icmp to %s: not permitted", addr)
+	}
+
+	local, proto := "0.0.0.0", 1
+	var typ, reply icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if !addr.Is4() {
+		local, proto = "::", 58
+		typ, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	c, err := icmp.ListenPacket(network, local)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+	defer stop()
+
+	// Ping sockets replace the ID with their port; raw sockets see every
+	// reply, so the ID and sequence are both checked
+	id, seq := os.Getpid()&0xffff, int(icmpSeq.Add(1)&0xffff)
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("wgAdmin")}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+	var dst net.Addr = &net.IPAddr{IP: addr.AsSlice()}
+	if network == "udp4" || network == "udp6" {
+		dst = &net.UDPAddr{IP: addr.AsSlice()}
+	}
+
+	start := time.Now()
+	if _, err := c.WriteTo(b, dst); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := c.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+		if fromAddr(from) != addr {
+			continue
+		}
+		m, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || m.Type != reply {
+			continue
+		}
+		echo, ok := m.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (network != "udp4" && network != "udp6" && echo.ID != id) {
+			continue
+		}
+		return time.Since(start), nil
+	}
+}
+
+func fromAddr(a net.Addr) netip.Addr {
+	var ip net.IP
+	switch a := a.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.IPAddr:
+		ip = a.IP
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return addr.Unmap()
+}
+
+// Auto probes with ICMP when permitted, otherwise by connecting to port
+func Auto(port int) ProbeFunc {
+	tcp := TCP(port)
+	return func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		if ICMPPermitted(addr) {
+			return ICMP(ctx, addr, timeout)
+		}
+		return tcp(ctx, addr, timeout)
+	}
+}
@@ -0,0 +1,217 @@
+// Package reachability probes the tunnel address of every peer at an
+// interval and keeps the recent latency and loss of each
+package reachability
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Health thresholds: a peer is degraded above either limit and down after
+// this many probes in a row got no answer
+const (
+	DegradedLoss    = 0.2
+	DegradedLatency = 250 * time.Millisecond
+	DownAfter       = 3
+)
+
+// DefaultKeep is the number of samples kept per peer, 20 minutes at the
+// default interval
+const DefaultKeep = 40
+
+// maxProbes bounds the probes in flight during one round
+const maxProbes = 16
+
+// Sample is the outcome of one probe
+type Sample struct {
+	Time    time.Time
+	Latency time.Duration // zero when lost
+	OK      bool
+}
+
+// Health summarizes the recent samples of a peer
+type Health int
+
+// Health levels, from no data to unreachable
+const (
+	Unknown Health = iota
+	Good
+	Degraded
+	Down
+)
+
+func (h Health) String() string {
+	switch h {
+	case Good:
+		return "good"
+	case Degraded:
+		return "degraded"
+	case Down:
+		return "down"
+	}
+	return "unknown"
+}
+
+// Stats are the recent samples of a peer and what they add up to
+type Stats struct {
+	Samples []Sample // oldest first
+	Loss    float64  // share of lost probes, 0 to 1
+	Avg     time.Duration
+	Health  Health
+}
+
+// Summarize computes the loss, average latency and health of samples
+func Summarize(samples []Sample) Stats {
+	s := Stats{Samples: samples}
+	if len(samples) == 0 {
+		return s
+	}
+
+	var lost int
+	var total time.Duration
+	for _, sample := range samples {
+		if !sample.OK {
+			lost++
+			continue
+		}
+		total += sample.Latency
+	}
+	s.Loss = float64(lost) / float64(len(samples))
+	if ok := len(samples) - lost; ok > 0 {
+		s.Avg = total / time.Duration(ok)
+	}
+
+	missed := 0
+	for i := len(samples) - 1; i >= 0 && !samples[i].OK; i-- {
+		missed++
+	}
+	switch {
+	case missed >= DownAfter || missed == len(samples):
+		s.Health = Down
+	case s.Loss > DegradedLoss || s.Avg > DegradedLatency:
+		s.Health = Degraded
+	default:
+		s.Health = Good
+	}
+	return s
+}
+
+// Summary describes the stats in a few words, e.g. "12.3 ms, 5% loss"
+func (s Stats) Summary() string {
+	switch {
+	case len(s.Samples) == 0:
+		return "not probed yet"
+	case s.Health == Down:
+		return fmt.Sprintf("down, %.0f%% loss", s.Loss*100)
+	}
+	return fmt.Sprintf("%.1f ms, %.0f%% loss", float64(s.Avg.Microseconds())/1000, s.Loss*100)
+}
+
+// Target is the tunnel address of one peer
+type Target struct {
+	Tunnel    string
+	PublicKey string
+	Addr      netip.Addr
+}
+
+// Address returns the tunnel address of a peer: its first allowed IP that
+// is a single host. Peers routing only networks have none.
+func Address(allowedIPs []net.IPNet) (netip.Addr, bool) {
+	for _, n := range allowedIPs {
+		ones, bits := n.Mask.Size()
+		if ones != bits || bits == 0 {
+			continue
+		}
+		if addr, ok := netip.AddrFromSlice(n.IP); ok {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// ProbeFunc sends one probe to addr and returns the round trip time
+type ProbeFunc func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error)
+
+// Monitor keeps the latest samples of every probed peer
+type Monitor struct {
+	mu      sync.Mutex
+	keep    int
+	samples map[string][]Sample // by tunnel and public key
+}
+
+// New creates a monitor keeping up to keep samples per peer
+func New(keep int) *Monitor {
+	return &Monitor{keep: keep, samples: make(map[string][]Sample)}
+}
+
+func key(tunnel, publicKey string) string {
+	return tunnel + "/" + publicKey
+}
+
+// Record adds a sample for the peer of t
+func (m *Monitor) Record(t Target, s Sample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := key(t.Tunnel, t.PublicKey)
+	samples := append(m.samples[k], s)
+	if m.keep > 0 && len(samples) > m.keep {
+		samples = samples[len(samples)-m.keep:]
+	}
+	m.samples[k] = samples
+}
+
+// Stats returns the stats of a peer; Unknown health when it was never probed
+func (m *Monitor) Stats(tunnel, publicKey string) Stats {
+	m.mu.Lock()
+	samples := append([]Sample(nil), m.samples[key(tunnel, publicKey)]...)
+	m.mu.Unlock()
+	return Summarize(samples)
+}
+
+// Run probes every target once, at most maxProbes at a time, and records
+// the samples. Peers that are no longer targets are forgotten. A canceled
+// ctx records nothing more.
+func (m *Monitor) Run(ctx context.Context, targets []Target, probe ProbeFunc, timeout time.Duration) {
+	m.prune(targets)
+
+	sem := make(chan struct{}, maxProbes)
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			start := time.Now()
+			rtt, err := probe(ctx, t.Addr, timeout)
+			if ctx.Err() != nil {
+				return
+			}
+			m.Record(t, Sample{Time: start, Latency: rtt, OK: err == nil})
+		}()
+	}
+	wg.Wait()
+}
+
+// prune drops the samples of peers missing from targets
+func (m *Monitor) prune(targets []Target) {
+	wanted := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		wanted[key(t.Tunnel, t.PublicKey)] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k := range m.samples {
+		if !wanted[k] {
+			delete(m.samples, k)
+		}
+	}
+}
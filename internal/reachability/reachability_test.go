@@ -0,0 +1,118 @@
+package reachability
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func samples(latencies ...time.Duration) []Sample {
+	out := make([]Sample, len(latencies))
+	for i, l := range latencies {
+		// A negative latency stands for a lost probe
+		out[i] = Sample{Latency: max(l, 0), OK: l >= 0}
+	}
+	return out
+}
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		samples []Sample
+		health  Health
+		summary string
+	}{
+		{"none", nil, Unknown, "not probed yet"},
+		{"good", samples(10*ms, 20*ms, -1, 30*ms, 10*ms, 20*ms), Good, "18.0 ms, 17% loss"},
+		{"slow", samples(300*ms, 400*ms), Degraded, "350.0 ms, 0% loss"},
+		{"lossy", samples(10*ms, -1, 10*ms, -1), Degraded, "10.0 ms, 50% loss"},
+		{"down", samples(10*ms, -1, -1, -1), Down, "down, 75% loss"},
+		{"never answered", samples(-1), Down, "down, 100% loss"},
+		{"recovered", samples(-1, -1, -1, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms, 10*ms), Degraded, "10.0 ms, 21% loss"},
+	}
+	for _, tt := range tests {
+		s := Summarize(tt.samples)
+		if s.Health != tt.health || s.Summary() != tt.summary {
+			t.Errorf("%s: health %s %q, want %s %q", tt.name, s.Health, s.Summary(), tt.health, tt.summary)
+		}
+	}
+}
+
+func TestAddress(t *testing.T) {
+	parse := func(cidrs ...string) []net.IPNet {
+		var out []net.IPNet
+		for _, c := range cidrs {
+			_, n, err := net.ParseCIDR(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, *n)
+		}
+		return out
+	}
+	for _, tt := range []struct {
+		allowed []net.IPNet
+		want    string
+	}{
+		{parse("10.8.0.2/32"), "10.8.0.2"},
+		{parse("192.168.1.0/24", "fd00::2/128"), "fd00::2"},
+		{parse("0.0.0.0/0"), ""},
+		{nil, ""},
+	} {
+		addr, ok := Address(tt.allowed)
+		if got := map[bool]string{true: addr.String(), false: ""}[ok]; got != tt.want {
+			t.Errorf("Address(%v) = %q, want %q", tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	m := New(3)
+	up := Target{Tunnel: "wg0", PublicKey: "up", Addr: netip.MustParseAddr("10.8.0.2")}
+	down := Target{Tunnel: "wg0", PublicKey: "down", Addr: netip.MustParseAddr("10.8.0.3")}
+	probe := func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		if addr == down.Addr {
+			return 0, errors.New("timeout")
+		}
+		return 5 * time.Millisecond, nil
+	}
+
+	for range 4 {
+		m.Run(context.Background(), []Target{up, down}, probe, time.Second)
+	}
+	if s := m.Stats("wg0", "up"); len(s.Samples) != 3 || s.Health != Good || s.Avg != 5*time.Millisecond {
+		t.Errorf("up = %+v, want 3 good samples of 5ms", s)
+	}
+	if s := m.Stats("wg0", "down"); s.Health != Down || s.Loss != 1 {
+		t.Errorf("down = %+v, want all lost", s)
+	}
+
+	// Peers that are no longer targets are forgotten
+	m.Run(context.Background(), []Target{up}, probe, time.Second)
+	if s := m.Stats("wg0", "down"); s.Health != Unknown || len(s.Samples) != 0 {
+		t.Errorf("removed peer still has stats %+v", s)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	probe := TCP(port)
+	addr := netip.MustParseAddr("127.0.0.1")
+
+	if _, err := probe(context.Background(), addr, time.Second); err != nil {
+		t.Errorf("open port: %v", err)
+	}
+	// A refused connection still counts as an answer
+	ln.Close()
+	if _, err := probe(context.Background(), addr, time.Second); err != nil {
+		t.Errorf("refused port: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+// Package scanwatch keeps the results of scheduled scans per tunnel and
+// reports what changed between consecutive runs
+package scanwatch
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wgAdmin/internal/netscan"
+)
+
+// Run is one completed scan of a tunnel network
+type Run struct {
+	Time    time.Time        `json:"time"`
+	CIDR    string           `json:"cidr"`
+	Ports   []int            `json:"ports"`
+	Results []netscan.Result `json:"results"`
+	Changes Changes          `json:"changes"` // against the previous run
+}
+
+// PortChange is a port that opened or closed on a host
+type PortChange struct {
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+}
+
+// Changes is the difference between two runs
+type Changes struct {
+	NewHosts    []string     `json:"new_hosts,omitempty"`
+	GoneHosts   []string     `json:"gone_hosts,omitempty"`
+	OpenedPorts []PortChange `json:"opened_ports,omitempty"`
+	ClosedPorts []PortChange `json:"closed_ports,omitempty"`
+}
+
+// Empty reports whether nothing changed
+func (c Changes) Empty() bool {
+	return len(c.NewHosts) == 0 && len(c.GoneHosts) == 0 && len(c.OpenedPorts) == 0 && len(c.ClosedPorts) == 0
+}
+
+// String summarizes the changes, e.g. "1 new host, 2 ports opened"
+func (c Changes) String() string {
+	if c.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	add := func(n int, one, many string) {
+		switch {
+		case n == 1:
+			parts = append(parts, one)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf(many, n))
+		}
+	}
+	add(len(c.NewHosts), "1 new host", "%d new hosts")
+	add(len(c.GoneHosts), "1 host vanished", "%d hosts vanished")
+	add(len(c.OpenedPorts), "1 port opened", "%d ports opened")
+	add(len(c.ClosedPorts), "1 port closed", "%d ports closed")
+	return strings.Join(parts, ", ")
+}
+
+// Diff compares two runs. Ports only count as opened or closed when both
+// runs checked them, so changing the port list does not report changes.
+func Diff(prev, next Run) Changes {
+	before := byIP(prev.Results)
+	after := byIP(next.Results)
+	checked := make(map[int]bool, len(prev.Ports))
+	for _, p := range prev.Ports {
+		if slices.Contains(next.Ports, p) {
+			checked[p] = true
+		}
+	}
+
+	var c Changes
+	for ip, r := range after {
+		old, ok := before[ip]
+		if !ok {
+			c.NewHosts = append(c.NewHosts, ip)
+			continue
+		}
+		for _, p := range r.PortsOpen {
+			if checked[p] && !slices.Contains(old.PortsOpen, p) {
+				c.OpenedPorts = append(c.OpenedPorts, PortChange{IP: ip, Port: p})
+			}
+		}
+		for _, p := range old.PortsOpen {
+			if checked[p] && !slices.Contains(r.PortsOpen, p) {
+				c.ClosedPorts = append(c.ClosedPorts, PortChange{IP: ip, Port: p})
+			}
+		}
+	}
+	for ip := range before {
+		if _, ok := after[ip]; !ok {
+			c.GoneHosts = append(c.GoneHosts, ip)
+		}
+	}
+
+	sortIPs(c.NewHosts)
+	sortIPs(c.GoneHosts)
+	sortPorts(c.OpenedPorts)
+	sortPorts(c.ClosedPorts)
+	return c
+}
+
+func byIP(results []netscan.Result) map[string]netscan.Result {
+	m := make(map[string]netscan.Result, len(results))
+	for _, r := range results {
+		m[r.IP] = r
+	}
+	return m
+}
+
+func sortIPs(ips []string) {
+	slices.SortFunc(ips, compareIP)
+}
+
+func sortPorts(changes []PortChange) {
+	slices.SortFunc(changes, func(a, b PortChange) int {
+		if c := compareIP(a.IP, b.IP); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Port, b.Port)
+	})
+}
+
+// compareIP orders addresses numerically
+func compareIP(a, b string) int {
+	x, errX := netip.ParseAddr(a)
+	y, errY := netip.ParseAddr(b)
+	if errX != nil || errY != nil {
+		return strings.Compare(a, b)
+	}
+	return x.Compare(y)
+}
+
+// History keeps the latest runs of every tunnel
+type History struct {
+	mu   sync.Mutex
+	keep int
+	runs map[string][]Run
+}
+
+// NewHistory creates a history keeping up to keep runs per tunnel
+func NewHistory(keep int) *History {
+	return &History{keep: keep, runs: make(map[string][]Run)}
+}
+
+// Add stores run for tunnel with its changes against the previous run and
+// returns the stored run. The first run of a tunnel has no changes.
+func (h *History) Add(tunnel string, run Run) Run {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	runs := h.runs[tunnel]
+	run.Changes = Changes{}
+	if len(runs) > 0 {
+		run.Changes = Diff(runs[len(runs)-1], run)
+	}
+	runs = append(runs, run)
+	if h.keep > 0 && len(runs) > h.keep {
+		runs = runs[len(runs)-h.keep:]
+	}
+	h.runs[tunnel] = runs
+	return run
+}
+
+// Runs returns the stored runs of tunnel, newest first
+func (h *History) Runs(tunnel string) []Run {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	runs := slices.Clone(h.runs[tunnel])
+	slices.Reverse(runs)
+	return runs
+}
+
+// Save writes all runs to path as JSON
+func (h *History) Save(path string) error {
+	h.mu.Lock()
+	b, err := json.Marshal(h.runs)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load replaces the stored runs with those saved at path.
+// A missing file is not an error.
+func (h *History) Load(path string) error {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var runs map[string][]Run
+	if err := json.Unmarshal(b, &runs); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs = runs
+	if h.runs == nil {
+		h.runs = make(map[string][]Run)
+	}
+	return nil
+}
+
+// Entry schedules scans of one tunnel
+type Entry struct {
+	Tunnel   string
+	Interval time.Duration
+}
+
+// ParseSchedule parses "tunnel=minutes" entries separated by ';', ',' or
+// newlines, e.g. "wg0=60; wg1=15"
+func ParseSchedule(text string) ([]Entry, error) {
+	var entries []Entry
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == ',' || r == '\n' }) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		tunnel, minutes, ok := strings.Cut(field, "=")
+		tunnel = strings.TrimSpace(tunnel)
+		if !ok || tunnel == "" {
+			return nil, fmt.Errorf("scheduled scan %q: want tunnel=minutes", field)
+		}
+		if seen[tunnel] {
+			return nil, fmt.Errorf("scheduled scan %s: listed twice", tunnel)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("scheduled scan %s: interval must be a number of minutes >= 1", tunnel)
+		}
+		seen[tunnel] = true
+		entries = append(entries, Entry{Tunnel: tunnel, Interval: time.Duration(n) * time.Minute})
+	}
+	return entries, nil
+}
+
+// FormatSchedule is the inverse of ParseSchedule
+func FormatSchedule(entries []Entry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s=%d", e.Tunnel, int(e.Interval/time.Minute))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Scan scans cidr to completion and returns the run, without changes. A
+// canceled ctx returns its error rather than a partial run.
+func Scan(ctx context.Context, cidr string, ports []int, workers int, timeout time.Duration) (Run, error) {
+	s, err := netscan.New(cidr, ports, workers, timeout)
+	if err != nil {
+		return Run{}, err
+	}
+	run := Run{Time: time.Now(), CIDR: cidr, Ports: ports}
+	s.Run(ctx)
+	for r := range s.Results {
+		run.Results = append(run.Results, r)
+	}
+	if err := ctx.Err(); err != nil {
+		return Run{}, err
+	}
+	netscan.Sort(run.Results, netscan.ByIP, false)
+	return run, nil
+}
@@ -0,0 +1,131 @@
+package scanwatch
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/netscan"
+)
+
+func TestDiff(t *testing.T) {
+	prev := Run{Ports: []int{22, 80}, Results: []netscan.Result{
+		{IP: "10.8.0.10", PortsOpen: []int{22}},
+		{IP: "10.8.0.2", PortsOpen: []int{22, 80}},
+		{IP: "10.8.0.3"},
+	}}
+	next := Run{Ports: []int{22, 80, 443}, Results: []netscan.Result{
+		{IP: "10.8.0.10", PortsOpen: []int{22, 80, 443}},
+		{IP: "10.8.0.2", PortsOpen: []int{80}},
+		{IP: "10.8.0.9", PortsOpen: []int{443}},
+	}}
+
+	got := Diff(prev, next)
+	want := Changes{
+		NewHosts:    []string{"10.8.0.9"},
+		GoneHosts:   []string{"10.8.0.3"},
+		OpenedPorts: []PortChange{{IP: "10.8.0.10", Port: 80}}, // 443 was not checked before
+		ClosedPorts: []PortChange{{IP: "10.8.0.2", Port: 22}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "1 new host, 1 host vanished, 1 port opened, 1 port closed" {
+		t.Errorf("String() = %q", s)
+	}
+	if !Diff(next, next).Empty() {
+		t.Error("Diff of a run with itself is not empty")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(2)
+	run := func(ips ...string) Run {
+		r := Run{Ports: []int{22}}
+		for _, ip := range ips {
+			r.Results = append(r.Results, netscan.Result{IP: ip, PortsOpen: []int{22}})
+		}
+		return r
+	}
+
+	if first := h.Add("wg0", run("10.8.0.2")); !first.Changes.Empty() {
+		t.Errorf("first run has changes %v", first.Changes)
+	}
+	h.Add("wg0", run("10.8.0.2", "10.8.0.3"))
+	last := h.Add("wg0", run("10.8.0.3"))
+	if !reflect.DeepEqual(last.Changes.GoneHosts, []string{"10.8.0.2"}) {
+		t.Errorf("changes = %+v, want 10.8.0.2 gone", last.Changes)
+	}
+
+	runs := h.Runs("wg0")
+	if len(runs) != 2 || len(runs[0].Results) != 1 || len(runs[1].Results) != 2 {
+		t.Fatalf("Runs = %+v, want the last two, newest first", runs)
+	}
+	if len(h.Runs("wg1")) != 0 {
+		t.Error("Runs of an unscanned tunnel is not empty")
+	}
+
+	path := filepath.Join(t.TempDir(), "history", "scans.json")
+	if err := h.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewHistory(2)
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Runs("wg0"); !reflect.DeepEqual(got, runs) {
+		t.Errorf("loaded %+v, want %+v", got, runs)
+	}
+	if err := NewHistory(2).Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Load of a missing file: %v", err)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	entries, err := ParseSchedule("wg0=60;\n wg1 = 15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{Tunnel: "wg0", Interval: time.Hour}, {Tunnel: "wg1", Interval: 15 * time.Minute}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("ParseSchedule = %+v, want %+v", entries, want)
+	}
+	if text := FormatSchedule(entries); text != "wg0=60; wg1=15" {
+		t.Errorf("FormatSchedule = %q", text)
+	}
+	if entries, err := ParseSchedule(""); err != nil || len(entries) != 0 {
+		t.Errorf("ParseSchedule(\"\") = %v, %v", entries, err)
+	}
+
+	for _, bad := range []string{"wg0", "=5", "wg0=0", "wg0=x", "wg0=5; wg0=10"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	run, err := Scan(context.Background(), "127.0.0.1/32", []int{port}, 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.CIDR != "127.0.0.1/32" || len(run.Results) != 1 || run.Results[0].IP != "127.0.0.1" {
+		t.Errorf("run = %+v", run)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Scan(ctx, "127.0.0.1/32", []int{port}, 2, time.Second); err == nil {
+		t.Error("canceled scan succeeded")
+	}
+}
@@ -0,0 +1,133 @@
+// Package search finds tunnels and peers by name, public key, address or
+// endpoint
+package search
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/peermeta"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// Fields a query can match
+const (
+	FieldTunnel    = "tunnel"
+	FieldAddress   = "address"
+	FieldName      = "name"
+	FieldKey       = "public key"
+	FieldAllowedIP = "allowed IP"
+	FieldEndpoint  = "endpoint"
+)
+
+// Tunnel is the searchable state of one tunnel
+type Tunnel struct {
+	Name   string
+	Config *config.Config         // nil when the config could not be read
+	Stats  []controller.PeerStats // live peers, for endpoints learned by the device
+}
+
+// Match is one tunnel or peer that matched a query. Peer is -1 when the
+// tunnel itself matched, otherwise the index into Config.Peers.
+type Match struct {
+	Tunnel    string
+	Peer      int
+	PeerName  string
+	PublicKey string
+	Field     string
+	Value     string
+}
+
+// Find returns the matches of query in tunnels, one per tunnel or peer.
+// Names, addresses and endpoints match by case-insensitive substring, public
+// keys by prefix. A query that is an IP address matches the peers and
+// interfaces whose networks contain it, which answers "which tunnel routes
+// 10.8.0.14?".
+func Find(tunnels []Tunnel, query string) []Match {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	var contains func(net.IPNet) bool
+	if addr, err := netip.ParseAddr(query); err == nil {
+		contains = func(n net.IPNet) bool { return n.Contains(net.IP(addr.AsSlice())) }
+	} else {
+		lower := strings.ToLower(query)
+		contains = func(n net.IPNet) bool { return strings.Contains(n.String(), lower) }
+	}
+	text := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), strings.ToLower(query))
+	}
+
+	var matches []Match
+	for _, t := range tunnels {
+		tunnel := Match{Tunnel: t.Name, Peer: -1}
+		if text(t.Name) {
+			tunnel.Field, tunnel.Value = FieldTunnel, t.Name
+		} else if t.Config != nil {
+			for _, a := range t.Config.Interface.Address {
+				if contains(a) {
+					tunnel.Field, tunnel.Value = FieldAddress, a.String()
+					break
+				}
+			}
+		}
+		if tunnel.Field != "" {
+			matches = append(matches, tunnel)
+		}
+		if t.Config == nil {
+			continue
+		}
+
+		live := make(map[string]string, len(t.Stats))
+		for _, s := range t.Stats {
+			live[s.PublicKey] = s.Endpoint
+		}
+		for i, p := range t.Config.Peers {
+			m := Match{
+				Tunnel:    t.Name,
+				Peer:      i,
+				PeerName:  peermeta.Name(p.Name),
+				PublicKey: p.PublicKey.String(),
+			}
+			m.Field, m.Value = matchPeer(p, m.PeerName, m.PublicKey, live[m.PublicKey], query, text, contains)
+			if m.Field != "" {
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
+
+// matchPeer returns the first field of a peer that matches the query
+func matchPeer(p config.PeerConfig, name, key, liveEndpoint, query string, text func(string) bool, contains func(net.IPNet) bool) (string, string) {
+	if name != "" && text(name) {
+		return FieldName, name
+	}
+	if strings.HasPrefix(key, query) {
+		return FieldKey, key
+	}
+	for _, n := range p.AllowedIPs {
+		if contains(n) {
+			return FieldAllowedIP, n.String()
+		}
+	}
+	for _, endpoint := range []string{p.Endpoint, liveEndpoint} {
+		if endpoint != "" && text(endpoint) {
+			return FieldEndpoint, endpoint
+		}
+	}
+	return "", ""
+}
+
+// Tunnels returns the names of the tunnels that have at least one match
+func Tunnels(matches []Match) map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range matches {
+		names[m.Tunnel] = true
+	}
+	return names
+}
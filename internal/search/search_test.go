@@ -0,0 +1,96 @@
+package search
+
+import (
+	"net"
+	"testing"
+
+	"wgAdmin/internal/controller"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func cidr(t *testing.T, s string) net.IPNet {
+	t.Helper()
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.IP = ip
+	return *n
+}
+
+func testTunnels(t *testing.T) ([]Tunnel, wgtypes.Key) {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := wgtypes.GeneratePrivateKey()
+	return []Tunnel{
+		{
+			Name: "office",
+			Config: &config.Config{
+				Interface: config.InterfaceConfig{Address: []net.IPNet{cidr(t, "10.8.0.1/24")}},
+				Peers: []config.PeerConfig{
+					{Name: "alice | expires=2026-10-24T12:00:00Z", PublicKey: key.PublicKey(), AllowedIPs: []net.IPNet{cidr(t, "10.8.0.14/32")}},
+					{Name: "branch", PublicKey: other.PublicKey(), AllowedIPs: []net.IPNet{cidr(t, "192.168.50.0/24")}},
+				},
+			},
+			Stats: []controller.PeerStats{{PublicKey: other.PublicKey().String(), Endpoint: "203.0.113.7:51820"}},
+		},
+		{Name: "home", Config: &config.Config{Interface: config.InterfaceConfig{Address: []net.IPNet{cidr(t, "10.9.0.1/24")}}}},
+	}, key
+}
+
+func TestFind(t *testing.T) {
+	tunnels, key := testTunnels(t)
+	pub := key.PublicKey().String()
+
+	tests := []struct {
+		query string
+		want  []Match
+	}{
+		{"ALI", []Match{{Tunnel: "office", Peer: 0, PeerName: "alice", PublicKey: pub, Field: FieldName, Value: "alice"}}},
+		{pub[:6], []Match{{Tunnel: "office", Peer: 0, PeerName: "alice", PublicKey: pub, Field: FieldKey, Value: pub}}},
+		{"192.168.50.23", []Match{{Tunnel: "office", Peer: 1, PeerName: "branch", Field: FieldAllowedIP, Value: "192.168.50.0/24"}}},
+		{"203.0.113", []Match{{Tunnel: "office", Peer: 1, PeerName: "branch", Field: FieldEndpoint, Value: "203.0.113.7:51820"}}},
+		{"hom", []Match{{Tunnel: "home", Peer: -1, Field: FieldTunnel, Value: "home"}}},
+		{"nothing", nil},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		got := Find(tunnels, tt.query)
+		if len(got) != len(tt.want) {
+			t.Errorf("Find(%q) = %+v, want %+v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			w := tt.want[i]
+			if w.Peer == 1 {
+				w.PublicKey = tunnels[0].Config.Peers[1].PublicKey.String()
+			}
+			if got[i] != w {
+				t.Errorf("Find(%q)[%d] = %+v, want %+v", tt.query, i, got[i], w)
+			}
+		}
+	}
+}
+
+func TestFindRoutes(t *testing.T) {
+	tunnels, _ := testTunnels(t)
+
+	// The tunnel subnet contains the address and a peer routes it
+	got := Find(tunnels, "10.8.0.14")
+	if len(got) != 2 || got[0].Field != FieldAddress || got[1].PeerName != "alice" || got[1].Field != FieldAllowedIP {
+		t.Fatalf("Find(10.8.0.14) = %+v", got)
+	}
+	if names := Tunnels(got); len(names) != 1 || !names["office"] {
+		t.Errorf("Tunnels() = %v, want office only", names)
+	}
+
+	// An address outside every network matches nothing, not a substring
+	if got := Find(tunnels, "10.8.1.1"); len(got) != 0 {
+		t.Errorf("Find(10.8.1.1) = %+v, want none", got)
+	}
+}
@@ -0,0 +1,67 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppID is the Fyne application ID, which names the preferences file
+const AppID = "com.wireguard.manager"
+
+// PreferenceReader is the part of fyne.Preferences that Load reads
+type PreferenceReader interface {
+	BoolWithFallback(key string, fallback bool) bool
+	IntWithFallback(key string, fallback int) int
+	StringWithFallback(key, fallback string) string
+}
+
+// PreferencesFile returns where Fyne stores the preferences of AppID on Linux
+func PreferencesFile() string {
+	dir, _ := os.UserConfigDir()
+	return filepath.Join(dir, "fyne", AppID, "preferences.json")
+}
+
+// LoadFile reads the settings saved by the GUI from its preferences file
+// without starting a Fyne app, for the command line. A missing file gives
+// the defaults.
+func LoadFile(path string) (*AppSettings, error) {
+	values := filePreferences{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Load(values), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("reading preferences %s: %w", path, err)
+	}
+	return Load(values), nil
+}
+
+// filePreferences holds the values of a preferences file as decoded from JSON
+type filePreferences map[string]any
+
+func (p filePreferences) BoolWithFallback(key string, fallback bool) bool {
+	if v, ok := p[key].(bool); ok {
+		return v
+	}
+	return fallback
+}
+
+func (p filePreferences) IntWithFallback(key string, fallback int) int {
+	// JSON numbers decode as floats
+	if v, ok := p[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
+
+func (p filePreferences) StringWithFallback(key, fallback string) string {
+	if v, ok := p[key].(string); ok {
+		return v
+	}
+	return fallback
+}
@@ -1,20 +1,52 @@
 package settings
 
-import "fyne.io/fyne/v2"
+import (
+	"encoding/json"
+
+	"wgAdmin/internal/history"
+	"wgAdmin/internal/netscan"
+	"wgAdmin/internal/scanwatch"
+
+	"fyne.io/fyne/v2"
+)
 
 // Preference key constants
 const (
 	KeyWGConfigPath        = "wg_config_path"
 	KeyClientConfigDir     = "client_config_dir"
+	KeyTrafficHistoryFile  = "traffic_history_file"
+	KeyAuditLogFile        = "audit_log_file"
 	KeyWindowWidth         = "window_width"
 	KeyWindowHeight        = "window_height"
 	KeyStartFullscreen     = "start_fullscreen"
 	KeyAutoRefreshEnabled  = "auto_refresh_enabled"
 	KeyAutoRefreshSecs     = "auto_refresh_seconds"
 	KeyConfirmBeforeDelete = "confirm_before_delete"
+	KeyMinimizeToTray      = "minimize_to_tray"
+	KeyNotifyIfaceDown     = "notify_interface_down"
+	KeyNotifyPeerConnect   = "notify_peer_connected"
+	KeyNotifyPeerDrop      = "notify_peer_disconnected"
+	KeyNotifyToggleFailed  = "notify_toggle_failed"
+	KeyNotifyScanChanges   = "notify_scan_changes"
+	KeyPeerStaleMinutes    = "peer_stale_minutes"
+	KeyExpiryCheckMinutes  = "peer_expiry_check_minutes"
+	KeyBackupKeepLast      = "backup_keep_last"
+	KeyBackupKeepDays      = "backup_keep_daily_days"
+	KeyAPIEnabled          = "api_enabled"
+	KeyAPISocket           = "api_socket"
+	KeyAPIAddress          = "api_address"
+	KeyAPIToken            = "api_token"
+	KeyMetricsEnabled      = "metrics_enabled"
+	KeyMetricsAddress      = "metrics_address"
 	KeyThemeVariant        = "theme_variant"
 	KeyScanWorkers         = "scan_workers"
 	KeyScanTimeoutSecs     = "scan_timeout_seconds"
+	KeyScanProfiles        = "scan_port_profiles"
+	KeyScanChoicePrefix    = "scan_choice_" // followed by the tunnel name
+	KeyScheduledScans      = "scheduled_scans"
+	KeyScanHistoryFile     = "scan_history_file"
+	KeyMonitorSecs         = "monitor_interval_seconds"
+	KeyMonitorTCPPort      = "monitor_tcp_port"
 	KeyPrivilegeEscalation = "privilege_escalation"
 	KeyFontSize            = "font_size"
 	KeyUseCustomFont       = "use_custom_font"
@@ -67,15 +99,38 @@ const (
 const (
 	DefaultWGConfigPath        = "/etc/wireguard"
 	DefaultClientConfigDir     = "clients"
+	DefaultTrafficHistoryFile  = "" // empty keeps history in memory only
+	DefaultAuditLogFile        = "/var/log/wgAdmin/audit.jsonl"
 	DefaultWindowWidth         = 950
 	DefaultWindowHeight        = 800
 	DefaultStartFullscreen     = false
 	DefaultAutoRefreshEnabled  = false
 	DefaultAutoRefreshSecs     = 5
 	DefaultConfirmBeforeDelete = true
+	DefaultMinimizeToTray      = false
+	DefaultNotifyIfaceDown     = true
+	DefaultNotifyPeerConnect   = false
+	DefaultNotifyPeerDrop      = true
+	DefaultNotifyToggleFailed  = true
+	DefaultNotifyScanChanges   = true
+	DefaultPeerStaleMinutes    = 3
+	DefaultExpiryCheckMinutes  = 5
+	DefaultBackupKeepLast      = 20
+	DefaultBackupKeepDays      = 30
+	DefaultAPIEnabled          = false
+	DefaultAPISocket           = "/run/wgAdmin/api.sock"
+	DefaultAPIAddress          = "" // empty serves on the socket only
+	DefaultAPIToken            = ""
+	DefaultMetricsEnabled      = false
+	DefaultMetricsAddress      = "127.0.0.1:9586"
 	DefaultThemeVariant        = "system"
 	DefaultScanWorkers         = 100
 	DefaultScanTimeoutSecs     = 2
+	DefaultScanProfiles        = "Common=21,22,23,25,53,80,139,443,445,3389,8080; SSH=22; Web=80,443,8080,8443; RDP=3389"
+	DefaultScheduledScans      = "" // no scheduled scans
+	DefaultScanHistoryFile     = "" // empty keeps scan history in memory only
+	DefaultMonitorSecs         = 0  // off until an interval is set in Settings
+	DefaultMonitorTCPPort      = 22
 	DefaultPrivilegeEscalation = "none"
 	DefaultFontSize            = "normal"
 	DefaultUseCustomFont       = true
@@ -128,20 +183,45 @@ const (
 type AppSettings struct {
 	WGConfigPath        string
 	ClientConfigDir     string
+	TrafficHistoryFile  string
+	AuditLogFile        string
 	WindowWidth         int
 	WindowHeight        int
 	StartFullscreen     bool
 	AutoRefreshEnabled  bool
 	AutoRefreshSecs     int
 	ConfirmBeforeDelete bool
+	MinimizeToTray      bool // closing the window hides it to the system tray
+	PeerStaleMinutes    int
+	ExpiryCheckMinutes  int // interval of the expired peer job; 0 turns it off
+	BackupKeepLast      int // versions always kept per tunnel
+	BackupKeepDays      int // one version per day kept for this many days
+	APIEnabled          bool
+	APISocket           string
+	APIAddress          string // TCP host:port; requires APIToken
+	APIToken            string
+	MetricsEnabled      bool
+	MetricsAddress      string // host:port serving /metrics
 	ThemeVariant        string
 	ScanWorkers         int
 	ScanTimeoutSecs     int
+	ScanProfiles        string // named port lists, see netscan.ParseProfiles
+	ScheduledScans      string // tunnel=minutes entries, see scanwatch.ParseSchedule
+	ScanHistoryFile     string
+	MonitorSecs         int // interval of the peer reachability probes; 0 turns them off
+	MonitorTCPPort      int // probed when ICMP is not permitted
 	PrivilegeEscalation string
 	FontSize            string
 	AccentColor         string // Deprecated: use LightAccentColor and DarkAccentColor
 	UseCustomFont       bool
 
+	// Desktop notifications, per event type
+	NotifyInterfaceDown    bool
+	NotifyPeerConnected    bool
+	NotifyPeerDisconnected bool
+	NotifyToggleFailed     bool
+	NotifyScanChanges      bool
+
 	// Light mode colors
 	LightAccentColor         string
 	LightBackgroundColor     string
@@ -186,24 +266,49 @@ type AppSettings struct {
 }
 
 // Load reads all settings from Fyne preferences, applying defaults for missing values.
-func Load(prefs fyne.Preferences) *AppSettings {
+func Load(prefs PreferenceReader) *AppSettings {
 	return &AppSettings{
 		WGConfigPath:        prefs.StringWithFallback(KeyWGConfigPath, DefaultWGConfigPath),
 		ClientConfigDir:     prefs.StringWithFallback(KeyClientConfigDir, DefaultClientConfigDir),
+		TrafficHistoryFile:  prefs.StringWithFallback(KeyTrafficHistoryFile, DefaultTrafficHistoryFile),
+		AuditLogFile:        prefs.StringWithFallback(KeyAuditLogFile, DefaultAuditLogFile),
 		WindowWidth:         prefs.IntWithFallback(KeyWindowWidth, DefaultWindowWidth),
 		WindowHeight:        prefs.IntWithFallback(KeyWindowHeight, DefaultWindowHeight),
 		StartFullscreen:     prefs.BoolWithFallback(KeyStartFullscreen, DefaultStartFullscreen),
 		AutoRefreshEnabled:  prefs.BoolWithFallback(KeyAutoRefreshEnabled, DefaultAutoRefreshEnabled),
 		AutoRefreshSecs:     prefs.IntWithFallback(KeyAutoRefreshSecs, DefaultAutoRefreshSecs),
 		ConfirmBeforeDelete: prefs.BoolWithFallback(KeyConfirmBeforeDelete, DefaultConfirmBeforeDelete),
+		MinimizeToTray:      prefs.BoolWithFallback(KeyMinimizeToTray, DefaultMinimizeToTray),
+		PeerStaleMinutes:    prefs.IntWithFallback(KeyPeerStaleMinutes, DefaultPeerStaleMinutes),
+		ExpiryCheckMinutes:  prefs.IntWithFallback(KeyExpiryCheckMinutes, DefaultExpiryCheckMinutes),
+		BackupKeepLast:      prefs.IntWithFallback(KeyBackupKeepLast, DefaultBackupKeepLast),
+		BackupKeepDays:      prefs.IntWithFallback(KeyBackupKeepDays, DefaultBackupKeepDays),
+		APIEnabled:          prefs.BoolWithFallback(KeyAPIEnabled, DefaultAPIEnabled),
+		APISocket:           prefs.StringWithFallback(KeyAPISocket, DefaultAPISocket),
+		APIAddress:          prefs.StringWithFallback(KeyAPIAddress, DefaultAPIAddress),
+		APIToken:            prefs.StringWithFallback(KeyAPIToken, DefaultAPIToken),
+		MetricsEnabled:      prefs.BoolWithFallback(KeyMetricsEnabled, DefaultMetricsEnabled),
+		MetricsAddress:      prefs.StringWithFallback(KeyMetricsAddress, DefaultMetricsAddress),
 		ThemeVariant:        prefs.StringWithFallback(KeyThemeVariant, DefaultThemeVariant),
 		ScanWorkers:         prefs.IntWithFallback(KeyScanWorkers, DefaultScanWorkers),
 		ScanTimeoutSecs:     prefs.IntWithFallback(KeyScanTimeoutSecs, DefaultScanTimeoutSecs),
+		ScanProfiles:        prefs.StringWithFallback(KeyScanProfiles, DefaultScanProfiles),
+		ScheduledScans:      prefs.StringWithFallback(KeyScheduledScans, DefaultScheduledScans),
+		ScanHistoryFile:     prefs.StringWithFallback(KeyScanHistoryFile, DefaultScanHistoryFile),
+		MonitorSecs:         prefs.IntWithFallback(KeyMonitorSecs, DefaultMonitorSecs),
+		MonitorTCPPort:      prefs.IntWithFallback(KeyMonitorTCPPort, DefaultMonitorTCPPort),
 		PrivilegeEscalation: prefs.StringWithFallback(KeyPrivilegeEscalation, DefaultPrivilegeEscalation),
 		FontSize:            prefs.StringWithFallback(KeyFontSize, DefaultFontSize),
 		AccentColor:         prefs.StringWithFallback(KeyAccentColor, DefaultAccentColor),
 		UseCustomFont:       prefs.BoolWithFallback(KeyUseCustomFont, DefaultUseCustomFont),
 
+		// Desktop notifications
+		NotifyInterfaceDown:    prefs.BoolWithFallback(KeyNotifyIfaceDown, DefaultNotifyIfaceDown),
+		NotifyPeerConnected:    prefs.BoolWithFallback(KeyNotifyPeerConnect, DefaultNotifyPeerConnect),
+		NotifyPeerDisconnected: prefs.BoolWithFallback(KeyNotifyPeerDrop, DefaultNotifyPeerDrop),
+		NotifyToggleFailed:     prefs.BoolWithFallback(KeyNotifyToggleFailed, DefaultNotifyToggleFailed),
+		NotifyScanChanges:      prefs.BoolWithFallback(KeyNotifyScanChanges, DefaultNotifyScanChanges),
+
 		// Light mode colors
 		LightAccentColor:         prefs.StringWithFallback(KeyLightAccentColor, DefaultLightAccentColor),
 		LightBackgroundColor:     prefs.StringWithFallback(KeyLightBackgroundColor, DefaultLightBackgroundColor),
@@ -252,15 +357,38 @@ func Load(prefs fyne.Preferences) *AppSettings {
 func (s *AppSettings) Save(prefs fyne.Preferences) {
 	prefs.SetString(KeyWGConfigPath, s.WGConfigPath)
 	prefs.SetString(KeyClientConfigDir, s.ClientConfigDir)
+	prefs.SetString(KeyTrafficHistoryFile, s.TrafficHistoryFile)
+	prefs.SetString(KeyAuditLogFile, s.AuditLogFile)
 	prefs.SetInt(KeyWindowWidth, s.WindowWidth)
 	prefs.SetInt(KeyWindowHeight, s.WindowHeight)
 	prefs.SetBool(KeyStartFullscreen, s.StartFullscreen)
 	prefs.SetBool(KeyAutoRefreshEnabled, s.AutoRefreshEnabled)
 	prefs.SetInt(KeyAutoRefreshSecs, s.AutoRefreshSecs)
 	prefs.SetBool(KeyConfirmBeforeDelete, s.ConfirmBeforeDelete)
+	prefs.SetBool(KeyMinimizeToTray, s.MinimizeToTray)
+	prefs.SetBool(KeyNotifyIfaceDown, s.NotifyInterfaceDown)
+	prefs.SetBool(KeyNotifyPeerConnect, s.NotifyPeerConnected)
+	prefs.SetBool(KeyNotifyPeerDrop, s.NotifyPeerDisconnected)
+	prefs.SetBool(KeyNotifyToggleFailed, s.NotifyToggleFailed)
+	prefs.SetBool(KeyNotifyScanChanges, s.NotifyScanChanges)
+	prefs.SetInt(KeyPeerStaleMinutes, s.PeerStaleMinutes)
+	prefs.SetInt(KeyExpiryCheckMinutes, s.ExpiryCheckMinutes)
+	prefs.SetInt(KeyBackupKeepLast, s.BackupKeepLast)
+	prefs.SetInt(KeyBackupKeepDays, s.BackupKeepDays)
+	prefs.SetBool(KeyAPIEnabled, s.APIEnabled)
+	prefs.SetString(KeyAPISocket, s.APISocket)
+	prefs.SetString(KeyAPIAddress, s.APIAddress)
+	prefs.SetString(KeyAPIToken, s.APIToken)
+	prefs.SetBool(KeyMetricsEnabled, s.MetricsEnabled)
+	prefs.SetString(KeyMetricsAddress, s.MetricsAddress)
 	prefs.SetString(KeyThemeVariant, s.ThemeVariant)
 	prefs.SetInt(KeyScanWorkers, s.ScanWorkers)
 	prefs.SetInt(KeyScanTimeoutSecs, s.ScanTimeoutSecs)
+	prefs.SetString(KeyScanProfiles, s.ScanProfiles)
+	prefs.SetString(KeyScheduledScans, s.ScheduledScans)
+	prefs.SetString(KeyScanHistoryFile, s.ScanHistoryFile)
+	prefs.SetInt(KeyMonitorSecs, s.MonitorSecs)
+	prefs.SetInt(KeyMonitorTCPPort, s.MonitorTCPPort)
 	prefs.SetString(KeyPrivilegeEscalation, s.PrivilegeEscalation)
 	prefs.SetString(KeyFontSize, s.FontSize)
 	prefs.SetString(KeyAccentColor, s.AccentColor)
@@ -308,3 +436,48 @@ func (s *AppSettings) Save(prefs fyne.Preferences) {
 	prefs.SetString(KeyDarkMenuBackgroundColor, s.DarkMenuBackgroundColor)
 	prefs.SetString(KeyDarkScrollbarColor, s.DarkScrollbarColor)
 }
+
+// BackupPolicy returns the version retention policy
+func (s *AppSettings) BackupPolicy() history.Policy {
+	return history.Policy{KeepLast: s.BackupKeepLast, KeepDailyDays: s.BackupKeepDays}
+}
+
+// PortProfiles returns the scan port profiles, or the defaults when the
+// stored ones do not parse
+func (s *AppSettings) PortProfiles() []netscan.Profile {
+	if profiles, err := netscan.ParseProfiles(s.ScanProfiles); err == nil && len(profiles) > 0 {
+		return profiles
+	}
+	profiles, _ := netscan.ParseProfiles(DefaultScanProfiles)
+	return profiles
+}
+
+// ScanSchedule returns the scheduled scans; none when the stored schedule
+// does not parse
+func (s *AppSettings) ScanSchedule() []scanwatch.Entry {
+	entries, _ := scanwatch.ParseSchedule(s.ScheduledScans)
+	return entries
+}
+
+// ScanChoice is the network and ports last scanned on a tunnel
+type ScanChoice struct {
+	CIDR    string `json:"cidr"`
+	Profile string `json:"profile"`
+	Ports   string `json:"ports,omitempty"` // the list of the custom profile
+}
+
+// LoadScanChoice returns the last scan choice of a tunnel, zero when none
+// was saved
+func LoadScanChoice(prefs fyne.Preferences, tunnel string) ScanChoice {
+	var c ScanChoice
+	if data := prefs.String(KeyScanChoicePrefix + tunnel); data != "" {
+		_ = json.Unmarshal([]byte(data), &c)
+	}
+	return c
+}
+
+// SaveScanChoice remembers the scan choice of a tunnel
+func SaveScanChoice(prefs fyne.Preferences, tunnel string, c ScanChoice) {
+	data, _ := json.Marshal(c)
+	prefs.SetString(KeyScanChoicePrefix+tunnel, string(data))
+}
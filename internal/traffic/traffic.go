@@ -0,0 +1,257 @@
+package traffic
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"wgAdmin/internal/controller"
+)
+
+// Sample is a reading of the cumulative rx/tx byte counters
+type Sample struct {
+	Time time.Time `json:"t"`
+	Rx   int64     `json:"rx"`
+	Tx   int64     `json:"tx"`
+}
+
+// Rate is the average throughput between two samples, in bytes per second
+type Rate struct {
+	Time time.Time
+	Rx   float64
+	Tx   float64
+}
+
+// Ring is a fixed size buffer of samples that overwrites the oldest entry when full
+type Ring struct {
+	samples []Sample
+	start   int
+	size    int
+}
+
+// NewRing creates a ring holding at most capacity samples
+func NewRing(capacity int) *Ring {
+	if capacity < 2 {
+		capacity = 2
+	}
+	return &Ring{samples: make([]Sample, capacity)}
+}
+
+// Add appends s, dropping the oldest sample if the ring is full
+func (r *Ring) Add(s Sample) {
+	if r.size < len(r.samples) {
+		r.samples[(r.start+r.size)%len(r.samples)] = s
+		r.size++
+		return
+	}
+	r.samples[r.start] = s
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// Samples returns the samples oldest first
+func (r *Ring) Samples() []Sample {
+	out := make([]Sample, r.size)
+	for i := 0; i < r.size; i++ {
+		out[i] = r.samples[(r.start+i)%len(r.samples)]
+	}
+	return out
+}
+
+// Last returns the newest sample
+func (r *Ring) Last() (Sample, bool) {
+	if r.size == 0 {
+		return Sample{}, false
+	}
+	return r.samples[(r.start+r.size-1)%len(r.samples)], true
+}
+
+// Rates converts consecutive samples newer than since into throughput.
+// A counter that went backwards (device recreated) starts a new series.
+func Rates(samples []Sample, since time.Time) []Rate {
+	var rates []Rate
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		if cur.Time.Before(since) {
+			continue
+		}
+		secs := cur.Time.Sub(prev.Time).Seconds()
+		if secs <= 0 || cur.Rx < prev.Rx || cur.Tx < prev.Tx {
+			continue
+		}
+		rates = append(rates, Rate{
+			Time: cur.Time,
+			Rx:   float64(cur.Rx-prev.Rx) / secs,
+			Tx:   float64(cur.Tx-prev.Tx) / secs,
+		})
+	}
+	return rates
+}
+
+// PeerKey returns the series key of a peer on an interface
+func PeerKey(iface, publicKey string) string {
+	return iface + "/" + publicKey
+}
+
+// Recorder keeps a ring of samples per interface and per peer
+type Recorder struct {
+	mu       sync.Mutex
+	capacity int
+	series   map[string]*Ring
+	lastSave time.Time
+}
+
+// NewRecorder creates a recorder keeping up to capacity samples per series
+func NewRecorder(capacity int) *Recorder {
+	return &Recorder{
+		capacity: capacity,
+		series:   make(map[string]*Ring),
+	}
+}
+
+// SetCapacity changes the number of samples kept per series, keeping the
+// newest samples of each
+func (r *Recorder) SetCapacity(capacity int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if capacity == r.capacity {
+		return
+	}
+	r.capacity = capacity
+	for key, ring := range r.series {
+		resized := NewRing(capacity)
+		for _, s := range ring.Samples() {
+			resized.Add(s)
+		}
+		r.series[key] = resized
+	}
+}
+
+// CapacityFor returns the number of samples needed to cover span when sampling every interval
+func CapacityFor(span, interval time.Duration) int {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return int(span/interval) + 1
+}
+
+// Record adds a sample for each peer and for the interface. The interface
+// counter grows by the sum of the peers' deltas, so removing a peer or
+// resetting its counters does not look like the interface counter resetting.
+func (r *Recorder) Record(iface string, peers []controller.PeerStats, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total, _ := r.last(iface)
+	total.Time = at
+	for _, p := range peers {
+		key := PeerKey(iface, p.PublicKey)
+		cur := Sample{Time: at, Rx: p.RxBytes, Tx: p.TxBytes}
+		prev, _ := r.last(key)
+		total.Rx += delta(prev.Rx, cur.Rx)
+		total.Tx += delta(prev.Tx, cur.Tx)
+		r.add(key, cur)
+	}
+	r.add(iface, total)
+}
+
+// delta is the growth of a counter; a counter that went backwards was reset
+// and has counted cur bytes since
+func delta(prev, cur int64) int64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func (r *Recorder) last(key string) (Sample, bool) {
+	ring, ok := r.series[key]
+	if !ok {
+		return Sample{}, false
+	}
+	return ring.Last()
+}
+
+func (r *Recorder) add(key string, s Sample) {
+	ring, ok := r.series[key]
+	if !ok {
+		ring = NewRing(r.capacity)
+		r.series[key] = ring
+	}
+	ring.Add(s)
+}
+
+// Rates returns the throughput of a series over the last window
+func (r *Recorder) Rates(key string, window time.Duration) []Rate {
+	r.mu.Lock()
+	ring, ok := r.series[key]
+	var samples []Sample
+	if ok {
+		samples = ring.Samples()
+	}
+	r.mu.Unlock()
+	return Rates(samples, time.Now().Add(-window))
+}
+
+// Save writes all series to path as JSON
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	data := make(map[string][]Sample, len(r.series))
+	for key, ring := range r.series {
+		data[key] = ring.Samples()
+	}
+	r.lastSave = time.Now()
+	r.mu.Unlock()
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveIfDue saves to path when the last save is older than every
+func (r *Recorder) SaveIfDue(path string, every time.Duration) error {
+	r.mu.Lock()
+	due := time.Since(r.lastSave) >= every
+	r.mu.Unlock()
+	if !due {
+		return nil
+	}
+	return r.Save(path)
+}
+
+// Load replaces the recorded series with those saved at path.
+// A missing file is not an error.
+func (r *Recorder) Load(path string) error {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var data map[string][]Sample
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.series = make(map[string]*Ring, len(data))
+	for key, samples := range data {
+		for _, s := range samples {
+			r.add(key, s)
+		}
+	}
+	r.lastSave = time.Now()
+	return nil
+}
@@ -0,0 +1,112 @@
+package traffic
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/controller"
+)
+
+func stats(key string, rx, tx int64) controller.PeerStats {
+	return controller.PeerStats{PublicKey: key, RxBytes: rx, TxBytes: tx}
+}
+
+// interfaceRx returns the rx counter of every interface sample
+func interfaceRx(r *Recorder, iface string) []int64 {
+	var out []int64
+	for _, s := range r.series[iface].Samples() {
+		out = append(out, s.Rx)
+	}
+	return out
+}
+
+func TestRingWraparound(t *testing.T) {
+	r := NewRing(3)
+	if _, ok := r.Last(); ok {
+		t.Error("empty ring has a last sample")
+	}
+	for i := int64(1); i <= 5; i++ {
+		r.Add(Sample{Rx: i})
+	}
+	var got []int64
+	for _, s := range r.Samples() {
+		got = append(got, s.Rx)
+	}
+	if !slices.Equal(got, []int64{3, 4, 5}) {
+		t.Errorf("samples = %v, want the newest three oldest first", got)
+	}
+	if last, _ := r.Last(); last.Rx != 5 {
+		t.Errorf("last = %d, want 5", last.Rx)
+	}
+}
+
+func TestRatesSkipCounterReset(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	samples := []Sample{
+		{Time: start, Rx: 1000, Tx: 100},
+		{Time: start.Add(time.Second), Rx: 2000, Tx: 200},
+		{Time: start.Add(2 * time.Second), Rx: 50, Tx: 10}, // device recreated
+		{Time: start.Add(3 * time.Second), Rx: 550, Tx: 60},
+	}
+	rates := Rates(samples, start.Add(-time.Second))
+	if len(rates) != 2 || rates[0].Rx != 1000 || rates[1].Rx != 500 || rates[1].Tx != 50 {
+		t.Errorf("rates = %+v, want 1000 then 500 B/s with the reset skipped", rates)
+	}
+}
+
+func TestRecordInterfaceTotal(t *testing.T) {
+	r := NewRecorder(10)
+	at := time.Now()
+	tick := func(peers ...controller.PeerStats) {
+		at = at.Add(time.Second)
+		r.Record("wg0", peers, at)
+	}
+
+	tick(stats("a", 100, 10), stats("b", 200, 20))
+	tick(stats("a", 150, 10), stats("b", 300, 20))
+	// b is removed: the interface keeps counting a only
+	tick(stats("a", 160, 10))
+	// a's counter is reset, then c is added
+	tick(stats("a", 5, 10))
+	tick(stats("a", 15, 10), stats("c", 40, 0))
+
+	if got, want := interfaceRx(r, "wg0"), []int64{300, 450, 460, 465, 515}; !slices.Equal(got, want) {
+		t.Errorf("interface rx = %v, want %v", got, want)
+	}
+	for _, rate := range r.Rates("wg0", time.Hour) {
+		if rate.Rx < 0 {
+			t.Errorf("negative interface rate %+v", rate)
+		}
+	}
+	if n := len(r.Rates("wg0", time.Hour)); n != 4 {
+		t.Errorf("interface rates = %d, want one per interval with no gaps", n)
+	}
+	if n := len(r.Rates(PeerKey("wg0", "a"), time.Hour)); n != 3 {
+		t.Errorf("peer a rates = %d, want the reset interval skipped", n)
+	}
+}
+
+func TestSetCapacityKeepsNewest(t *testing.T) {
+	r := NewRecorder(10)
+	at := time.Now()
+	for i := int64(1); i <= 6; i++ {
+		r.Record("wg0", []controller.PeerStats{stats("a", i*100, 0)}, at.Add(time.Duration(i)*time.Second))
+	}
+
+	// A longer refresh interval needs fewer samples for the same span
+	r.SetCapacity(CapacityFor(2*time.Minute, time.Minute))
+	if got := interfaceRx(r, "wg0"); !slices.Equal(got, []int64{400, 500, 600}) {
+		t.Errorf("after shrinking: %v, want the newest three", got)
+	}
+
+	// Growing keeps every sample and makes room for more
+	r.SetCapacity(5)
+	r.Record("wg0", []controller.PeerStats{stats("a", 700, 0)}, at.Add(7*time.Second))
+	if got := interfaceRx(r, "wg0"); !slices.Equal(got, []int64{400, 500, 600, 700}) {
+		t.Errorf("after growing: %v", got)
+	}
+	if n := len(r.series[PeerKey("wg0", "a")].Samples()); n != 4 {
+		t.Errorf("peer series has %d samples, want 4", n)
+	}
+}
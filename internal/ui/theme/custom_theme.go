@@ -432,6 +432,17 @@ func (c Colors) Inactive(variant fyne.ThemeVariant) color.Color {
 	return c.getThemeColor("", "", settings.DefaultLightErrorColor, settings.DefaultDarkErrorColor, variant)
 }
 
+// Warning returns the warning color
+func (c Colors) Warning(variant fyne.ThemeVariant) color.Color {
+	if activeTheme != nil && activeTheme.settings != nil {
+		if variant == theme.VariantLight {
+			return c.getThemeColor(activeTheme.settings.LightWarningColor, "", settings.DefaultLightWarningColor, settings.DefaultDarkWarningColor, variant)
+		}
+		return c.getThemeColor("", activeTheme.settings.DarkWarningColor, settings.DefaultLightWarningColor, settings.DefaultDarkWarningColor, variant)
+	}
+	return c.getThemeColor("", "", settings.DefaultLightWarningColor, settings.DefaultDarkWarningColor, variant)
+}
+
 // CardBackground returns the card background color
 func (c Colors) CardBackground(variant fyne.ThemeVariant) color.Color {
 	if activeTheme != nil && activeTheme.settings != nil {
@@ -487,6 +498,14 @@ func (c Colors) StatusErrorBg(variant fyne.ThemeVariant) color.Color {
 	return color.NRGBA{R: 60, G: 30, B: 30, A: 255}
 }
 
+// StatusWarningBg returns a tinted background for warning status
+func (c Colors) StatusWarningBg(variant fyne.ThemeVariant) color.Color {
+	if variant == theme.VariantLight {
+		return color.NRGBA{R: 254, G: 247, B: 224, A: 255}
+	}
+	return color.NRGBA{R: 60, G: 50, B: 20, A: 255}
+}
+
 // StatusInfoBg returns a tinted background for info status
 func (c Colors) StatusInfoBg(variant fyne.ThemeVariant) color.Color {
 	if activeTheme != nil && activeTheme.settings != nil {
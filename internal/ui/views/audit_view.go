@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/ui/helpers"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const allFilter = "All"
+
+// AuditView displays the audit log with filters and export
+type AuditView struct {
+	path string
+
+	win          fyne.Window
+	entries      []audit.Entry
+	filtered     []audit.Entry
+	list         *widget.List
+	countLabel   *widget.Label
+	searchEntry  *widget.Entry
+	actionSelect *widget.Select
+	resultSelect *widget.Select
+}
+
+// NewAuditView creates a viewer for the audit log at path
+func NewAuditView(path string) *AuditView {
+	return &AuditView{path: path}
+}
+
+// Show opens the audit log window
+func (av *AuditView) Show() {
+	av.win = fyne.CurrentApp().NewWindow("Audit Log")
+	av.win.Resize(fyne.NewSize(900, 600))
+
+	if av.path == "" {
+		av.win.SetContent(container.NewCenter(widget.NewLabel("No audit log file is configured.")))
+		av.win.Show()
+		return
+	}
+
+	av.searchEntry = widget.NewEntry()
+	av.searchEntry.SetPlaceHolder("Filter by user, tunnel, peer or error...")
+	av.searchEntry.OnChanged = func(string) { av.applyFilter() }
+
+	av.actionSelect = widget.NewSelect(append([]string{allFilter}, audit.Actions...), func(string) { av.applyFilter() })
+	av.resultSelect = widget.NewSelect([]string{allFilter, audit.ResultOK, audit.ResultError}, func(string) { av.applyFilter() })
+
+	av.countLabel = widget.NewLabel("")
+
+	av.list = widget.NewList(
+		func() int { return len(av.filtered) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.TextStyle = fyne.TextStyle{Monospace: true}
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(formatAuditEntry(av.filtered[id]))
+		},
+	)
+
+	reloadBtn := widget.NewButtonWithIcon("Reload", theme.ViewRefreshIcon(), func() {
+		av.reload()
+	})
+	exportBtn := widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
+		av.export()
+	})
+
+	filters := container.NewBorder(nil, nil, nil,
+		container.NewHBox(widget.NewLabel("Action:"), av.actionSelect, widget.NewLabel("Result:"), av.resultSelect, reloadBtn, exportBtn),
+		av.searchEntry)
+	pathLabel := widget.NewLabel(av.path)
+	pathLabel.TextStyle = fyne.TextStyle{Italic: true}
+	footer := container.NewBorder(nil, nil, pathLabel, av.countLabel)
+
+	av.win.SetContent(container.NewPadded(container.NewBorder(filters, footer, nil, nil, av.list)))
+
+	av.actionSelect.SetSelected(allFilter)
+	av.resultSelect.SetSelected(allFilter)
+	av.reload()
+	av.win.Show()
+}
+
+func (av *AuditView) reload() {
+	entries, err := audit.Read(av.path)
+	if err != nil {
+		helpers.ShowError(fmt.Errorf("failed to read audit log: %w", err), av.win)
+	}
+	av.entries = entries
+	av.applyFilter()
+}
+
+// applyFilter shows the matching entries, newest first
+func (av *AuditView) applyFilter() {
+	if av.list == nil {
+		return
+	}
+	search := strings.ToLower(strings.TrimSpace(av.searchEntry.Text))
+	action := av.actionSelect.Selected
+	result := av.resultSelect.Selected
+
+	av.filtered = av.filtered[:0]
+	for i := len(av.entries) - 1; i >= 0; i-- {
+		e := av.entries[i]
+		if action != "" && action != allFilter && e.Action != action {
+			continue
+		}
+		if result != "" && result != allFilter && e.Result != result {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(strings.Join([]string{e.User, e.Source, e.Tunnel, e.Peer, e.Error}, " ")), search) {
+			continue
+		}
+		av.filtered = append(av.filtered, e)
+	}
+
+	av.countLabel.SetText(fmt.Sprintf("%d of %d entries", len(av.filtered), len(av.entries)))
+	av.list.Refresh()
+}
+
+// export writes the filtered entries as CSV when the file name ends in .csv,
+// otherwise as JSON lines
+func (av *AuditView) export() {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			helpers.ShowError(err, av.win)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if strings.EqualFold(filepath.Ext(writer.URI().Name()), ".csv") {
+			err = writeAuditCSV(writer, av.filtered)
+		} else {
+			err = writeAuditJSON(writer, av.filtered)
+		}
+		if err != nil {
+			helpers.ShowError(fmt.Errorf("export failed: %w", err), av.win)
+		}
+	}, av.win)
+	d.SetFileName("audit-" + time.Now().Format("20060102") + ".jsonl")
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+func formatAuditEntry(e audit.Entry) string {
+	target := e.Tunnel
+	if e.Peer != "" {
+		target += "/" + e.Peer
+	}
+	line := fmt.Sprintf("%s  %-10s %-4s %-18s %-16s %s",
+		e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Source, e.Action, target, e.Result)
+	if e.Error != "" {
+		line += ": " + e.Error
+	}
+	return line
+}
+
+func writeAuditJSON(w io.Writer, entries []audit.Entry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAuditCSV(w io.Writer, entries []audit.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "user", "source", "action", "tunnel", "peer", "result", "error"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{e.Time.Format(time.RFC3339), e.User, e.Source, e.Action, e.Tunnel, e.Peer, e.Result, e.Error}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -1,34 +1,49 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
-	"time"
 
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/history"
 	"wgAdmin/internal/ui/helpers"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/MrVasquez96/go-wg/wg"
+	"github.com/MrVasquez96/go-wg/wg/config"
 )
 
-// BackupView displays available backups and allows restoring them
+// retainer is implemented by controllers that keep a version history
+type retainer interface {
+	History() *history.Store
+	ApplyRetention() (int, error)
+}
+
+// BackupView displays the backups of each tunnel as a timeline and allows
+// restoring them in place or under a new name
 type BackupView struct {
 	window    fyne.Window
-	ctrl      *wg.WG
+	ctrl      controller.Controller
+	audit     *audit.Logger
 	onRestore func()
 
 	win           fyne.Window
 	listContainer *fyne.Container
+	timeline      *widget.Accordion
 }
 
 // NewBackupView creates a new backup/restore view
-func NewBackupView(parent fyne.Window, ctrl *wg.WG, onRestore func()) *BackupView {
+func NewBackupView(parent fyne.Window, ctrl controller.Controller, auditLog *audit.Logger, onRestore func()) *BackupView {
 	return &BackupView{
 		window:        parent,
 		ctrl:          ctrl,
+		audit:         auditLog,
 		onRestore:     onRestore,
 		listContainer: container.NewVBox(),
 	}
@@ -37,32 +52,24 @@ func NewBackupView(parent fyne.Window, ctrl *wg.WG, onRestore func()) *BackupVie
 // Show opens the backup/restore window
 func (bv *BackupView) Show() {
 	bv.win = fyne.CurrentApp().NewWindow("Backups")
-	bv.win.Resize(fyne.NewSize(700, 500))
-
-	// Clean old backups button
-	cleanBtn := widget.NewButtonWithIcon("Delete Backups Older Than 30 Days", theme.DeleteIcon(), func() {
-		helpers.ShowConfirm("Clean Old Backups",
-			"Delete all backup files older than 30 days?",
-			func(yes bool) {
-				if !yes {
-					return
-				}
-				removed, err := bv.ctrl.CleanOldBackups(30 * 24 * time.Hour)
-				if err != nil {
-					helpers.ShowError(fmt.Errorf("cleanup failed: %w", err), bv.win)
-					return
-				}
-				helpers.ShowInformation("Cleanup Complete",
-					fmt.Sprintf("Removed %d old backup(s).", removed), bv.win)
-				bv.refresh()
-			}, bv.win)
-	})
-	cleanBtn.Importance = widget.DangerImportance
+	bv.win.Resize(fyne.NewSize(750, 550))
 
-	header := container.NewHBox(cleanBtn)
+	var header fyne.CanvasObject = widget.NewLabel("Backups are taken when a tunnel is deleted.")
+	if r, ok := bv.ctrl.(retainer); ok {
+		policy := r.History().Policy()
+		info := widget.NewLabel(fmt.Sprintf("A version is saved on every write. Keeping the last %d per tunnel and one a day for %d days.",
+			policy.KeepLast, policy.KeepDailyDays))
+		info.Wrapping = fyne.TextWrapWord
+
+		pruneBtn := widget.NewButtonWithIcon("Apply Retention Policy", theme.DeleteIcon(), func() {
+			bv.confirmRetention(r)
+		})
+		pruneBtn.Importance = widget.DangerImportance
+		header = container.NewBorder(nil, nil, nil, pruneBtn, info)
+	}
 
 	scroll := container.NewVScroll(bv.listContainer)
-	scroll.SetMinSize(fyne.NewSize(660, 400))
+	scroll.SetMinSize(fyne.NewSize(700, 420))
 
 	content := container.NewBorder(container.NewPadded(header), nil, nil, nil, scroll)
 	bv.win.SetContent(container.NewPadded(content))
@@ -73,6 +80,7 @@ func (bv *BackupView) Show() {
 
 func (bv *BackupView) refresh() {
 	bv.listContainer.RemoveAll()
+	bv.timeline = nil
 
 	backups, err := bv.ctrl.ListBackups()
 	if err != nil {
@@ -85,37 +93,166 @@ func (bv *BackupView) refresh() {
 		return
 	}
 
+	// Group per tunnel, keeping the newest-first order within each group
+	var names []string
+	groups := make(map[string][]controller.Backup)
 	for _, b := range backups {
-		backup := b // capture for closure
+		if _, ok := groups[b.Name]; !ok {
+			names = append(names, b.Name)
+		}
+		groups[b.Name] = append(groups[b.Name], b)
+	}
+
+	bv.timeline = widget.NewAccordion()
+	bv.timeline.MultiOpen = true
+	for _, name := range names {
+		title := fmt.Sprintf("%s (%d versions)", name, len(groups[name]))
+		if !bv.ctrl.ConfigExists(name) {
+			title += " - deleted"
+		}
+		bv.timeline.Append(widget.NewAccordionItem(title, bv.newTimeline(groups[name])))
+	}
+	if len(names) == 1 {
+		bv.timeline.Open(0)
+	}
+	bv.listContainer.Add(bv.timeline)
+}
 
-		nameLabel := widget.NewLabel(backup.Name)
-		nameLabel.TextStyle = fyne.TextStyle{Bold: true}
+// newTimeline lists the versions of one tunnel, newest first
+func (bv *BackupView) newTimeline(backups []controller.Backup) fyne.CanvasObject {
+	rows := container.NewVBox()
+	for i, b := range backups {
+		backup := b // capture for closure
 
 		timeLabel := widget.NewLabel(backup.Timestamp.Format("2006-01-02 15:04:05"))
+		timeLabel.TextStyle = fyne.TextStyle{Bold: i == 0}
+
+		reason := backup.Reason
+		if reason == "" {
+			reason = "backup"
+		}
+		reasonLabel := widget.NewLabel(reason)
+		reasonLabel.TextStyle = fyne.TextStyle{Italic: true}
 
 		restoreBtn := widget.NewButtonWithIcon("Restore", theme.HistoryIcon(), func() {
-			msg := fmt.Sprintf("Restore '%s' from backup?\n\nThis will overwrite %s.conf if it exists.",
-				backup.Filename, backup.Name)
-			helpers.ShowConfirm("Restore Backup", msg, func(yes bool) {
-				if !yes {
-					return
-				}
-				if err := bv.ctrl.RestoreBackup(backup.Filename); err != nil {
-					helpers.ShowError(fmt.Errorf("restore failed: %w", err), bv.win)
-					return
-				}
-				helpers.ShowInformation("Restored",
-					fmt.Sprintf("Successfully restored %s", backup.Name), bv.win)
-				if bv.onRestore != nil {
-					bv.onRestore()
-				}
-			}, bv.win)
+			bv.confirmRestore(backup)
 		})
 		restoreBtn.Importance = widget.HighImportance
 
-		infoBox := container.NewVBox(nameLabel, timeLabel)
-		row := container.NewBorder(nil, nil, infoBox, restoreBtn)
-		bv.listContainer.Add(container.NewPadded(row))
-		bv.listContainer.Add(widget.NewSeparator())
+		restoreAsBtn := widget.NewButtonWithIcon("Restore As...", theme.ContentCopyIcon(), func() {
+			bv.showRestoreAs(backup)
+		})
+
+		info := container.NewHBox(timeLabel, reasonLabel)
+		rows.Add(container.NewBorder(nil, nil, info, container.NewHBox(restoreAsBtn, restoreBtn)))
+		if i < len(backups)-1 {
+			rows.Add(widget.NewSeparator())
+		}
+	}
+	return rows
+}
+
+func (bv *BackupView) confirmRetention(r retainer) {
+	policy := r.History().Policy()
+	helpers.ShowConfirm("Apply Retention Policy",
+		fmt.Sprintf("Delete versions beyond the last %d per tunnel, keeping one a day for %d days?\n\nDeletion backups older than %d days are also removed.",
+			policy.KeepLast, policy.KeepDailyDays, policy.KeepDailyDays),
+		func(yes bool) {
+			if !yes {
+				return
+			}
+			removed, err := r.ApplyRetention()
+			bv.audit.Record(audit.ActionCleanBackup, "", "", err)
+			if err != nil {
+				helpers.ShowError(fmt.Errorf("cleanup failed: %w", err), bv.win)
+				return
+			}
+			helpers.ShowInformation("Cleanup Complete",
+				fmt.Sprintf("Removed %d old version(s).", removed), bv.win)
+			bv.refresh()
+		}, bv.win)
+}
+
+// confirmRestore previews the restore as a diff against the current config,
+// falling back to a plain confirmation when the backup cannot be read
+func (bv *BackupView) confirmRestore(backup controller.Backup) {
+	backupCfg, err := bv.ctrl.ReadBackup(backup.Filename)
+	if err != nil {
+		msg := fmt.Sprintf("Restore '%s' from backup?\n\nThis will overwrite %s.conf if it exists.\n\n(Preview unavailable: %v)",
+			backup.Filename, backup.Name, err)
+		helpers.ShowConfirm("Restore Backup", msg, func(yes bool) {
+			if yes {
+				bv.restore(backup)
+			}
+		}, bv.win)
+		return
+	}
+
+	// Restoring over a live tunnel is only done from an explicit Overwrite
+	var current *config.Config
+	confirm := "Restore"
+	if bv.ctrl.ConfigExists(backup.Name) {
+		current, _ = controller.ReadConfig(bv.ctrl.GetConfigPath(backup.Name))
+		confirm = "Overwrite " + backup.Name
+	}
+	ShowConfigDiff(bv.win, "Restore "+backup.Name, confirm, current, backupCfg, func() {
+		bv.restore(backup)
+	})
+}
+
+// showRestoreAs asks for a new tunnel name and restores the backup under it
+func (bv *BackupView) showRestoreAs(backup controller.Backup) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. " + backup.Name + "old")
+	nameEntry.Validator = func(name string) error {
+		if !wg.ValidateName(name) {
+			return errors.New("must be 1-15 alphanumeric characters")
+		}
+		if bv.ctrl.ConfigExists(name) {
+			return errors.New("a tunnel with this name exists")
+		}
+		return nil
+	}
+
+	d := dialog.NewForm("Restore "+backup.Name+" As", "Restore", "Cancel",
+		[]*widget.FormItem{widget.NewFormItem("New Name", nameEntry)},
+		func(ok bool) {
+			if ok {
+				bv.restoreAs(backup, nameEntry.Text)
+			}
+		}, bv.win)
+	d.Resize(fyne.NewSize(400, 180))
+	d.Show()
+}
+
+// restore writes the backup back in place and notifies onRestore
+func (bv *BackupView) restore(backup controller.Backup) {
+	err := bv.ctrl.RestoreBackup(backup.Filename)
+	bv.audit.Record(audit.ActionRestore, backup.Name, "", err)
+	if err != nil {
+		helpers.ShowError(fmt.Errorf("restore failed: %w", err), bv.win)
+		return
+	}
+	helpers.ShowInformation("Restored",
+		fmt.Sprintf("Successfully restored %s", backup.Name), bv.win)
+	bv.refresh()
+	if bv.onRestore != nil {
+		bv.onRestore()
+	}
+}
+
+// restoreAs writes the backup to a new tunnel, leaving the original alone
+func (bv *BackupView) restoreAs(backup controller.Backup, name string) {
+	err := controller.RestoreBackupAs(bv.ctrl, backup.Filename, name)
+	bv.audit.Record(audit.ActionRestore, name, "", err)
+	if err != nil {
+		helpers.ShowError(fmt.Errorf("restore failed: %w", err), bv.win)
+		return
+	}
+	helpers.ShowInformation("Restored",
+		fmt.Sprintf("Restored %s from %s as %s", backup.Name, backup.Timestamp.Format("2006-01-02 15:04:05"), name), bv.win)
+	bv.refresh()
+	if bv.onRestore != nil {
+		bv.onRestore()
 	}
 }
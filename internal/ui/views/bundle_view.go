@@ -0,0 +1,360 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/bundle"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/settings"
+	"wgAdmin/internal/ui/helpers"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// minPassphrase is the shortest passphrase accepted for an export
+const minPassphrase = 8
+
+// BundleView exports all tunnels, client configs and settings into an
+// encrypted bundle and restores a bundle on this machine
+type BundleView struct {
+	window   fyne.Window
+	ctrl     controller.Controller
+	audit    *audit.Logger
+	settings *settings.AppSettings
+	onImport func(updated *settings.AppSettings)
+
+	win           fyne.Window
+	bundle        *bundle.Bundle
+	items         []bundle.Item
+	checks        []*widget.Check
+	settingsCheck *widget.Check
+	clientsCheck  *widget.Check
+	summary       *widget.Label
+	listContainer *fyne.Container
+}
+
+// NewBundleView creates a new export/import view. onImport is called after a
+// restore, with the imported settings if they were restored and nil otherwise.
+func NewBundleView(parent fyne.Window, ctrl controller.Controller, auditLog *audit.Logger, cfg *settings.AppSettings, onImport func(*settings.AppSettings)) *BundleView {
+	return &BundleView{
+		window:        parent,
+		ctrl:          ctrl,
+		audit:         auditLog,
+		settings:      cfg,
+		onImport:      onImport,
+		summary:       widget.NewLabel("Open a bundle to choose what to restore."),
+		listContainer: container.NewVBox(),
+	}
+}
+
+// Show opens the export/import window
+func (bv *BundleView) Show() {
+	bv.win = fyne.CurrentApp().NewWindow("Export / Import")
+	bv.win.Resize(fyne.NewSize(750, 600))
+
+	// --- Export ---
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder(fmt.Sprintf("At least %d characters", minPassphrase))
+	confirmEntry := widget.NewPasswordEntry()
+	exportBtn := widget.NewButtonWithIcon("Export Bundle...", theme.UploadIcon(), func() {
+		if len(passEntry.Text) < minPassphrase {
+			helpers.ShowError(fmt.Errorf("passphrase must be at least %d characters", minPassphrase), bv.win)
+			return
+		}
+		if passEntry.Text != confirmEntry.Text {
+			helpers.ShowError(errors.New("passphrases do not match"), bv.win)
+			return
+		}
+		bv.export(passEntry.Text)
+	})
+	exportBtn.Importance = widget.HighImportance
+
+	exportForm := widget.NewForm(
+		widget.NewFormItem("Passphrase", passEntry),
+		widget.NewFormItem("Confirm", confirmEntry),
+	)
+	exportCard := widget.NewCard("Export",
+		"All tunnel configs, generated client configs and settings in one encrypted file",
+		container.NewVBox(exportForm, container.NewHBox(layout.NewSpacer(), exportBtn)))
+
+	// --- Import ---
+	openBtn := widget.NewButtonWithIcon("Open Bundle...", theme.FolderOpenIcon(), func() {
+		bv.open()
+	})
+	bv.clientsCheck = widget.NewCheck("Restore client configs of the selected tunnels", nil)
+	bv.clientsCheck.SetChecked(true)
+	bv.settingsCheck = widget.NewCheck("Restore application settings", nil)
+	restoreBtn := widget.NewButtonWithIcon("Restore Selected", theme.DownloadIcon(), func() {
+		bv.confirmRestore()
+	})
+	restoreBtn.Importance = widget.HighImportance
+
+	importTop := container.NewBorder(nil, nil, nil, openBtn, bv.summary)
+	importBottom := container.NewVBox(bv.clientsCheck, bv.settingsCheck,
+		container.NewHBox(layout.NewSpacer(), restoreBtn))
+	scroll := container.NewVScroll(bv.listContainer)
+	scroll.SetMinSize(fyne.NewSize(680, 200))
+	importCard := widget.NewCard("Import",
+		"Each config is validated; tunnels that already exist are unselected until you choose to overwrite them",
+		container.NewBorder(importTop, importBottom, nil, nil, scroll))
+
+	content := container.NewBorder(container.NewPadded(exportCard), nil, nil, nil, container.NewPadded(importCard))
+	bv.win.SetContent(container.NewPadded(content))
+	bv.win.Show()
+}
+
+// export collects and encrypts the bundle, then asks where to save it
+func (bv *BundleView) export(passphrase string) {
+	b, err := bundle.Collect(bv.ctrl, bv.settings.ClientConfigDir, bv.settings)
+	if err == nil {
+		var data []byte
+		data, err = bundle.Encrypt(b, passphrase)
+		if err == nil {
+			bv.saveBundle(b, data)
+			return
+		}
+	}
+	bv.audit.Record(audit.ActionExport, "", "", err)
+	helpers.ShowError(fmt.Errorf("export failed: %w", err), bv.win)
+}
+
+func (bv *BundleView) saveBundle(b *bundle.Bundle, data []byte) {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			helpers.ShowError(err, bv.win)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		_, err = writer.Write(data)
+		if cerr := writer.Close(); err == nil {
+			err = cerr
+		}
+		bv.audit.Record(audit.ActionExport, "", "", err)
+		if err != nil {
+			helpers.ShowError(fmt.Errorf("export failed: %w", err), bv.win)
+			return
+		}
+		helpers.ShowInformation("Export Complete",
+			fmt.Sprintf("Exported %d tunnel(s) and %d client config(s) to %s", len(b.Tunnels), len(b.Clients), writer.URI().Path()), bv.win)
+	}, bv.win)
+	name := "wgadmin"
+	if b.Host != "" {
+		name += "-" + b.Host
+	}
+	d.SetFileName(name + "-" + time.Now().Format("20060102") + bundle.Extension)
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+// open reads a bundle file and asks for its passphrase
+func (bv *BundleView) open() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			helpers.ShowError(err, bv.win)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			helpers.ShowError(err, bv.win)
+			return
+		}
+
+		passEntry := widget.NewPasswordEntry()
+		dialog.ShowForm("Bundle Passphrase", "Open", "Cancel",
+			[]*widget.FormItem{widget.NewFormItem("Passphrase", passEntry)},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				b, err := bundle.Decrypt(data, passEntry.Text)
+				if err != nil {
+					helpers.ShowError(err, bv.win)
+					return
+				}
+				bv.load(b)
+			}, bv.win)
+	}, bv.win)
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+// load validates the tunnels of b against this machine and lists them
+func (bv *BundleView) load(b *bundle.Bundle) {
+	bv.bundle = b
+	bv.items = bundle.Plan(b, bv.ctrl)
+	bv.checks = make([]*widget.Check, len(bv.items))
+	bv.listContainer.RemoveAll()
+
+	conflicts, invalid := 0, 0
+	for i, item := range bv.items {
+		check := widget.NewCheck(item.Name, nil)
+		status := widget.NewLabel("")
+		var actions fyne.CanvasObject = layout.NewSpacer()
+
+		switch {
+		case item.Err != nil:
+			invalid++
+			check.Disable()
+			status.SetText("Invalid: " + item.Err.Error())
+			status.Importance = widget.DangerImportance
+		case item.Conflict:
+			conflicts++
+			status.SetText("Exists - selecting it overwrites the current config")
+			status.Importance = widget.WarningImportance
+			it := item
+			actions = widget.NewButtonWithIcon("Diff", theme.VisibilityIcon(), func() {
+				bv.showDiff(it)
+			})
+		default:
+			check.SetChecked(true)
+			status.SetText("New")
+			status.Importance = widget.SuccessImportance
+		}
+		if item.Clients > 0 {
+			status.SetText(fmt.Sprintf("%s (%d client configs)", status.Text, item.Clients))
+		}
+		status.Wrapping = fyne.TextWrapWord
+
+		bv.checks[i] = check
+		bv.listContainer.Add(container.NewBorder(nil, nil, check, actions, status))
+		bv.listContainer.Add(widget.NewSeparator())
+	}
+
+	created := b.Created.Local().Format("2006-01-02 15:04")
+	source := created
+	if b.Host != "" {
+		source = b.Host + ", " + created
+	}
+	bv.summary.SetText(fmt.Sprintf("Bundle from %s: %d tunnel(s), %d conflict(s), %d invalid",
+		source, len(bv.items), conflicts, invalid))
+
+	if bv.settingsCheck != nil {
+		if b.Settings == nil {
+			bv.settingsCheck.SetChecked(false)
+			bv.settingsCheck.Disable()
+		} else {
+			bv.settingsCheck.Enable()
+		}
+	}
+}
+
+// showDiff compares a conflicting tunnel with the current config
+func (bv *BundleView) showDiff(item bundle.Item) {
+	current, err := controller.ReadConfig(bv.ctrl.GetConfigPath(item.Name))
+	if err != nil {
+		helpers.ShowError(err, bv.win)
+		return
+	}
+	ShowConfigDiff(bv.win, "Import "+item.Name, "Select", current, item.Config, func() {
+		for i, it := range bv.items {
+			if it.Name == item.Name {
+				bv.checks[i].SetChecked(true)
+			}
+		}
+	})
+}
+
+// selected returns the checked tunnels
+func (bv *BundleView) selected() []bundle.Item {
+	var items []bundle.Item
+	for i, item := range bv.items {
+		if bv.checks[i].Checked {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (bv *BundleView) confirmRestore() {
+	if bv.bundle == nil {
+		helpers.ShowError(errors.New("open a bundle first"), bv.win)
+		return
+	}
+	items := bv.selected()
+	withSettings := bv.settingsCheck.Checked
+	if len(items) == 0 && !withSettings {
+		helpers.ShowError(errors.New("nothing selected to restore"), bv.win)
+		return
+	}
+
+	var overwrite []string
+	for _, item := range items {
+		if item.Conflict {
+			overwrite = append(overwrite, item.Name)
+		}
+	}
+	msg := fmt.Sprintf("Restore %d tunnel(s) into %s?", len(items), bv.settings.WGConfigPath)
+	if len(overwrite) > 0 {
+		msg += "\n\nThese existing tunnels will be overwritten: " + strings.Join(overwrite, ", ")
+	}
+	if withSettings {
+		msg += "\n\nApplication settings will be replaced."
+	}
+	helpers.ShowConfirm("Restore Bundle", msg, func(yes bool) {
+		if yes {
+			bv.restore(items, bv.clientsCheck.Checked, withSettings)
+		}
+	}, bv.win)
+}
+
+// restore writes the chosen tunnels, their client configs and optionally the
+// settings, then reports what failed
+func (bv *BundleView) restore(items []bundle.Item, withClients, withSettings bool) {
+	var failed []string
+	restored, clients := 0, 0
+	for _, item := range items {
+		err := bundle.RestoreTunnel(bv.ctrl, item)
+		bv.audit.Record(audit.ActionImport, item.Name, "", err)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", item.Name, err))
+			continue
+		}
+		restored++
+
+		if withClients {
+			n, err := bundle.RestoreClients(bv.bundle, item.Name, bv.settings.ClientConfigDir)
+			clients += n
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s client configs: %v", item.Name, err))
+			}
+		}
+	}
+
+	var updated *settings.AppSettings
+	if withSettings && bv.bundle.Settings != nil {
+		updated = bv.bundle.Settings
+		updated.Save(fyne.CurrentApp().Preferences())
+		bv.audit.Record(audit.ActionSettings, "", "", nil)
+	}
+
+	msg := fmt.Sprintf("Restored %d tunnel(s) and %d client config(s).", restored, clients)
+	if updated != nil {
+		msg += "\nSettings were restored."
+	}
+	if len(failed) > 0 {
+		helpers.ShowError(fmt.Errorf("%s\n\nFailed:\n%s", msg, strings.Join(failed, "\n")), bv.win)
+	} else {
+		helpers.ShowInformation("Restore Complete", msg, bv.win)
+	}
+
+	bv.load(bv.bundle)
+	if bv.onImport != nil {
+		bv.onImport(updated)
+	}
+}
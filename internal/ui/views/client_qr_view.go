@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"wgAdmin/internal/qr"
+	"wgAdmin/internal/ui/helpers"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// qrPNGSize is the pixel size of exported PNG images
+const qrPNGSize = 1024
+
+// ClientQRView shows client configs as QR codes for mobile onboarding
+type ClientQRView struct {
+	tunnelName string
+	paths      []string
+}
+
+// NewClientQRView creates a view of the given client config files
+func NewClientQRView(tunnelName string, paths []string) *ClientQRView {
+	return &ClientQRView{
+		tunnelName: tunnelName,
+		paths:      paths,
+	}
+}
+
+// ClientConfigPaths returns the client configs already generated for a tunnel
+func ClientConfigPaths(clientConfigDir, tunnelName string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(clientConfigDir, tunnelName, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Show opens the QR code window
+func (v *ClientQRView) Show() {
+	win := fyne.CurrentApp().NewWindow("Client QR Codes: " + v.tunnelName)
+	win.Resize(fyne.NewSize(560, 700))
+
+	if len(v.paths) == 0 {
+		win.SetContent(container.NewCenter(widget.NewLabel("No client configs found for this tunnel.")))
+		win.Show()
+		return
+	}
+
+	image := canvas.NewImageFromImage(nil)
+	image.FillMode = canvas.ImageFillContain
+	image.ScaleMode = canvas.ImageScalePixels
+	image.SetMinSize(fyne.NewSize(400, 400))
+
+	pathLabel := widget.NewLabel("")
+	pathLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	pathLabel.Wrapping = fyne.TextWrapBreak
+
+	var current struct {
+		name   string
+		config string
+		code   *qr.Code
+	}
+
+	load := func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			helpers.ShowError(fmt.Errorf("failed to read client config: %w", err), win)
+			return
+		}
+		code, err := qr.New(string(data))
+		if err != nil {
+			helpers.ShowError(err, win)
+			return
+		}
+		current.name = strings.TrimSuffix(filepath.Base(path), ".conf")
+		current.config = string(data)
+		current.code = code
+
+		image.Image = code.Image(512)
+		image.Refresh()
+		absPath, _ := filepath.Abs(path)
+		pathLabel.SetText(absPath)
+	}
+
+	names := make([]string, len(v.paths))
+	byName := make(map[string]string, len(v.paths))
+	for i, path := range v.paths {
+		names[i] = strings.TrimSuffix(filepath.Base(path), ".conf")
+		byName[names[i]] = path
+	}
+	clientSelect := widget.NewSelect(names, func(name string) {
+		load(byName[name])
+	})
+
+	save := func(ext string, render func() ([]byte, error)) {
+		if current.code == nil {
+			return
+		}
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				helpers.ShowError(err, win)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			data, err := render()
+			if err == nil {
+				_, err = writer.Write(data)
+			}
+			if err != nil {
+				helpers.ShowError(fmt.Errorf("failed to save QR code: %w", err), win)
+			}
+		}, win)
+		d.SetFileName(current.name + ext)
+		d.Resize(fyne.NewSize(800, 600))
+		d.Show()
+	}
+
+	savePNGBtn := widget.NewButtonWithIcon("Save PNG", theme.DocumentSaveIcon(), func() {
+		save(".png", func() ([]byte, error) {
+			return current.code.PNG(qrPNGSize)
+		})
+	})
+	saveSVGBtn := widget.NewButtonWithIcon("Save SVG", theme.DocumentSaveIcon(), func() {
+		save(".svg", func() ([]byte, error) {
+			return current.code.SVG(), nil
+		})
+	})
+	copyBtn := widget.NewButtonWithIcon("Copy Config", theme.ContentCopyIcon(), func() {
+		if current.config != "" {
+			win.Clipboard().SetContent(current.config)
+		}
+	})
+
+	header := container.NewBorder(nil, nil, widget.NewLabel("Client:"), nil, clientSelect)
+	footer := container.NewVBox(
+		pathLabel,
+		container.NewHBox(savePNGBtn, saveSVGBtn, copyBtn),
+	)
+
+	win.SetContent(container.NewPadded(container.NewBorder(header, footer, nil, nil, image)))
+	clientSelect.SetSelected(names[0])
+	win.Show()
+}
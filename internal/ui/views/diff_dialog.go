@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"image/color"
+
+	"wgAdmin/internal/confdiff"
+	customTheme "wgAdmin/internal/ui/theme"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+)
+
+// ShowConfigDiff shows what changes between oldCfg and newCfg side by side,
+// with keys redacted unless revealed, and calls onConfirm if the user
+// accepts. oldCfg is nil when the file does not exist yet.
+func ShowConfigDiff(parent fyne.Window, title, confirm string, oldCfg, newCfg *config.Config, onConfirm func()) {
+	rows := confdiff.Compare(oldCfg, newCfg)
+
+	rowsBox := container.NewVBox()
+	render := func(redact bool) {
+		rowsBox.RemoveAll()
+		for _, r := range rows {
+			left, right := r.Left, r.Right
+			if redact {
+				left, right = confdiff.Redact(left), confdiff.Redact(right)
+			}
+			rowsBox.Add(container.NewGridWithColumns(2,
+				newDiffCell(left, r.Op, true),
+				newDiffCell(right, r.Op, false),
+			))
+		}
+		rowsBox.Refresh()
+	}
+	render(true)
+
+	showKeys := widget.NewCheck("Show keys", func(on bool) {
+		render(!on)
+	})
+
+	summary := "Review the changes before they are written."
+	if !confdiff.HasChanges(rows) {
+		summary = "No changes."
+	}
+	leftTitle := widget.NewLabel("Current")
+	leftTitle.TextStyle = fyne.TextStyle{Bold: true}
+	if oldCfg == nil {
+		leftTitle.SetText("Current (new file)")
+	}
+	rightTitle := widget.NewLabel("New")
+	rightTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	header := container.NewVBox(
+		container.NewBorder(nil, nil, nil, showKeys, widget.NewLabel(summary)),
+		container.NewGridWithColumns(2, leftTitle, rightTitle),
+	)
+	content := container.NewBorder(header, nil, nil, nil, container.NewVScroll(rowsBox))
+
+	d := dialog.NewCustomConfirm(title, confirm, "Cancel", content, func(ok bool) {
+		if ok && onConfirm != nil {
+			onConfirm()
+		}
+	}, parent)
+	d.Resize(fyne.NewSize(900, 600))
+	d.Show()
+}
+
+// newDiffCell renders one side of a diff row, tinted by how it changed
+func newDiffCell(text string, op confdiff.Op, left bool) fyne.CanvasObject {
+	variant := customTheme.CurrentVariant()
+
+	var bg color.Color = color.Transparent
+	switch {
+	case op == confdiff.Changed:
+		bg = customTheme.AppColors.StatusWarningBg(variant)
+	case op == confdiff.Removed && left:
+		bg = customTheme.AppColors.StatusErrorBg(variant)
+	case op == confdiff.Added && !left:
+		bg = customTheme.AppColors.StatusSuccessBg(variant)
+	}
+
+	label := widget.NewLabel(text)
+	label.TextStyle = fyne.TextStyle{Monospace: true}
+	label.Wrapping = fyne.TextWrapBreak
+	return container.NewStack(canvas.NewRectangle(bg), label)
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/ui/helpers"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// DriftView shows where a running device differs from its config file and
+// reconciles them in either direction
+type DriftView struct {
+	window   fyne.Window
+	ctrl     controller.Controller
+	audit    *audit.Logger
+	name     string
+	diffs    []controller.Difference
+	onChange func()
+
+	win           fyne.Window
+	listContainer *fyne.Container
+}
+
+// NewDriftView creates a new drift view
+func NewDriftView(parent fyne.Window, ctrl controller.Controller, auditLog *audit.Logger, name string, diffs []controller.Difference, onChange func()) *DriftView {
+	return &DriftView{
+		window:        parent,
+		ctrl:          ctrl,
+		audit:         auditLog,
+		name:          name,
+		diffs:         diffs,
+		onChange:      onChange,
+		listContainer: container.NewVBox(),
+	}
+}
+
+// Show opens the drift window
+func (dv *DriftView) Show() {
+	dv.win = fyne.CurrentApp().NewWindow("Drift: " + dv.name)
+	dv.win.Resize(fyne.NewSize(800, 450))
+
+	applyBtn := widget.NewButtonWithIcon("Apply File to Device", theme.UploadIcon(), func() {
+		helpers.ShowConfirm("Apply File to Device",
+			fmt.Sprintf("Reconfigure the running device %s from %s?\n\nPeers not in the file are removed from the device.",
+				dv.name, dv.ctrl.GetConfigPath(dv.name)),
+			func(yes bool) {
+				if yes {
+					dv.reconcile(controller.ApplyFileToDevice, audit.ActionDriftApply, "Device updated from file")
+				}
+			}, dv.win)
+	})
+	applyBtn.Importance = widget.HighImportance
+
+	saveBtn := widget.NewButtonWithIcon("Save Device to File", theme.DocumentSaveIcon(), func() {
+		helpers.ShowConfirm("Save Device to File",
+			fmt.Sprintf("Overwrite %s with the running state of %s?", dv.ctrl.GetConfigPath(dv.name), dv.name),
+			func(yes bool) {
+				if yes {
+					dv.reconcile(controller.SaveDeviceToFile, audit.ActionDriftSave, "File updated from device")
+				}
+			}, dv.win)
+	})
+
+	buttons := container.NewHBox(layout.NewSpacer(), saveBtn, applyBtn)
+	content := container.NewBorder(nil, container.NewPadded(buttons), nil, nil, container.NewVScroll(dv.listContainer))
+	dv.win.SetContent(container.NewPadded(content))
+
+	dv.refresh()
+	dv.win.Show()
+}
+
+func (dv *DriftView) refresh() {
+	dv.listContainer.RemoveAll()
+
+	if len(dv.diffs) == 0 {
+		dv.listContainer.Add(widget.NewLabel("The running device matches the config file."))
+		return
+	}
+
+	grid := container.NewGridWithColumns(4)
+	for _, title := range []string{"Peer", "Setting", "File", "Device"} {
+		label := widget.NewLabel(title)
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		grid.Add(label)
+	}
+	for _, d := range dv.diffs {
+		peer := d.Peer
+		if peer == "" {
+			peer = "(interface)"
+		}
+		for _, text := range []string{peer, d.Field, d.File, d.Device} {
+			label := widget.NewLabel(text)
+			label.TextStyle = fyne.TextStyle{Monospace: true}
+			label.Wrapping = fyne.TextWrapBreak
+			grid.Add(label)
+		}
+	}
+	dv.listContainer.Add(grid)
+}
+
+// reconcile runs fn, then re-checks the drift and notifies onChange
+func (dv *DriftView) reconcile(fn func(controller.Controller, string) error, action, done string) {
+	err := fn(dv.ctrl, dv.name)
+	dv.audit.Record(action, dv.name, "", err)
+	if err != nil {
+		helpers.ShowError(err, dv.win)
+		return
+	}
+
+	diffs, err := controller.CheckDrift(dv.ctrl, dv.name)
+	if err != nil {
+		helpers.ShowError(err, dv.win)
+		return
+	}
+	dv.diffs = diffs
+	dv.refresh()
+	helpers.ShowInformation("Drift", done, dv.win)
+
+	if dv.onChange != nil {
+		dv.onChange()
+	}
+}
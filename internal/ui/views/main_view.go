@@ -1,15 +1,29 @@
 package ui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/netip"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"wgAdmin/internal/api"
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/expiry"
+	"wgAdmin/internal/metrics"
+	"wgAdmin/internal/netscan"
+	"wgAdmin/internal/notify"
+	"wgAdmin/internal/peermeta"
+	"wgAdmin/internal/reachability"
+	"wgAdmin/internal/scanwatch"
+	"wgAdmin/internal/search"
 	"wgAdmin/internal/settings"
+	"wgAdmin/internal/traffic"
 	"wgAdmin/internal/ui/helpers"
 	wgtheme "wgAdmin/internal/ui/theme"
 	"wgAdmin/internal/wgwidget"
@@ -18,18 +32,30 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
-	"github.com/MrVasquez96/go-wg/wg"
 	"github.com/MrVasquez96/go-wg/wg/config"
 )
 
+// historySpan is how far back the traffic history reaches
+const historySpan = 24 * time.Hour
+
+// scanHistoryKeep is the number of scheduled scan runs kept per tunnel
+const scanHistoryKeep = 50
+
 // MainView is the main application view
 type MainView struct {
-	window        fyne.Window
-	ctrl          *wg.WG
-	settings      *settings.AppSettings
+	window fyne.Window
+
+	// mu guards ctrl, settings and audit, which applySettings replaces
+	// while background loops read them; use the accessors below
+	mu       sync.RWMutex
+	ctrl     controller.Controller
+	settings *settings.AppSettings
+	audit    *audit.Logger
+
 	listContainer *fyne.Container
 	statusBar     *wgwidget.StatusBar
 	busyDialog    *wgwidget.BusyDialog
@@ -40,12 +66,40 @@ type MainView struct {
 	headerBg      *canvas.Rectangle
 
 	interfaces  []config.Interface
+	configs     map[string]*config.Config // as edited, for search
+	peerStats   map[string][]controller.PeerStats
+	drift       map[string][]controller.Difference
+	expanded    map[string]bool
+	cards       map[string]*wgwidget.InterfaceCard // as shown, by tunnel
+	history     *traffic.Recorder
+	apiServer   *api.Server
+	metrics     *metrics.Exporter
+	tray        desktop.App // nil when the driver has no system tray
+	notifier    *notify.Notifier
+	scanHistory *scanwatch.History
+	monitor     *reachability.Monitor
+	stopExpiry  chan struct{}
+	stopScans   chan struct{}
+	stopMonitor chan struct{}
 	stopAuto    chan struct{}
 	lastRefresh time.Time
 }
 
 // NewMainView creates a new main view
-func NewMainView(window fyne.Window, ctrl *wg.WG, cfg *settings.AppSettings) *MainView {
+func NewMainView(window fyne.Window, ctrl controller.Controller, cfg *settings.AppSettings) *MainView {
+	history := traffic.NewRecorder(traffic.CapacityFor(historySpan, time.Duration(cfg.AutoRefreshSecs)*time.Second))
+	if cfg.TrafficHistoryFile != "" {
+		if err := history.Load(cfg.TrafficHistoryFile); err != nil {
+			log.Printf("load traffic history: %v", err)
+		}
+	}
+	scanHistory := scanwatch.NewHistory(scanHistoryKeep)
+	if cfg.ScanHistoryFile != "" {
+		if err := scanHistory.Load(cfg.ScanHistoryFile); err != nil {
+			log.Printf("load scan history: %v", err)
+		}
+	}
+
 	return &MainView{
 		window:        window,
 		ctrl:          ctrl,
@@ -55,14 +109,28 @@ func NewMainView(window fyne.Window, ctrl *wg.WG, cfg *settings.AppSettings) *Ma
 		busyDialog:    wgwidget.NewBusyDialog(window),
 		filterEntry:   widget.NewEntry(),
 		autoRefresh:   widget.NewCheck(fmt.Sprintf("Auto refresh (%ds)", cfg.AutoRefreshSecs), nil),
+		configs:       make(map[string]*config.Config),
+		peerStats:     make(map[string][]controller.PeerStats),
+		drift:         make(map[string][]controller.Difference),
+		expanded:      make(map[string]bool),
+		history:       history,
+		audit:         audit.New(cfg.AuditLogFile, "gui"),
 		stopAuto:      make(chan struct{}),
+		notifier:      notify.New(cfg, sendNotification),
+		scanHistory:   scanHistory,
+		monitor:       reachability.New(reachability.DefaultKeep),
 	}
 }
 
+// sendNotification shows e as a desktop notification
+func sendNotification(e notify.Event) {
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(e.Title, e.Message))
+}
+
 // Build creates the main view content
 func (v *MainView) Build(meta fyne.AppMetadata) fyne.CanvasObject {
 	// Title — use the custom theme so forced variant is respected
-	customT := wgtheme.NewWGAdminTheme(v.settings)
+	customT := wgtheme.NewWGAdminTheme(v.appSettings())
 	variant := wgtheme.CurrentVariant()
 	v.headerTitle = canvas.NewText(meta.Name, customT.Color(theme.ColorNameForeground, variant))
 	v.headerTitle.TextStyle = fyne.TextStyle{Bold: true}
@@ -82,12 +150,22 @@ func (v *MainView) Build(meta fyne.AppMetadata) fyne.CanvasObject {
 		v.showBackupsDialog()
 	})
 
+	// Export/import bundle button
+	bundleBtn := widget.NewButtonWithIcon("", theme.StorageIcon(), func() {
+		v.showBundleDialog()
+	})
+
+	// Audit log button
+	auditBtn := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		NewAuditView(v.auditLog().Path()).Show()
+	})
+
 	// Filter
-	v.filterEntry.SetPlaceHolder("Filter by string...")
+	v.filterEntry.SetPlaceHolder("Search tunnels, peers, keys, IPs...")
 	v.filterEntry.OnChanged = func(s string) {
 		v.rebuild()
 	}
-	filterContainer := container.NewGridWrap(fyne.NewSize(200, v.filterEntry.MinSize().Height), v.filterEntry)
+	filterContainer := container.NewGridWrap(fyne.NewSize(260, v.filterEntry.MinSize().Height), v.filterEntry)
 
 	// Refresh button
 	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
@@ -105,20 +183,27 @@ func (v *MainView) Build(meta fyne.AppMetadata) fyne.CanvasObject {
 
 	// Settings button
 	settingsBtn := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
-		sv := NewSettingsView(v.window, v.settings, func(updated *settings.AppSettings) {
+		sv := NewSettingsView(v.window, v.appSettings(), func(updated *settings.AppSettings) {
 			v.applySettings(updated)
 		})
 		sv.Show()
 	})
 
 	// Auto-refresh on startup if configured
-	if v.settings.AutoRefreshEnabled {
+	if v.appSettings().AutoRefreshEnabled {
 		v.autoRefresh.SetChecked(true)
 	}
 
+	v.restartAPI()
+	v.restartMetrics()
+	v.restartExpiry()
+	v.restartScheduledScans()
+	v.restartMonitor()
+	v.setupTray()
+
 	// Header layout with background
 	leftHeader := container.NewHBox(v.headerTitle)
-	rightHeader := container.NewHBox(filterContainer, importBtn, addBtn, backupsBtn, v.autoRefresh, refreshBtn, settingsBtn)
+	rightHeader := container.NewHBox(filterContainer, importBtn, addBtn, backupsBtn, bundleBtn, auditBtn, v.autoRefresh, refreshBtn, settingsBtn)
 	headerContent := container.NewBorder(nil, nil, leftHeader, rightHeader)
 	v.headerBg = canvas.NewRectangle(customT.Color(theme.ColorNameHeaderBackground, variant))
 	header := container.NewVBox(
@@ -132,7 +217,7 @@ func (v *MainView) Build(meta fyne.AppMetadata) fyne.CanvasObject {
 
 	// Footer hint
 	v.hint = widget.NewRichTextFromMarkdown(
-		fmt.Sprintf("Configs: `%s` | Native WireGuard | Requires root privileges", v.settings.WGConfigPath))
+		fmt.Sprintf("Configs: `%s` | Native WireGuard | Requires root privileges", v.appSettings().WGConfigPath))
 	v.hint.Wrapping = fyne.TextWrapWord
 
 	// Main content
@@ -165,32 +250,27 @@ func (v *MainView) newImportButton() *widget.Button {
 				helpers.ShowError(errors.New("Wireguard config must end with '.conf'"), v.window)
 				return
 			}
-			cfg, err := config.ParseConfig(filePath)
+			cfg, err := controller.ReadConfig(filePath)
 			if err != nil {
 				helpers.ShowError(errors.New("invalid config\n"+err.Error()), v.window)
 				return
 			}
 			if err = cfg.Validate(); err != nil {
-				helpers.ShowInformation("Validation", "Failed to validate config!", v.window)
+				helpers.ShowError(errors.New("Failed to validate config:\n"+err.Error()), v.window)
+				return
 			}
 			name := cfg.Name
 			if name == "Unknown" {
 				name = reader.URI().Name()
 				name = filepath.Base(name)
 			}
-			_, err = os.Stat(filepath.Join(v.settings.WGConfigPath, reader.URI().Name()))
-
-			if err == nil {
-				helpers.ShowConfirm("File exists", "File already exists. Overwrite?", func(b bool) {
-					if b {
-						v.save(name, cfg)
-					} else {
-						helpers.ShowInformation("Aborted", "Nothing saved", v.window)
-					}
-				}, v.window)
-				return
+			var current *config.Config
+			if tunnel := strings.TrimSuffix(name, ".conf"); v.controller().ConfigExists(tunnel) {
+				current, _ = controller.ReadConfig(v.controller().GetConfigPath(tunnel))
 			}
-			v.save(name, cfg)
+			ShowConfigDiff(v.window, "Import "+strings.TrimSuffix(name, ".conf"), "Import", current, cfg, func() {
+				v.save(name, cfg)
+			})
 
 		}, v.window)
 		fileDialog.Resize(fyne.NewSize(800, 600))
@@ -201,7 +281,8 @@ func (v *MainView) save(name string, cfg *config.Config) {
 	if strings.Contains(name, ".conf") {
 		name = strings.ReplaceAll(name, ".conf", "")
 	}
-	err := config.WriteConfig(v.settings.WGConfigPath, name, cfg)
+	err := v.controller().WriteConfig(name, *cfg)
+	v.auditLog().Record(audit.ActionImport, name, "", err)
 	if err != nil {
 		helpers.ShowError(errors.New("Error saving:\n"+err.Error()), v.window)
 		return
@@ -214,7 +295,14 @@ func (v *MainView) Refresh() {
 	v.busyDialog.Show("Refresh", "Refreshing interfaces...")
 
 	go func() {
-		interfaces, err := v.ctrl.ListInterfaces()
+		interfaces, err := v.controller().ListInterfaces()
+		configs := v.collectConfigs(interfaces)
+		stats := v.collectPeerStats(interfaces)
+		v.recordTraffic(stats)
+		drift := v.collectDrift(interfaces)
+		if err == nil {
+			v.notifier.Update(notify.Snapshot{Interfaces: interfaces, Peers: stats})
+		}
 
 		fyne.DoAndWait(func() {
 			v.busyDialog.Hide()
@@ -225,29 +313,49 @@ func (v *MainView) Refresh() {
 			}
 
 			v.interfaces = interfaces
+			v.configs = configs
+			v.peerStats = stats
+			v.drift = drift
 			v.lastRefresh = time.Now()
 			v.rebuild()
+			v.updateTray()
 		})
 	}()
 }
 
 func (v *MainView) rebuild() {
 	v.listContainer.Objects = nil
+	v.cards = make(map[string]*wgwidget.InterfaceCard)
 
-	filter := strings.TrimSpace(strings.ToLower(v.filterEntry.Text))
+	var shown map[string]bool
+	if query := strings.TrimSpace(v.filterEntry.Text); query != "" {
+		matches := search.Find(v.searchTunnels(), query)
+		shown = search.Tunnels(matches)
+		v.listContainer.Add(v.searchResults(matches))
+	}
 
 	for i, iface := range v.interfaces {
-		if filter != "" && !strings.Contains(strings.ToLower(iface.Name), filter) {
+		if shown != nil && !shown[iface.Name] {
 			continue
 		}
 
 		ifaceCopy := iface
-		card := wgwidget.NewInterfaceCard(ifaceCopy, wgwidget.InterfaceCardCallbacks{
+		state := wgwidget.InterfaceCardState{
+			Peers:         v.peerStats[iface.Name],
+			PeersExpanded: v.expanded[iface.Name],
+			StaleAfter:    time.Duration(v.appSettings().PeerStaleMinutes) * time.Minute,
+			Drift:         v.drift[iface.Name],
+			Health:        v.peerHealth(iface.Name),
+		}
+		card := wgwidget.NewInterfaceCard(ifaceCopy, state, wgwidget.InterfaceCardCallbacks{
 			OnToggle: func(name string, activate bool) {
 				v.toggleInterface(name, activate)
 			},
 			OnScan: func(name, ip string) {
-				scanView := NewScanViewWithProgress(name, ip, v.settings.ScanWorkers, v.settings.ScanTimeoutSecs)
+				scanView := NewScanView(name, v.scanNetwork(name, ip), v.appSettings(), func() netscan.Tunnel {
+					return scanTunnel(v.controller(), name)
+				})
+				scanView.history = v.scanHistory
 				scanView.Show()
 			},
 			OnEdit: func(name string) {
@@ -263,8 +371,20 @@ func (v *MainView) rebuild() {
 				v.window.Clipboard().SetContent(pubKey)
 				v.statusBar.SetStatus("Public key copied to clipboard", true)
 			},
+			OnExpandPeers: func(name string, expanded bool) {
+				v.expanded[name] = expanded
+			},
+			OnDrift: func(name string) {
+				NewDriftView(v.window, v.controller(), v.auditLog(), name, v.drift[name], func() {
+					v.Refresh()
+				}).Show()
+			},
+			OnTraffic: func(name string) {
+				NewTrafficView(name, v.history, v.peerStats[name]).Show()
+			},
 		})
 
+		v.cards[iface.Name] = card
 		v.listContainer.Add(card)
 		if i != len(v.interfaces)-1 {
 			v.listContainer.Add(widget.NewSeparator())
@@ -274,6 +394,122 @@ func (v *MainView) rebuild() {
 	v.listContainer.Refresh()
 }
 
+// collectPeerStats reads the live peer state of every active interface
+func (v *MainView) collectPeerStats(interfaces []config.Interface) map[string][]controller.PeerStats {
+	stats := make(map[string][]controller.PeerStats)
+	for _, iface := range interfaces {
+		if !iface.Active {
+			continue
+		}
+		peers, err := controller.GetPeerStats(v.controller(), iface.Name)
+		if err != nil {
+			log.Printf("peer stats for %s: %v", iface.Name, err)
+			continue
+		}
+		stats[iface.Name] = peers
+	}
+	return stats
+}
+
+// collectDrift compares the config file of every active interface with its device
+func (v *MainView) collectDrift(interfaces []config.Interface) map[string][]controller.Difference {
+	drift := make(map[string][]controller.Difference)
+	for _, iface := range interfaces {
+		if !iface.Active {
+			continue
+		}
+		diffs, err := controller.CheckDrift(v.controller(), iface.Name)
+		if err != nil {
+			log.Printf("drift check for %s: %v", iface.Name, err)
+			continue
+		}
+		if len(diffs) > 0 {
+			drift[iface.Name] = diffs
+		}
+	}
+	return drift
+}
+
+// scanNetwork returns the network to scan for an interface: the network of
+// its configured address holding ip, or a /24 around ip when there is none
+func (v *MainView) scanNetwork(name, ip string) string {
+	if cfg := v.configs[name]; cfg != nil {
+		for _, addr := range cfg.Interface.Address {
+			if addr.IP.String() == ip || len(cfg.Interface.Address) == 1 {
+				return netscan.Network(addr)
+			}
+		}
+	}
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		if prefix, err := addr.Prefix(24); err == nil && addr.Is4() {
+			return prefix.String()
+		}
+	}
+	return ""
+}
+
+// scanTunnel returns the addresses and peers of a tunnel for matching scan
+// results, with the last handshakes of the running device
+func scanTunnel(ctrl controller.Controller, name string) netscan.Tunnel {
+	var t netscan.Tunnel
+	cfg, err := controller.ReadConfig(ctrl.GetConfigPath(name))
+	if err != nil {
+		log.Printf("read %s for scan: %v", name, err)
+		return t
+	}
+	handshakes := make(map[string]time.Time)
+	if stats, err := controller.GetPeerStats(ctrl, name); err == nil {
+		for _, p := range stats {
+			handshakes[p.PublicKey] = p.LastHandshake
+		}
+	}
+
+	t.Addresses = cfg.Interface.Address
+	for _, p := range cfg.Peers {
+		key := p.PublicKey.String()
+		meta := peermeta.Parse(p.Name)
+		if meta.Disabled {
+			meta.Name += " (disabled)"
+		}
+		t.Peers = append(t.Peers, netscan.KnownPeer{
+			Name:          meta.Name,
+			PublicKey:     key,
+			AllowedIPs:    p.AllowedIPs,
+			LastHandshake: handshakes[key],
+		})
+	}
+	return t
+}
+
+// collectConfigs reads the config of every interface, disabled peers included
+func (v *MainView) collectConfigs(interfaces []config.Interface) map[string]*config.Config {
+	configs := make(map[string]*config.Config, len(interfaces))
+	for _, iface := range interfaces {
+		cfg, err := controller.ReadConfig(v.controller().GetConfigPath(iface.Name))
+		if err != nil {
+			log.Printf("read config of %s: %v", iface.Name, err)
+			continue
+		}
+		configs[iface.Name] = cfg
+	}
+	return configs
+}
+
+// recordTraffic adds the byte counters to the history and persists it
+// at most once a minute when a history file is configured
+func (v *MainView) recordTraffic(stats map[string][]controller.PeerStats) {
+	now := time.Now()
+	for name, peers := range stats {
+		v.history.Record(name, peers, now)
+	}
+
+	if path := v.appSettings().TrafficHistoryFile; path != "" {
+		if err := v.history.SaveIfDue(path, time.Minute); err != nil {
+			log.Printf("save traffic history: %v", err)
+		}
+	}
+}
+
 func (v *MainView) toggleInterface(name string, activate bool) {
 	action := "Deactivating"
 	if activate {
@@ -283,7 +519,18 @@ func (v *MainView) toggleInterface(name string, activate bool) {
 	v.busyDialog.Show(name, fmt.Sprintf("%s...", action))
 
 	go func() {
-		err := v.ctrl.ToggleInterface(name, activate)
+		if !activate {
+			v.notifier.ExpectDown(name)
+		}
+		err := v.controller().ToggleInterface(name, activate)
+		if err != nil {
+			v.notifier.ToggleFailed(name, activate, err)
+		}
+		if activate {
+			v.auditLog().Record(audit.ActionActivate, name, "", err)
+		} else {
+			v.auditLog().Record(audit.ActionDeactivate, name, "", err)
+		}
 
 		fyne.Do(func() {
 			v.busyDialog.Hide()
@@ -312,53 +559,65 @@ func (v *MainView) toggleInterface(name string, activate bool) {
 	}()
 }
 
-func (v *MainView) showAddTunnelForm() {
-	form := NewTunnelForm(v.window, v.ctrl, "", nil, func(name string, cfg *config.Config) error {
-		err := v.ctrl.WriteConfig(name, *cfg)
+func (v *MainView) showAddTunnelForm() *TunnelForm {
+	form := NewTunnelForm(v.window, v.controller(), "", nil, func(name string, cfg *config.Config) error {
+		err := v.controller().WriteConfig(name, *cfg)
+		v.auditLog().Record(audit.ActionCreate, name, "", err)
 		if err == nil {
 			v.Refresh()
 		}
 		return err
-	}, nil, v.settings.ClientConfigDir)
+	}, nil, v.appSettings().ClientConfigDir)
 	form.Show()
+	return form
 }
 
 func (v *MainView) showEditTunnelForm(name string) {
-	path := v.ctrl.GetConfigPath(name)
-	cfg, err := config.ParseConfig(path)
+	path := v.controller().GetConfigPath(name)
+	cfg, err := controller.ReadConfig(path)
 	if err != nil {
 		helpers.ShowError(fmt.Errorf("failed to load config: %w", err), v.window)
 		return
 	}
 
-	opened := v.preCheckActiveDialog(name, cfg)
+	opened := v.preCheckActiveDialog(name, cfg, v.openEditForm)
 	if !opened {
 		v.openEditForm(name, cfg)
 	}
 }
 
 func (v *MainView) showEditPeersTunnelForm(name string) {
-	path := v.ctrl.GetConfigPath(name)
-	cfg, err := config.ParseConfig(path)
+	v.showPeersFormAt(name, "")
+}
+
+// showPeersFormAt opens the peers form of a tunnel with the peer with
+// publicKey selected; an empty key selects nothing
+func (v *MainView) showPeersFormAt(name, publicKey string) {
+	path := v.controller().GetConfigPath(name)
+	cfg, err := controller.ReadConfig(path)
 	if err != nil {
 		helpers.ShowError(fmt.Errorf("failed to load config: %w", err), v.window)
 		return
 	}
 
-	opened := v.preCheckActiveDialog(name, cfg)
+	open := func(name string, cfg *config.Config) {
+		v.openPeersForm(name, cfg).selectPeer(publicKey)
+	}
+	opened := v.preCheckActiveDialog(name, cfg, open)
 	if !opened {
-		v.openPeersForm(name, cfg)
+		open(name, cfg)
 	}
 }
 
-func (v *MainView) preCheckActiveDialog(name string, cfg *config.Config) bool {
+func (v *MainView) preCheckActiveDialog(name string, cfg *config.Config, open func(string, *config.Config)) bool {
 	iface := v.findInterface(name)
 	if iface != nil && iface.Active {
 		helpers.ShowConfirm("Tunnel Active",
-			fmt.Sprintf("Tunnel '%s' is currently active. It's recommended to deactivate before editing.\n\nContinue anyway?", name),
+			fmt.Sprintf("Tunnel '%s' is currently active. Peer changes are applied to the running device when saved; "+
+				"interface changes need the tunnel to be reactivated.\n\nContinue?", name),
 			func(yes bool) {
 				if yes {
-					v.openEditForm(name, cfg)
+					open(name, cfg)
 				}
 			}, v.window)
 		return true
@@ -370,23 +629,70 @@ func (v *MainView) openEditForm(name string, cfg *config.Config) {
 	v.openForm(name, cfg, true)
 }
 
-func (v *MainView) openPeersForm(name string, cfg *config.Config) {
-	v.openForm(name, cfg, false)
+func (v *MainView) openPeersForm(name string, cfg *config.Config) *TunnelForm {
+	return v.openForm(name, cfg, false)
 }
 
-func (v *MainView) openForm(name string, cfg *config.Config, isMain bool) {
-	form := NewTunnelForm(v.window, v.ctrl, name, cfg, func(_ string, newConfig *config.Config) error {
-		err := v.ctrl.WriteConfig(name, *newConfig)
+func (v *MainView) openForm(name string, cfg *config.Config, isMain bool) *TunnelForm {
+	form := NewTunnelForm(v.window, v.controller(), name, cfg, func(_ string, newConfig *config.Config) error {
+		iface := v.findInterface(name)
+		active := iface != nil && iface.Active
+
+		var oldConfig *config.Config
+		if active {
+			var err error
+			if oldConfig, err = controller.ReadConfig(v.controller().GetConfigPath(name)); err != nil {
+				log.Printf("read %s before hot apply: %v", name, err)
+			}
+		}
+
+		err := v.controller().WriteConfig(name, *newConfig)
+		v.auditLog().Record(audit.ActionEdit, name, "", err)
 		if err == nil {
+			if active {
+				v.hotApply(name, oldConfig, newConfig)
+			}
 			v.Refresh()
 		}
 		return err
-	}, nil, v.settings.ClientConfigDir)
+	}, nil, v.appSettings().ClientConfigDir)
+	if v.appSettings().MonitorSecs > 0 {
+		form.health = func(publicKey string) reachability.Stats {
+			return v.monitor.Stats(name, publicKey)
+		}
+	}
 	if isMain {
 		form.Show()
 	} else {
 		form.ShowPeers()
 	}
+	return form
+}
+
+// hotApply pushes the peer changes of a saved config to the running device
+// and reports the outcome of every peer in the status bar
+func (v *MainView) hotApply(name string, oldConfig, newConfig *config.Config) {
+	if oldConfig == nil {
+		v.statusBar.SetStatus(fmt.Sprintf("Saved %s; could not read the previous config, reactivate to apply", name), false)
+		return
+	}
+
+	results := controller.ApplyPeerDelta(v.controller(), name, oldConfig.Peers, newConfig.Peers)
+	if len(results) == 0 {
+		v.statusBar.SetStatus(fmt.Sprintf("Saved %s (no peer changes to apply)", name), true)
+		return
+	}
+
+	ok := true
+	parts := make([]string, len(results))
+	for i, r := range results {
+		v.auditLog().Record(audit.ActionPeerApply, name, r.Peer, r.Err)
+		parts[i] = r.String()
+		if r.Err != nil {
+			ok = false
+		}
+	}
+	v.statusBar.SetStatus(fmt.Sprintf("Applied live to %s: %s", name, strings.Join(parts, ", ")), ok)
 }
 
 func (v *MainView) confirmDeleteTunnel(name string) {
@@ -397,10 +703,11 @@ func (v *MainView) confirmDeleteTunnel(name string) {
 
 		go func() {
 			if iface != nil && iface.Active {
-				_ = v.ctrl.ToggleInterface(name, false)
+				_ = v.controller().ToggleInterface(name, false)
 			}
 
-			err := v.ctrl.DeleteInterface(name, true)
+			err := v.controller().DeleteInterface(name, true)
+			v.auditLog().Record(audit.ActionDelete, name, "", err)
 
 			fyne.DoAndWait(func() {
 				v.busyDialog.Hide()
@@ -416,12 +723,12 @@ func (v *MainView) confirmDeleteTunnel(name string) {
 		}()
 	}
 
-	if !v.settings.ConfirmBeforeDelete {
+	if !v.appSettings().ConfirmBeforeDelete {
 		deleteFn()
 		return
 	}
 
-	msg := fmt.Sprintf("Delete tunnel '%s'?\n\nThis will remove:\n%s", name, v.ctrl.GetConfigPath(name))
+	msg := fmt.Sprintf("Delete tunnel '%s'?\n\nThis will remove:\n%s", name, v.controller().GetConfigPath(name))
 	if iface != nil && iface.Active {
 		msg += "\n\nWarning: This tunnel is currently active and will be deactivated."
 	}
@@ -434,8 +741,19 @@ func (v *MainView) confirmDeleteTunnel(name string) {
 	}, v.window)
 }
 
+func (v *MainView) showBundleDialog() {
+	bv := NewBundleView(v.window, v.controller(), v.auditLog(), v.appSettings(), func(updated *settings.AppSettings) {
+		if updated != nil {
+			v.applySettings(updated)
+			return
+		}
+		v.Refresh()
+	})
+	bv.Show()
+}
+
 func (v *MainView) showBackupsDialog() {
-	bv := NewBackupView(v.window, v.ctrl, func() {
+	bv := NewBackupView(v.window, v.controller(), v.auditLog(), func() {
 		v.Refresh()
 	})
 	bv.Show()
@@ -451,7 +769,7 @@ func (v *MainView) findInterface(name string) *config.Interface {
 }
 
 func (v *MainView) startAutoRefresh() {
-	ticker := time.NewTicker(time.Duration(v.settings.AutoRefreshSecs) * time.Second)
+	ticker := time.NewTicker(time.Duration(v.appSettings().AutoRefreshSecs) * time.Second)
 	defer ticker.Stop()
 
 	for {
@@ -471,15 +789,369 @@ func (v *MainView) stopAutoRefresh() {
 	}
 }
 
+// Close stops the background services of the view
+func (v *MainView) Close() {
+	v.stopAutoRefresh()
+	if v.apiServer != nil {
+		v.apiServer.Close()
+		v.apiServer = nil
+	}
+	if v.metrics != nil {
+		v.metrics.Close()
+		v.metrics = nil
+	}
+	if v.stopExpiry != nil {
+		close(v.stopExpiry)
+		v.stopExpiry = nil
+	}
+	if v.stopScans != nil {
+		close(v.stopScans)
+		v.stopScans = nil
+	}
+	if v.stopMonitor != nil {
+		close(v.stopMonitor)
+		v.stopMonitor = nil
+	}
+}
+
+// restartAPI stops the API server and starts it again with the current
+// settings and controller, if it is enabled
+func (v *MainView) restartAPI() {
+	if v.apiServer != nil {
+		v.apiServer.Close()
+		v.apiServer = nil
+	}
+	cfg := v.appSettings()
+	if !cfg.APIEnabled {
+		return
+	}
+
+	srv := api.New(v.controller(), cfg.ClientConfigDir, audit.New(cfg.AuditLogFile, "api"), cfg.APIToken)
+	opts := api.Options{Socket: cfg.APISocket, Address: cfg.APIAddress}
+	if err := srv.Start(opts); err != nil {
+		v.statusBar.SetStatus(fmt.Sprintf("API server not started: %v", err), false)
+		return
+	}
+	v.apiServer = srv
+}
+
+// restartMetrics stops the /metrics listener and starts it again with the
+// current settings and controller, if it is enabled
+func (v *MainView) restartMetrics() {
+	if v.metrics != nil {
+		v.metrics.Close()
+		v.metrics = nil
+	}
+	cfg := v.appSettings()
+	if !cfg.MetricsEnabled {
+		return
+	}
+
+	exp := metrics.New(v.controller(), time.Duration(cfg.PeerStaleMinutes)*time.Minute)
+	if err := exp.Start(cfg.MetricsAddress); err != nil {
+		v.statusBar.SetStatus(fmt.Sprintf("Metrics endpoint not started: %v", err), false)
+		return
+	}
+	v.metrics = exp
+}
+
+// restartExpiry stops the expired peer job and starts it again with the
+// current interval, controller and audit log, if it is enabled
+func (v *MainView) restartExpiry() {
+	if v.stopExpiry != nil {
+		close(v.stopExpiry)
+		v.stopExpiry = nil
+	}
+	if v.appSettings().ExpiryCheckMinutes <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	v.stopExpiry = stop
+	ctrl, auditLog := v.controller(), v.auditLog()
+	interval := time.Duration(v.appSettings().ExpiryCheckMinutes) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			v.expirePeers(ctrl, auditLog)
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// expirePeers removes peers whose access expired and reports them in the
+// status bar
+func (v *MainView) expirePeers(ctrl controller.Controller, auditLog *audit.Logger) {
+	removed, err := expiry.Run(ctrl, auditLog, time.Now())
+	if len(removed) == 0 && err == nil {
+		return
+	}
+
+	names := make([]string, len(removed))
+	for i, r := range removed {
+		names[i] = r.Tunnel + "/" + r.Peer
+	}
+	fyne.Do(func() {
+		if err != nil {
+			v.statusBar.SetStatus(fmt.Sprintf("Removing expired peers: %v", err), false)
+		} else {
+			v.statusBar.SetStatus(fmt.Sprintf("Removed expired peers: %s", strings.Join(names, ", ")), true)
+		}
+		if len(removed) > 0 {
+			v.Refresh()
+		}
+	})
+}
+
+// restartScheduledScans stops the scheduled scans and starts them again
+// with the current schedule and controller
+func (v *MainView) restartScheduledScans() {
+	if v.stopScans != nil {
+		close(v.stopScans)
+		v.stopScans = nil
+	}
+	entries := v.appSettings().ScanSchedule()
+	if len(entries) == 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	v.stopScans = stop
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	ctrl, cfg := v.controller(), v.appSettings()
+	for _, e := range entries {
+		go func() {
+			ticker := time.NewTicker(e.Interval)
+			defer ticker.Stop()
+			// A tunnel without history gets its baseline right away
+			if len(v.scanHistory.Runs(e.Tunnel)) == 0 {
+				v.runScheduledScan(ctx, ctrl, cfg, e.Tunnel)
+			}
+			for {
+				select {
+				case <-ticker.C:
+					v.runScheduledScan(ctx, ctrl, cfg, e.Tunnel)
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// runScheduledScan scans an active tunnel with its last scan choice, stores
+// the run and reports changes against the previous run
+func (v *MainView) runScheduledScan(ctx context.Context, ctrl controller.Controller, cfg *settings.AppSettings, name string) {
+	if _, err := ctrl.Device(name); err != nil {
+		return // not active
+	}
+	cidr, ports := scheduledScanTarget(cfg, scanTunnel(ctrl, name), settings.LoadScanChoice(fyne.CurrentApp().Preferences(), name))
+	if cidr == "" {
+		log.Printf("scheduled scan of %s: no network to scan", name)
+		return
+	}
+
+	run, err := scanwatch.Scan(ctx, cidr, ports, cfg.ScanWorkers, time.Duration(cfg.ScanTimeoutSecs)*time.Second)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		fyne.Do(func() {
+			v.statusBar.SetStatus(fmt.Sprintf("Scheduled scan of %s: %v", name, err), false)
+		})
+		return
+	}
+	run = v.scanHistory.Add(name, run)
+	if cfg.ScanHistoryFile != "" {
+		if err := v.scanHistory.Save(cfg.ScanHistoryFile); err != nil {
+			log.Printf("save scan history: %v", err)
+		}
+	}
+	if run.Changes.Empty() {
+		return
+	}
+
+	summary := run.Changes.String()
+	v.notifier.ScanChanged(name, summary)
+	fyne.Do(func() {
+		v.statusBar.SetStatus(fmt.Sprintf("Scan of %s: %s", name, summary), false)
+	})
+}
+
+// scheduledScanTarget returns the network and ports a scheduled scan uses:
+// the tunnel's last scan choice, else its first address network and the
+// first port profile
+func scheduledScanTarget(cfg *settings.AppSettings, tunnel netscan.Tunnel, choice settings.ScanChoice) (string, []int) {
+	cidr := choice.CIDR
+	if cidr == "" && len(tunnel.Addresses) > 0 {
+		cidr = netscan.Network(tunnel.Addresses[0])
+	}
+
+	profiles := cfg.PortProfiles()
+	ports := profiles[0].Ports
+	if choice.Profile == netscan.CustomProfile {
+		if custom, err := netscan.ParsePorts(choice.Ports); err == nil && len(custom) > 0 {
+			ports = custom
+		}
+	}
+	for _, p := range profiles {
+		if p.Name == choice.Profile {
+			ports = p.Ports
+		}
+	}
+	return cidr, ports
+}
+
+// restartMonitor stops the peer reachability probes and starts them again
+// with the current interval and controller, if they are enabled
+func (v *MainView) restartMonitor() {
+	if v.stopMonitor != nil {
+		close(v.stopMonitor)
+		v.stopMonitor = nil
+	}
+	if v.appSettings().MonitorSecs <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	v.stopMonitor = stop
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	ctrl := v.controller()
+	interval := time.Duration(v.appSettings().MonitorSecs) * time.Second
+	probe := reachability.Auto(v.appSettings().MonitorTCPPort)
+	// A probe never outlasts the interval, so rounds do not pile up
+	timeout := min(2*time.Second, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			v.monitor.Run(ctx, monitorTargets(ctrl), probe, timeout)
+			if ctx.Err() == nil {
+				fyne.Do(v.refreshHealth)
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// monitorTargets returns the tunnel address of every enabled peer of the
+// active interfaces
+func monitorTargets(ctrl controller.Controller) []reachability.Target {
+	interfaces, err := ctrl.ListInterfaces()
+	if err != nil {
+		log.Printf("list interfaces for reachability: %v", err)
+		return nil
+	}
+	var targets []reachability.Target
+	for _, iface := range interfaces {
+		if !iface.Active {
+			continue
+		}
+		cfg, err := controller.ReadConfig(ctrl.GetConfigPath(iface.Name))
+		if err != nil {
+			log.Printf("read %s for reachability: %v", iface.Name, err)
+			continue
+		}
+		for _, p := range cfg.Peers {
+			if peermeta.Parse(p.Name).Disabled {
+				continue
+			}
+			if addr, ok := reachability.Address(p.AllowedIPs); ok {
+				targets = append(targets, reachability.Target{Tunnel: iface.Name, PublicKey: p.PublicKey.String(), Addr: addr})
+			}
+		}
+	}
+	return targets
+}
+
+// refreshHealth shows the latest monitor round on the cards in place, and
+// rebuilds the list only when a card cannot be updated
+func (v *MainView) refreshHealth() {
+	for name, card := range v.cards {
+		if !card.UpdateHealth(v.peerHealth(name)) {
+			v.rebuild()
+			return
+		}
+	}
+}
+
+// peerHealth returns the reachability of the probed peers of a tunnel by
+// public key, or nil when the monitor is off
+func (v *MainView) peerHealth(name string) map[string]reachability.Stats {
+	if v.appSettings().MonitorSecs <= 0 {
+		return nil
+	}
+	health := make(map[string]reachability.Stats)
+	for _, p := range v.peerStats[name] {
+		if stats := v.monitor.Stats(name, p.PublicKey); len(stats.Samples) > 0 {
+			health[p.PublicKey] = stats
+		}
+	}
+	return health
+}
+
+// controller returns the controller of the current config path
+func (v *MainView) controller() controller.Controller {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.ctrl
+}
+
+// appSettings returns the current settings; they are replaced, never changed
+func (v *MainView) appSettings() *settings.AppSettings {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.settings
+}
+
+// auditLog returns the audit logger of the current log file
+func (v *MainView) auditLog() *audit.Logger {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.audit
+}
+
 func (v *MainView) applySettings(updated *settings.AppSettings) {
-	oldPath := v.settings.WGConfigPath
+	v.mu.Lock()
+	old := v.settings
 	v.settings = updated
+	// Re-initialize controller if path or backup retention changed
+	if updated.WGConfigPath != old.WGConfigPath || updated.BackupPolicy() != old.BackupPolicy() {
+		v.ctrl = controller.Open(updated.WGConfigPath, updated.BackupPolicy())
+	}
+	if updated.AuditLogFile != v.audit.Path() {
+		v.audit = audit.New(updated.AuditLogFile, "gui")
+	}
+	v.mu.Unlock()
 
-	// Re-initialize controller if path changed
-	if updated.WGConfigPath != oldPath {
-		newCtrl := wg.New(updated.WGConfigPath)
-		v.ctrl = &newCtrl
+	// The traffic history covers the same span at the new sampling interval
+	if updated.AutoRefreshSecs != old.AutoRefreshSecs {
+		v.history.SetCapacity(traffic.CapacityFor(historySpan, time.Duration(updated.AutoRefreshSecs)*time.Second))
 	}
+	v.notifier.SetSettings(updated)
+
+	v.restartAPI()
+	v.restartMetrics()
+	v.restartExpiry()
+	v.restartScheduledScans()
+	v.restartMonitor()
 
 	// Restart auto-refresh with new interval
 	v.stopAutoRefresh()
@@ -497,7 +1169,7 @@ func (v *MainView) applySettings(updated *settings.AppSettings) {
 
 	// Apply theme variant — the new theme reads ThemeVariant from settings
 	// and forces light/dark/system accordingly
-	newTheme := wgtheme.NewWGAdminTheme(v.settings)
+	newTheme := wgtheme.NewWGAdminTheme(v.appSettings())
 	fyne.CurrentApp().Settings().SetTheme(newTheme)
 
 	// Update header colors to match new theme variant
@@ -5,7 +5,10 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
+	"wgAdmin/internal/ipam"
+	"wgAdmin/internal/peermeta"
 	"wgAdmin/internal/ui/helpers"
 
 	"fyne.io/fyne/v2"
@@ -19,6 +22,9 @@ import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
+// expiryLayout is the format of the access expiry field
+const expiryLayout = "2006-01-02 15:04"
+
 // PeerForm handles peer configuration
 type PeerForm struct {
 	nameEntry                *widget.Entry
@@ -28,8 +34,15 @@ type PeerForm struct {
 	allowedIPsEntry          *widget.Entry
 	persistentKeepaliveEntry *widget.Entry
 	presharedKeyEntry        *widget.Entry
+	expiresEntry             *widget.Entry
 
 	generatedPrivateKey string
+	meta                peermeta.Meta // metadata stored with the name
+
+	// Address pool used to suggest and check AllowedIPs
+	subnets []net.IPNet
+	others  []config.PeerConfig
+	index   int
 
 	onSave   func(peer config.PeerConfig, privateKey string)
 	onCancel func()
@@ -45,6 +58,8 @@ func NewPeerForm(existing *config.PeerConfig, onSave func(config.PeerConfig, str
 		allowedIPsEntry:          widget.NewEntry(),
 		persistentKeepaliveEntry: widget.NewEntry(),
 		presharedKeyEntry:        widget.NewEntry(),
+		expiresEntry:             widget.NewEntry(),
+		index:                    -1,
 		onSave:                   onSave,
 		onCancel:                 onCancel,
 	}
@@ -55,13 +70,18 @@ func NewPeerForm(existing *config.PeerConfig, onSave func(config.PeerConfig, str
 	f.allowedIPsEntry.SetPlaceHolder("e.g., 10.0.0.2/32")
 	f.persistentKeepaliveEntry.SetPlaceHolder("e.g., 25 (seconds, optional)")
 	f.presharedKeyEntry.SetPlaceHolder("Base64 encoded key (optional)")
+	f.expiresEntry.SetPlaceHolder(expiryLayout + " (optional, local time)")
 
 	f.privateKeyEntry.OnChanged = func(s string) {
 		f.updatePublicKey()
 	}
 
 	if existing != nil {
-		f.nameEntry.SetText(existing.Name)
+		f.meta = peermeta.Parse(existing.Name)
+		f.nameEntry.SetText(f.meta.Name)
+		if !f.meta.Expires.IsZero() {
+			f.expiresEntry.SetText(f.meta.Expires.Local().Format(expiryLayout))
+		}
 		f.publicKeyEntry.SetText(existing.PublicKey.String())
 
 		ips := make([]string, len(existing.AllowedIPs))
@@ -101,8 +121,54 @@ func (f *PeerForm) updatePublicKey() {
 	f.generatedPrivateKey = f.privateKeyEntry.Text
 }
 
+// SetAddressPool sets the tunnel subnets and the peers of the tunnel. A new
+// peer is offered the next free address and AllowedIPs overlapping another
+// peer are rejected. index is the position of the edited peer in peers, or -1.
+func (f *PeerForm) SetAddressPool(subnets []net.IPNet, peers []config.PeerConfig, index int) {
+	f.subnets = subnets
+	f.others = peers
+	f.index = index
+}
+
+// nextFreeAddress returns the next unused address of every tunnel subnet
+func (f *PeerForm) nextFreeAddress() (string, error) {
+	others := make([]config.PeerConfig, 0, len(f.others))
+	for i, p := range f.others {
+		if i != f.index {
+			others = append(others, p)
+		}
+	}
+	free, err := ipam.Next(f.subnets, others)
+	if err != nil {
+		return "", err
+	}
+	addrs := make([]string, len(free))
+	for i, n := range free {
+		addrs[i] = n.String()
+	}
+	return strings.Join(addrs, ", "), nil
+}
+
 // Show displays the peer form dialog
 func (f *PeerForm) Show(parent fyne.Window) {
+	if f.allowedIPsEntry.Text == "" && len(f.subnets) > 0 {
+		if addrs, err := f.nextFreeAddress(); err == nil {
+			f.allowedIPsEntry.SetText(addrs)
+		}
+	}
+
+	nextFreeBtn := widget.NewButtonWithIcon("Next Free", theme.ContentAddIcon(), func() {
+		addrs, err := f.nextFreeAddress()
+		if err != nil {
+			helpers.ShowError(err, parent)
+			return
+		}
+		f.allowedIPsEntry.SetText(addrs)
+	})
+	if len(f.subnets) == 0 {
+		nextFreeBtn.Disable()
+	}
+
 	generateKeyBtn := widget.NewButtonWithIcon("Generate Keys", theme.ViewRefreshIcon(), func() {
 		priv, pub, err := wg.GenerateKeyPair()
 		if err != nil {
@@ -116,6 +182,16 @@ func (f *PeerForm) Show(parent fyne.Window) {
 		f.generatedPrivateKey = priv
 	})
 
+	expiryPresets := widget.NewSelect([]string{"Never", "1 day", "1 week", "30 days"}, func(s string) {
+		days := map[string]int{"1 day": 1, "1 week": 7, "30 days": 30}[s]
+		if days == 0 {
+			f.expiresEntry.SetText("")
+			return
+		}
+		f.expiresEntry.SetText(time.Now().AddDate(0, 0, days).Format(expiryLayout))
+	})
+	expiryPresets.PlaceHolder = "Preset"
+
 	copyPubKeyBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
 		text := f.publicKeyEntry.Text
 		if text != "" && text != "(invalid key)" {
@@ -134,13 +210,17 @@ func (f *PeerForm) Show(parent fyne.Window) {
 
 		widget.NewSeparator(),
 		widget.NewLabel("Allowed IPs * (client's VPN address)"),
-		f.allowedIPsEntry,
+		container.NewBorder(nil, nil, nil, nextFreeBtn, f.allowedIPsEntry),
 
 		widget.NewLabel("Persistent Keepalive (for client config)"),
 		f.persistentKeepaliveEntry,
 
 		widget.NewLabel("Preshared Key"),
 		f.presharedKeyEntry,
+
+		widget.NewSeparator(),
+		widget.NewLabel("Access Expires (peer is removed afterwards)"),
+		container.NewBorder(nil, nil, nil, expiryPresets, f.expiresEntry),
 	)
 
 	d := dialog.NewCustomConfirm("Peer Configuration", "Save", "Cancel", form, func(confirmed bool) {
@@ -173,7 +253,20 @@ func (f *PeerForm) validate() (config.PeerConfig, []error) {
 	if name == "" {
 		return peer, []error{wg.ValidationError{Field: "Peer.Name", Message: "required"}}
 	}
-	peer.Name = name
+	if strings.Contains(name, "|") {
+		return peer, []error{wg.ValidationError{Field: "Peer.Name", Message: "cannot contain '|'"}}
+	}
+	meta := f.meta
+	meta.Name = name
+	meta.Expires = time.Time{}
+	if text := strings.TrimSpace(f.expiresEntry.Text); text != "" {
+		expires, err := time.ParseInLocation(expiryLayout, text, time.Local)
+		if err != nil {
+			return peer, []error{wg.ValidationError{Field: "Peer.Expires", Message: "use " + expiryLayout}}
+		}
+		meta.Expires = expires
+	}
+	peer.Name = meta.String()
 
 	if f.publicKeyEntry.Text == "" {
 		return peer, []error{wg.ValidationError{Field: "Peer.PublicKey", Message: "required"}}
@@ -208,6 +301,9 @@ func (f *PeerForm) validate() (config.PeerConfig, []error) {
 		}
 		peer.AllowedIPs = append(peer.AllowedIPs, *ipNet)
 	}
+	if err := ipam.CheckOverlap(peer, f.others, f.index); err != nil {
+		return peer, []error{wg.ValidationError{Field: "Peer.AllowedIPs", Message: err.Error()}}
+	}
 
 	if f.persistentKeepaliveEntry.Text != "" {
 		keepalive, err := strconv.Atoi(f.persistentKeepaliveEntry.Text)
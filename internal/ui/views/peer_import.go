@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"wgAdmin/internal/peercsv"
+	"wgAdmin/internal/ui/helpers"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showCSVImport asks for a CSV file of peers and shows the dry run
+func (f *TunnelForm) showCSVImport(win fyne.Window) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			helpers.ShowError(err, win)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		plan, err := f.planCSVImport(reader)
+		if err != nil {
+			helpers.ShowError(fmt.Errorf("CSV import: %w", err), win)
+			return
+		}
+		f.showImportPlan(plan, win)
+	}, win)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".csv"}))
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
+}
+
+// planCSVImport parses r against the current peers and address pool
+// without changing the form
+func (f *TunnelForm) planCSVImport(r io.Reader) (*peercsv.Plan, error) {
+	return peercsv.Parse(r, f.addressPool(), f.peers)
+}
+
+// showImportPlan lists every row of plan with its address or error and adds
+// the valid rows once confirmed
+func (f *TunnelForm) showImportPlan(plan *peercsv.Plan, win fyne.Window) {
+	valid := plan.Valid()
+
+	rows := container.NewVBox()
+	for _, r := range plan.Rows {
+		var text string
+		if r.Err != nil {
+			text = fmt.Sprintf("Line %d  %s: %v", r.Line, r.Peer.Name, r.Err)
+		} else {
+			ips := make([]string, len(r.Peer.AllowedIPs))
+			for i, ip := range r.Peer.AllowedIPs {
+				ips[i] = ip.String()
+			}
+			text = fmt.Sprintf("Line %d  %s  %s", r.Line, r.Peer.Name, strings.Join(ips, ", "))
+			if r.PrivateKey != "" {
+				text += "  (new keys)"
+			}
+		}
+		label := widget.NewLabel(text)
+		if r.Err != nil {
+			label.Importance = widget.DangerImportance
+		}
+		rows.Add(label)
+	}
+
+	summary := widget.NewLabel(fmt.Sprintf("%d peer(s) will be added, %d row(s) have errors and will be skipped.",
+		len(valid), plan.Invalid()))
+	summary.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(summary, nil, nil, nil, container.NewVScroll(rows))
+
+	if len(valid) == 0 {
+		d := dialog.NewCustom("CSV Import - Dry Run", "Close", content, win)
+		d.Resize(fyne.NewSize(650, 450))
+		d.Show()
+		return
+	}
+
+	d := dialog.NewCustomConfirm("CSV Import - Dry Run", fmt.Sprintf("Add %d Peers", len(valid)), "Cancel", content,
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			f.addImportedPeers(valid)
+			helpers.ShowInformation("CSV Import",
+				fmt.Sprintf("Added %d peer(s). Save the tunnel to write them and their client configs.", len(valid)), win)
+		}, win)
+	d.Resize(fyne.NewSize(650, 450))
+	d.Show()
+}
+
+// addImportedPeers appends rows to the peer list; generated private keys are
+// kept for generateClientConfigs
+func (f *TunnelForm) addImportedPeers(rows []peercsv.Row) {
+	for _, r := range rows {
+		f.peers = append(f.peers, r.Peer)
+		if r.PrivateKey != "" {
+			f.peerPrivateKeys[r.Peer.PublicKey.String()] = r.PrivateKey
+		}
+	}
+	if f.peersList != nil {
+		f.peersList.Refresh()
+	}
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"wgAdmin/internal/netscan"
+	"wgAdmin/internal/scanwatch"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ScanHistoryView lists the scheduled scan runs of a tunnel and what
+// changed in each
+type ScanHistoryView struct {
+	ifaceName string
+	history   *scanwatch.History
+
+	win    fyne.Window
+	runs   []scanwatch.Run
+	list   *widget.List
+	detail *widget.Label
+}
+
+// NewScanHistoryView creates a viewer for the scan runs of a tunnel
+func NewScanHistoryView(ifaceName string, history *scanwatch.History) *ScanHistoryView {
+	return &ScanHistoryView{ifaceName: ifaceName, history: history}
+}
+
+// Show opens the history window
+func (hv *ScanHistoryView) Show() {
+	hv.win = fyne.CurrentApp().NewWindow("Scan History: " + hv.ifaceName)
+	hv.win.Resize(fyne.NewSize(900, 550))
+
+	hv.detail = widget.NewLabel("")
+	hv.detail.TextStyle = fyne.TextStyle{Monospace: true}
+	hv.detail.Wrapping = fyne.TextWrapWord
+
+	hv.list = widget.NewList(
+		func() int { return len(hv.runs) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			run := hv.runs[id]
+			label.Importance = widget.MediumImportance
+			if !run.Changes.Empty() {
+				label.Importance = widget.WarningImportance
+			}
+			label.SetText(fmt.Sprintf("%s  %d hosts  %s",
+				run.Time.Format("2006-01-02 15:04"), len(run.Results), run.Changes))
+		},
+	)
+	hv.list.OnSelected = func(id widget.ListItemID) {
+		hv.detail.SetText(formatScanRun(hv.runs[id]))
+	}
+
+	reloadBtn := widget.NewButtonWithIcon("Reload", theme.ViewRefreshIcon(), hv.reload)
+	header := container.NewBorder(nil, nil,
+		widget.NewLabel("Scheduled scans, newest first"), reloadBtn)
+
+	split := container.NewHSplit(hv.list, container.NewVScroll(hv.detail))
+	split.Offset = 0.45
+	hv.win.SetContent(container.NewPadded(container.NewBorder(header, nil, nil, nil, split)))
+	hv.reload()
+	hv.win.Show()
+}
+
+// reload reads the runs again and selects the newest
+func (hv *ScanHistoryView) reload() {
+	hv.runs = hv.history.Runs(hv.ifaceName)
+	hv.list.UnselectAll()
+	hv.list.Refresh()
+	if len(hv.runs) == 0 {
+		hv.detail.SetText("No scheduled scans of " + hv.ifaceName + " yet.\nAdd it under Settings > Network Scanner > Scheduled Scans.")
+		return
+	}
+	hv.list.Select(0)
+}
+
+// formatScanRun describes the changes of a run followed by its hosts
+func formatScanRun(run scanwatch.Run) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on ports %s\n\n", run.CIDR, netscan.FormatPorts(run.Ports))
+
+	c := run.Changes
+	if c.Empty() {
+		b.WriteString("No changes since the previous run\n")
+	}
+	for _, ip := range c.NewHosts {
+		fmt.Fprintf(&b, "+ %s  new host\n", ip)
+	}
+	for _, ip := range c.GoneHosts {
+		fmt.Fprintf(&b, "- %s  vanished\n", ip)
+	}
+	for _, p := range c.OpenedPorts {
+		fmt.Fprintf(&b, "+ %s:%d  opened\n", p.IP, p.Port)
+	}
+	for _, p := range c.ClosedPorts {
+		fmt.Fprintf(&b, "- %s:%d  closed\n", p.IP, p.Port)
+	}
+
+	fmt.Fprintf(&b, "\n%d hosts:\n", len(run.Results))
+	for _, r := range run.Results {
+		fmt.Fprintf(&b, "  %-16s %s\n", r.IP, scanCell(r, 2))
+	}
+	return b.String()
+}
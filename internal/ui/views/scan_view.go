@@ -1,171 +1,468 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"strconv"
+	"path/filepath"
 	"strings"
-	"sync/atomic"
 	"time"
+
+	"wgAdmin/internal/netscan"
+	"wgAdmin/internal/scanwatch"
+	"wgAdmin/internal/settings"
 	"wgAdmin/internal/ui/helpers"
+	"wgAdmin/internal/wgwidget"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+)
+
+// scanColumns are the result table columns, in order; by is empty for
+// columns that cannot be sorted
+var scanColumns = []struct {
+	title string
+	by    string
+	width float32
+}{
+	{"IP", netscan.ByIP, 140},
+	{"Hostname", netscan.ByHostname, 220},
+	{"Open ports", netscan.ByPorts, 200},
+	{"Latency", netscan.ByLatency, 90},
+	{"Peer", "", 200},
+	{"Handshake", "", 110},
+}
 
-	scanner "github.com/MrVasquez96/go-ipscan"
+// Result table columns filled from the tunnel rather than the scan
+const (
+	scanColPeer      = 4
+	scanColHandshake = 5
 )
 
 // ScanView displays network scan results
 type ScanView struct {
 	ifaceName   string
 	cidr        string
+	profiles    []netscan.Profile
 	workers     int
 	timeoutSecs int
+	loadTunnel  func() netscan.Tunnel // nil when results are not matched to peers
+	history     *scanwatch.History    // scheduled scan runs; nil hides the History button
+
+	// Window state, only touched on the UI goroutine
+	win           fyne.Window
+	cidrEntry     *widget.Entry
+	portsEntry    *widget.Entry
+	profileSelect *widget.Select
+	startBtn      *widget.Button
+	pauseBtn      *widget.Button
+	stopBtn       *widget.Button
+	exportBtn     *widget.Button
+	statusLabel   *widget.Label
+	progress      *widget.ProgressBar
+	table         *widget.Table
+	results       []netscan.Result
+	tunnel        netscan.Tunnel // peers the results are matched against
+	sortBy        string
+	sortDesc      bool
+	scanner       *netscan.Scanner
+	cancel        context.CancelFunc // nil when no scan is running
+	closed        bool
 }
 
-// NewScanView creates a new scan view
-func NewScanView(ifaceName, ip string, workers, timeoutSecs int) *ScanView {
+// NewScanView creates a new scan view for the network cidr of an interface.
+// loadTunnel returns the interface's addresses and peers, read again on
+// every scan start and end to match results to peers; it may be nil.
+func NewScanView(ifaceName, cidr string, cfg *settings.AppSettings, loadTunnel func() netscan.Tunnel) *ScanView {
 	return &ScanView{
 		ifaceName:   ifaceName,
-		cidr:        scanner.DeriveCIDR24(ip),
-		workers:     workers,
-		timeoutSecs: timeoutSecs,
+		cidr:        cidr,
+		profiles:    cfg.PortProfiles(),
+		workers:     cfg.ScanWorkers,
+		timeoutSecs: cfg.ScanTimeoutSecs,
+		loadTunnel:  loadTunnel,
+		sortBy:      netscan.ByIP,
 	}
 }
 
 func (v *ScanView) Show() {
-	log.Printf("[DEBUG] Show() called with CIDR: %s, Interface: %s", v.cidr, v.ifaceName)
-
-	if v.cidr == "" {
-		log.Println("[WARN] Scan abort: CIDR is empty")
-		return
+	prefs := fyne.CurrentApp().Preferences()
+	choice := settings.LoadScanChoice(prefs, v.ifaceName)
+	if choice.CIDR == "" {
+		choice.CIDR = v.cidr
 	}
 
-	win := fyne.CurrentApp().NewWindow("Scan: " + v.ifaceName)
-	win.Resize(fyne.NewSize(850, 600))
+	v.win = fyne.CurrentApp().NewWindow("Scan: " + v.ifaceName)
+	v.win.Resize(fyne.NewSize(1000, 600))
 
-	progress := widget.NewProgressBar()
-	progressNote := widget.NewLabel("0%")
-	resultsContainer := container.NewVBox()
-	resultsScroll := container.NewVScroll(resultsContainer)
+	v.cidrEntry = widget.NewEntry()
+	v.cidrEntry.SetText(choice.CIDR)
+	v.cidrEntry.SetPlaceHolder("10.8.0.0/24")
 
-	cidrLabel := widget.NewLabel(v.cidr)
-	cidrLabel.TextStyle = fyne.TextStyle{Bold: true, Monospace: true}
+	v.portsEntry = widget.NewEntry()
+	v.portsEntry.SetPlaceHolder("22, 80, 8000-8010")
+
+	names := make([]string, 0, len(v.profiles)+1)
+	for _, p := range v.profiles {
+		names = append(names, p.Name)
+	}
+	names = append(names, netscan.CustomProfile)
+	v.profileSelect = widget.NewSelect(names, func(name string) {
+		if name == netscan.CustomProfile {
+			v.portsEntry.Enable()
+			return
+		}
+		for _, p := range v.profiles {
+			if p.Name == name {
+				v.portsEntry.SetText(netscan.FormatPorts(p.Ports))
+			}
+		}
+		v.portsEntry.Disable()
+	})
+	selected := names[0]
+	for _, name := range names {
+		if name == choice.Profile {
+			selected = name
+		}
+	}
+	v.profileSelect.SetSelected(selected)
+	if selected == netscan.CustomProfile {
+		v.portsEntry.SetText(choice.Ports)
+	}
+
+	v.startBtn = widget.NewButtonWithIcon("Start", theme.MediaPlayIcon(), v.start)
+	v.startBtn.Importance = widget.HighImportance
+	v.pauseBtn = widget.NewButtonWithIcon("Pause", theme.MediaPauseIcon(), v.togglePause)
+	v.stopBtn = widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), v.stop)
+	v.exportBtn = widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), v.export)
+	v.statusLabel = widget.NewLabel("Ready")
+	v.progress = widget.NewProgressBar()
+	v.table = v.newResultsTable()
+	v.setRunning(false)
+
+	setup := widget.NewForm(
+		widget.NewFormItem("Network", v.cidrEntry),
+		widget.NewFormItem("Ports", container.NewBorder(nil, nil, v.profileSelect, nil, v.portsEntry)),
+	)
+	controls := container.NewHBox(v.startBtn, v.pauseBtn, v.stopBtn, v.exportBtn)
+	if v.history != nil {
+		controls.Add(widget.NewButtonWithIcon("History", theme.HistoryIcon(), func() {
+			NewScanHistoryView(v.ifaceName, v.history).Show()
+		}))
+	}
 
 	header := container.NewVBox(
-		container.NewHBox(
-			widget.NewLabel("Scanning Network:"),
-			cidrLabel,
-		),
-		container.NewPadded(container.NewBorder(nil, nil, widget.NewLabel("Progress:"), progressNote, progress)),
+		setup,
+		container.NewBorder(nil, nil, controls, v.statusLabel, v.progress),
 		widget.NewSeparator(),
 	)
 
-	win.SetContent(container.NewPadded(container.NewBorder(header, nil, nil, nil, resultsScroll)))
-	win.Show()
+	// Closing the window ends the scan so nothing updates a dead window
+	v.win.SetOnClosed(func() {
+		v.closed = true
+		if v.cancel != nil {
+			v.cancel()
+		}
+	})
+
+	v.win.SetContent(container.NewPadded(container.NewBorder(header, nil, nil, nil, v.table)))
+	v.win.Show()
+}
+
+// newResultsTable creates the results table; tapping a column header sorts
+// by that column, tapping it again reverses the order
+func (v *ScanView) newResultsTable() *widget.Table {
+	table := widget.NewTable(
+		func() (int, int) { return len(v.results), len(scanColumns) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			label.Importance = widget.MediumImportance
+			if id.Row >= len(v.results) {
+				label.SetText("")
+				return
+			}
+			r := v.results[id.Row]
+			label.TextStyle = fyne.TextStyle{Monospace: id.Col < scanColPeer && id.Col != 1}
+			if id.Col < scanColPeer {
+				label.SetText(scanCell(r, id.Col))
+				return
+			}
+			who := v.tunnel.Identify(r.IP)
+			if who.Unknown {
+				label.Importance = widget.WarningImportance
+			}
+			label.SetText(peerCell(who, id.Col))
+		},
+	)
+	table.ShowHeaderRow = true
+	table.CreateHeader = func() fyne.CanvasObject {
+		btn := widget.NewButton("", nil)
+		btn.Importance = widget.LowImportance
+		btn.Alignment = widget.ButtonAlignLeading
+		return btn
+	}
+	table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		btn := obj.(*widget.Button)
+		if id.Col < 0 || id.Col >= len(scanColumns) {
+			btn.SetText("")
+			btn.OnTapped = nil
+			return
+		}
+		col := scanColumns[id.Col]
+		btn.SetText(col.title)
+		btn.SetIcon(nil)
+		if col.by == "" {
+			btn.OnTapped = nil
+			return
+		}
+		if col.by == v.sortBy {
+			btn.SetIcon(theme.MenuDropDownIcon())
+			if v.sortDesc {
+				btn.SetIcon(theme.MenuDropUpIcon())
+			}
+		}
+		btn.OnTapped = func() {
+			v.sortDesc = col.by == v.sortBy && !v.sortDesc
+			v.sortBy = col.by
+			netscan.Sort(v.results, v.sortBy, v.sortDesc)
+			v.table.Refresh()
+		}
+	}
+	for i, col := range scanColumns {
+		table.SetColumnWidth(i, col.width)
+	}
+	return table
+}
+
+// scanCell formats one column of a result
+func scanCell(r netscan.Result, col int) string {
+	switch col {
+	case 0:
+		return r.IP
+	case 1:
+		return r.Hostname()
+	case 2:
+		if len(r.PortsOpen) == 0 {
+			return "none open"
+		}
+		return strings.ReplaceAll(netscan.FormatPorts(r.PortsOpen), ",", ", ")
+	default:
+		return fmt.Sprintf("%.1f ms", float64(r.Latency.Microseconds())/1000)
+	}
+}
+
+// peerCell formats the peer columns of a result
+func peerCell(who netscan.Identity, col int) string {
+	if col == scanColHandshake {
+		if who.Peer == nil || who.Local {
+			return ""
+		}
+		return wgwidget.FormatAge(who.Peer.LastHandshake)
+	}
+	switch {
+	case who.Local:
+		return "(this interface)"
+	case who.Peer != nil && who.Peer.Name == "":
+		return "(unnamed)"
+	case who.Peer != nil:
+		return who.Peer.Name
+	case who.Unknown:
+		return "⚠ not a known peer"
+	}
+	return ""
+}
+
+// refreshTunnel reloads the peers the results are matched against
+func (v *ScanView) refreshTunnel() {
+	if v.loadTunnel != nil {
+		v.tunnel = v.loadTunnel()
+	}
+}
 
-	timeout := time.Duration(v.timeoutSecs) * time.Second
-	log.Println("[DEBUG] Initializing scanner...", v.cidr)
-	s, err := scanner.NewScanner(v.cidr, v.workers, timeout)
+// start validates the setup, remembers it for the tunnel and starts a scan
+func (v *ScanView) start() {
+	cidr := strings.TrimSpace(v.cidrEntry.Text)
+	ports, err := netscan.ParsePorts(v.portsEntry.Text)
 	if err != nil {
-		log.Printf("[ERROR] Scanner initialization failed: %v", err)
-		helpers.ShowError(err, win)
+		helpers.ShowError(err, v.win)
 		return
 	}
+	s, err := netscan.New(cidr, ports, v.workers, time.Duration(v.timeoutSecs)*time.Second)
+	if err != nil {
+		helpers.ShowError(err, v.win)
+		return
+	}
+
+	saved := settings.ScanChoice{CIDR: cidr, Profile: v.profileSelect.Selected}
+	if saved.Profile == netscan.CustomProfile {
+		saved.Ports = netscan.FormatPorts(ports)
+	}
+	settings.SaveScanChoice(fyne.CurrentApp().Preferences(), v.ifaceName, saved)
 
-	// Start non-blocking scan
-	s.Run()
+	ctx, cancel := context.WithCancel(context.Background())
+	v.scanner, v.cancel = s, cancel
+	v.results = nil
+	v.refreshTunnel()
+	v.table.Refresh()
+	v.progress.SetValue(0)
+	v.setRunning(true)
+	v.statusLabel.SetText("Scanning...")
+	v.run(ctx, s)
+}
+
+// togglePause pauses or resumes the running scan
+func (v *ScanView) togglePause() {
+	if v.scanner == nil {
+		return
+	}
+	if v.scanner.Paused() {
+		v.scanner.Resume()
+		v.pauseBtn.SetText("Pause")
+		v.pauseBtn.SetIcon(theme.MediaPauseIcon())
+		v.statusLabel.SetText("Scanning...")
+		return
+	}
+	v.scanner.Pause()
+	v.pauseBtn.SetText("Resume")
+	v.pauseBtn.SetIcon(theme.MediaPlayIcon())
+	v.statusLabel.SetText("Paused")
+}
+
+// stop cancels the running scan; the results so far are kept
+func (v *ScanView) stop() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+}
+
+// setRunning enables the controls that apply while a scan runs or not
+func (v *ScanView) setRunning(running bool) {
+	v.pauseBtn.SetText("Pause")
+	v.pauseBtn.SetIcon(theme.MediaPauseIcon())
+	if running {
+		v.startBtn.Disable()
+		v.pauseBtn.Enable()
+		v.stopBtn.Enable()
+		v.exportBtn.Disable()
+		return
+	}
+	v.startBtn.Enable()
+	v.pauseBtn.Disable()
+	v.stopBtn.Disable()
+	if len(v.results) > 0 {
+		v.exportBtn.Enable()
+	} else {
+		v.exportBtn.Disable()
+	}
+}
+
+// run shows the results and progress of s until it ends or ctx is canceled
+func (v *ScanView) run(ctx context.Context, s *netscan.Scanner) {
+	s.Run(ctx)
+
+	setProgress := func() {
+		scanned, total := s.Progress()
+		if total == 0 {
+			return
+		}
+		fyne.Do(func() {
+			if !v.closed {
+				v.progress.SetValue(float64(scanned) / float64(total))
+			}
+		})
+	}
 
 	// Result listener: update UI as results arrive
+	finished := make(chan struct{})
 	go func() {
-		log.Println("[DEBUG] Listener goroutine started: Waiting for results...")
+		defer close(finished)
 		for res := range s.Results {
 			resCopy := res
-			scanned, total := s.Progress()
-			pct := float64(scanned) / float64(total)
-
+			setProgress()
 			fyne.Do(func() {
-				bar := pct
-				progress.SetValue(bar)
-				progressNote.SetText(fmt.Sprintf("%d%%", int(bar*100)))
-				resultsContainer.Add(makeScanRow(resCopy))
-				resultsContainer.Refresh()
+				if v.closed {
+					return
+				}
+				v.results = append(v.results, resCopy)
+				netscan.Sort(v.results, v.sortBy, v.sortDesc)
+				v.table.Refresh()
 			})
 		}
-
-		log.Println("[DEBUG] Results channel closed. Scan complete.")
 		fyne.Do(func() {
-			progress.SetValue(1)
-			progressNote.SetText("100%")
+			v.cancel()
+			v.scanner, v.cancel = nil, nil
+			if v.closed {
+				return
+			}
+			status := fmt.Sprintf("Done: %d hosts", len(v.results))
+			if ctx.Err() != nil {
+				status = fmt.Sprintf("Stopped: %d hosts", len(v.results))
+			} else {
+				v.progress.SetValue(1)
+			}
+			unknown := 0
+			for _, r := range v.results {
+				if v.tunnel.Identify(r.IP).Unknown {
+					unknown++
+				}
+			}
+			if unknown > 0 {
+				status += fmt.Sprintf(", %d not known peers", unknown)
+			}
+			v.statusLabel.SetText(status)
+			v.refreshTunnel()
+			v.table.Refresh()
+			v.setRunning(false)
 		})
 	}()
 
 	// Progress poller: update bar even when no new results arrive
 	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
 		for {
-			scanned, total := s.Progress()
-			if total == 0 {
-				break
+			select {
+			case <-finished:
+				return
+			case <-ticker.C:
+				setProgress()
 			}
-			pct := float64(scanned) / float64(total)
-			fyne.Do(func() {
-				progress.SetValue(pct)
-				progressNote.SetText(fmt.Sprintf("%d%%", int(pct*100)))
-			})
-			if scanned >= total {
-				break
-			}
-			time.Sleep(500 * time.Millisecond)
 		}
 	}()
 }
 
-func makeScanRow(r scanner.ScanResult) fyne.CanvasObject {
-	host := ""
-	if len(r.Hostnames) > 0 {
-		host = r.Hostnames[0]
-	}
-
-	portStr := "No open ports"
-	if len(r.PortsOpen) > 0 {
-		var ports []string
-		for _, p := range r.PortsOpen {
-			ports = append(ports, strconv.Itoa(p))
+// export writes the results as JSON when the file name ends in .json,
+// otherwise as CSV
+func (v *ScanView) export() {
+	results := append([]netscan.Result(nil), v.results...)
+	tunnel := v.tunnel
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			helpers.ShowError(err, v.win)
+			return
 		}
-		portStr = "Ports: " + strings.Join(ports, ", ")
-	}
-
-	ipLabel := widget.NewLabel(r.IP)
-	ipLabel.TextStyle = fyne.TextStyle{Monospace: true}
-
-	portLabel := widget.NewLabel(portStr)
-	portLabel.TextStyle = fyne.TextStyle{Monospace: true}
-
-	return container.NewPadded(container.NewGridWithColumns(3,
-		ipLabel,
-		widget.NewLabel(host),
-		portLabel,
-	))
-}
-
-// ScanViewWithProgress is a version with external progress tracking
-type ScanViewWithProgress struct {
-	*ScanView
-	scannedCount *uint32
-	totalIPs     uint32
-}
-
-// NewScanViewWithProgress creates a scan view with progress tracking
-func NewScanViewWithProgress(ifaceName, ip string, workers, timeoutSecs int) *ScanViewWithProgress {
-	var count uint32
-	return &ScanViewWithProgress{
-		ScanView:     NewScanView(ifaceName, ip, workers, timeoutSecs),
-		scannedCount: &count,
-	}
-}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
 
-// Progress returns the current scan progress
-func (v *ScanViewWithProgress) Progress() (scanned, total uint32) {
-	return atomic.LoadUint32(v.scannedCount), v.totalIPs
+		if strings.EqualFold(filepath.Ext(writer.URI().Name()), ".json") {
+			err = netscan.WriteJSON(writer, results, tunnel)
+		} else {
+			err = netscan.WriteCSV(writer, results, tunnel)
+		}
+		if err != nil {
+			helpers.ShowError(fmt.Errorf("export failed: %w", err), v.win)
+		}
+	}, v.win)
+	d.SetFileName("scan-" + v.ifaceName + "-" + time.Now().Format("20060102-1504") + ".csv")
+	d.Resize(fyne.NewSize(800, 600))
+	d.Show()
 }
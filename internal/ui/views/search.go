@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+
+	"wgAdmin/internal/search"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxSearchResults caps the peer matches listed above the tunnel cards
+const maxSearchResults = 25
+
+// searchTunnels returns the state of every listed tunnel for a search
+func (v *MainView) searchTunnels() []search.Tunnel {
+	tunnels := make([]search.Tunnel, len(v.interfaces))
+	for i, iface := range v.interfaces {
+		tunnels[i] = search.Tunnel{
+			Name:   iface.Name,
+			Config: v.configs[iface.Name],
+			Stats:  v.peerStats[iface.Name],
+		}
+	}
+	return tunnels
+}
+
+// searchResults lists the peers that matched a search, each with a button
+// that opens the peers form at that peer. Tunnel matches are left to the
+// cards below.
+func (v *MainView) searchResults(matches []search.Match) fyne.CanvasObject {
+	rows := container.NewVBox()
+	count := 0
+	for _, m := range matches {
+		if m.Peer < 0 {
+			continue
+		}
+		count++
+		if count > maxSearchResults {
+			continue
+		}
+
+		name := m.PeerName
+		if name == "" {
+			name = "(unnamed)"
+		}
+		label := widget.NewLabel(fmt.Sprintf("%s › %s — %s %s", m.Tunnel, name, m.Field, m.Value))
+		label.Truncation = fyne.TextTruncateEllipsis
+
+		match := m
+		openBtn := widget.NewButtonWithIcon("Open", theme.NavigateNextIcon(), func() {
+			v.showPeersFormAt(match.Tunnel, match.PublicKey)
+		})
+		rows.Add(container.NewBorder(nil, nil, nil, openBtn, label))
+	}
+
+	title := fmt.Sprintf("%d matching peers", count)
+	switch {
+	case count == 0:
+		title = "No matching peers"
+	case count == 1:
+		title = "1 matching peer"
+	case count > maxSearchResults:
+		rows.Add(widget.NewLabel(fmt.Sprintf("and %d more, refine the search", count-maxSearchResults)))
+	}
+	header := widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	return container.NewVBox(header, rows, widget.NewSeparator())
+}
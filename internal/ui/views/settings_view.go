@@ -1,10 +1,15 @@
 package ui
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
+	"wgAdmin/internal/netscan"
+	"wgAdmin/internal/scanwatch"
 	"wgAdmin/internal/settings"
 	"wgAdmin/internal/ui/helpers"
 
@@ -53,11 +58,21 @@ func (sv *SettingsView) Show() {
 	clientDirEntry.SetText(sv.current.ClientConfigDir)
 	clientDirEntry.SetPlaceHolder("clients")
 
+	trafficFileEntry := widget.NewEntry()
+	trafficFileEntry.SetText(sv.current.TrafficHistoryFile)
+	trafficFileEntry.SetPlaceHolder("optional, e.g. /var/lib/wgAdmin/traffic.json")
+
+	auditFileEntry := widget.NewEntry()
+	auditFileEntry.SetText(sv.current.AuditLogFile)
+	auditFileEntry.SetPlaceHolder(settings.DefaultAuditLogFile)
+
 	pathsForm := widget.NewForm(
 		widget.NewFormItem("WireGuard Config Path", wgPathEntry),
 		widget.NewFormItem("Client Config Directory", clientDirEntry),
+		widget.NewFormItem("Traffic History File", trafficFileEntry),
+		widget.NewFormItem("Audit Log File", auditFileEntry),
 	)
-	pathsCard := widget.NewCard("Paths", "Directories for configuration files", pathsForm)
+	pathsCard := widget.NewCard("Paths", "Locations of configuration files, history and the audit log", pathsForm)
 
 	// --- Window section ---
 	widthEntry := widget.NewEntry()
@@ -89,6 +104,15 @@ func (sv *SettingsView) Show() {
 	confirmDeleteCheck := widget.NewCheck("Confirm before deleting tunnels", nil)
 	confirmDeleteCheck.Checked = sv.current.ConfirmBeforeDelete
 
+	minimizeToTrayCheck := widget.NewCheck("Minimize to system tray when closing the window", nil)
+	minimizeToTrayCheck.Checked = sv.current.MinimizeToTray
+
+	staleMinutesEntry := widget.NewEntry()
+	staleMinutesEntry.SetText(strconv.Itoa(sv.current.PeerStaleMinutes))
+
+	expiryMinutesEntry := widget.NewEntry()
+	expiryMinutesEntry.SetText(strconv.Itoa(sv.current.ExpiryCheckMinutes))
+
 	themeSelect := widget.NewSelect([]string{"system", "light", "dark"}, nil)
 	themeSelect.SetSelected(sv.current.ThemeVariant)
 
@@ -97,9 +121,44 @@ func (sv *SettingsView) Show() {
 		widget.NewFormItem("", autoRefreshCheck),
 		widget.NewFormItem("Refresh Interval (s)", refreshSecsEntry),
 		widget.NewFormItem("", confirmDeleteCheck),
+		widget.NewFormItem("", minimizeToTrayCheck),
+		widget.NewFormItem("Peer Stale After (min)", staleMinutesEntry),
+		widget.NewFormItem("Remove Expired Peers Every (min, 0 = off)", expiryMinutesEntry),
 	)
 	behaviorCard := widget.NewCard("Behavior", "", behaviorForm)
 
+	// --- Notifications section ---
+	notifyDownCheck := widget.NewCheck("Tunnel went down unexpectedly", nil)
+	notifyDownCheck.Checked = sv.current.NotifyInterfaceDown
+
+	notifyConnectCheck := widget.NewCheck("Peer connected", nil)
+	notifyConnectCheck.Checked = sv.current.NotifyPeerConnected
+
+	notifyDropCheck := widget.NewCheck("Peer disconnected (stale)", nil)
+	notifyDropCheck.Checked = sv.current.NotifyPeerDisconnected
+
+	notifyToggleCheck := widget.NewCheck("Activating or deactivating failed", nil)
+	notifyToggleCheck.Checked = sv.current.NotifyToggleFailed
+
+	notifyScanCheck := widget.NewCheck("Scheduled scan found changes", nil)
+	notifyScanCheck.Checked = sv.current.NotifyScanChanges
+
+	notifyCard := widget.NewCard("Notifications", "Desktop notifications, checked on every refresh",
+		container.NewVBox(notifyDownCheck, notifyConnectCheck, notifyDropCheck, notifyToggleCheck, notifyScanCheck))
+
+	// --- Backups section ---
+	keepLastEntry := widget.NewEntry()
+	keepLastEntry.SetText(strconv.Itoa(sv.current.BackupKeepLast))
+
+	keepDaysEntry := widget.NewEntry()
+	keepDaysEntry.SetText(strconv.Itoa(sv.current.BackupKeepDays))
+
+	backupForm := widget.NewForm(
+		widget.NewFormItem("Keep Last Versions", keepLastEntry),
+		widget.NewFormItem("Keep Daily For (days)", keepDaysEntry),
+	)
+	backupCard := widget.NewCard("Backups", "A version is saved on every write; older versions are pruned", backupForm)
+
 	// --- Scanner section ---
 	workersEntry := widget.NewEntry()
 	workersEntry.SetText(strconv.Itoa(sv.current.ScanWorkers))
@@ -107,12 +166,88 @@ func (sv *SettingsView) Show() {
 	scanTimeoutEntry := widget.NewEntry()
 	scanTimeoutEntry.SetText(strconv.Itoa(sv.current.ScanTimeoutSecs))
 
+	scanProfilesEntry := widget.NewMultiLineEntry()
+	scanProfilesEntry.SetText(strings.ReplaceAll(sv.current.ScanProfiles, "; ", "\n"))
+	scanProfilesEntry.SetPlaceHolder("Name=22,80,8000-8010, one per line")
+	scanProfilesEntry.SetMinRowsVisible(4)
+
+	scheduledScansEntry := widget.NewMultiLineEntry()
+	scheduledScansEntry.SetText(strings.ReplaceAll(sv.current.ScheduledScans, "; ", "\n"))
+	scheduledScansEntry.SetPlaceHolder("tunnel=minutes, one per line")
+	scheduledScansEntry.SetMinRowsVisible(2)
+
+	scanHistoryEntry := widget.NewEntry()
+	scanHistoryEntry.SetText(sv.current.ScanHistoryFile)
+	scanHistoryEntry.SetPlaceHolder("empty keeps history in memory only")
+
 	scanForm := widget.NewForm(
 		widget.NewFormItem("Concurrent Workers", workersEntry),
 		widget.NewFormItem("Timeout (seconds)", scanTimeoutEntry),
+		widget.NewFormItem("Port Profiles", scanProfilesEntry),
+		widget.NewFormItem("Scheduled Scans", scheduledScansEntry),
+		widget.NewFormItem("History File", scanHistoryEntry),
 	)
 	scanCard := widget.NewCard("Network Scanner", "", scanForm)
 
+	// --- Reachability section ---
+	monitorSecsEntry := widget.NewEntry()
+	monitorSecsEntry.SetText(strconv.Itoa(sv.current.MonitorSecs))
+
+	monitorPortEntry := widget.NewEntry()
+	monitorPortEntry.SetText(strconv.Itoa(sv.current.MonitorTCPPort))
+
+	monitorForm := widget.NewForm(
+		widget.NewFormItem("Probe Every (s, 0 = off)", monitorSecsEntry),
+		widget.NewFormItem("TCP Port", monitorPortEntry),
+	)
+	monitorCard := widget.NewCard("Reachability Monitor",
+		"Pings each peer's tunnel address; connects to the TCP port when ICMP is not permitted", monitorForm)
+
+	// --- API section ---
+	apiEnabledCheck := widget.NewCheck("Enable local API server", nil)
+	apiEnabledCheck.Checked = sv.current.APIEnabled
+
+	apiSocketEntry := widget.NewEntry()
+	apiSocketEntry.SetText(sv.current.APISocket)
+	apiSocketEntry.SetPlaceHolder(settings.DefaultAPISocket)
+
+	apiAddressEntry := widget.NewEntry()
+	apiAddressEntry.SetText(sv.current.APIAddress)
+	apiAddressEntry.SetPlaceHolder("e.g. 127.0.0.1:8484 (empty for socket only)")
+
+	apiTokenEntry := widget.NewPasswordEntry()
+	apiTokenEntry.SetText(sv.current.APIToken)
+	generateTokenBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			helpers.ShowError(err, win)
+			return
+		}
+		apiTokenEntry.SetText(hex.EncodeToString(buf))
+	})
+
+	apiForm := widget.NewForm(
+		widget.NewFormItem("", apiEnabledCheck),
+		widget.NewFormItem("Unix Socket", apiSocketEntry),
+		widget.NewFormItem("TCP Address", apiAddressEntry),
+		widget.NewFormItem("Token", container.NewBorder(nil, nil, nil, generateTokenBtn, apiTokenEntry)),
+	)
+	apiCard := widget.NewCard("API", "REST API for automation; TCP requires a token", apiForm)
+
+	// --- Metrics section ---
+	metricsEnabledCheck := widget.NewCheck("Enable Prometheus /metrics endpoint", nil)
+	metricsEnabledCheck.Checked = sv.current.MetricsEnabled
+
+	metricsAddressEntry := widget.NewEntry()
+	metricsAddressEntry.SetText(sv.current.MetricsAddress)
+	metricsAddressEntry.SetPlaceHolder(settings.DefaultMetricsAddress)
+
+	metricsForm := widget.NewForm(
+		widget.NewFormItem("", metricsEnabledCheck),
+		widget.NewFormItem("Listen Address", metricsAddressEntry),
+	)
+	metricsCard := widget.NewCard("Metrics", "Interface and peer metrics for Prometheus", metricsForm)
+
 	// --- Privilege escalation section ---
 	privOptions := []string{"none", "pkexec", "sudo"}
 	if !settings.PkexecAvailable() {
@@ -261,10 +396,16 @@ func (sv *SettingsView) Show() {
 	// --- Buttons ---
 	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
 		updated, err := sv.validate(
-			wgPathEntry, clientDirEntry,
+			wgPathEntry, clientDirEntry, trafficFileEntry, auditFileEntry,
 			widthEntry, heightEntry, fullscreenCheck,
-			autoRefreshCheck, refreshSecsEntry, confirmDeleteCheck, themeSelect,
-			workersEntry, scanTimeoutEntry,
+			autoRefreshCheck, refreshSecsEntry, confirmDeleteCheck, minimizeToTrayCheck, themeSelect,
+			staleMinutesEntry, expiryMinutesEntry,
+			notifyDownCheck, notifyConnectCheck, notifyDropCheck, notifyToggleCheck, notifyScanCheck,
+			keepLastEntry, keepDaysEntry,
+			workersEntry, scanTimeoutEntry, scanProfilesEntry, scheduledScansEntry, scanHistoryEntry,
+			monitorSecsEntry, monitorPortEntry,
+			apiEnabledCheck, apiSocketEntry, apiAddressEntry, apiTokenEntry,
+			metricsEnabledCheck, metricsAddressEntry,
 			privSelect,
 			fontSizeSelect, useCustomFontCheck,
 			// Light mode colors
@@ -299,15 +440,38 @@ func (sv *SettingsView) Show() {
 			}
 			wgPathEntry.SetText(settings.DefaultWGConfigPath)
 			clientDirEntry.SetText(settings.DefaultClientConfigDir)
+			trafficFileEntry.SetText(settings.DefaultTrafficHistoryFile)
+			auditFileEntry.SetText(settings.DefaultAuditLogFile)
 			widthEntry.SetText(strconv.Itoa(settings.DefaultWindowWidth))
 			heightEntry.SetText(strconv.Itoa(settings.DefaultWindowHeight))
 			fullscreenCheck.SetChecked(settings.DefaultStartFullscreen)
 			autoRefreshCheck.SetChecked(settings.DefaultAutoRefreshEnabled)
 			refreshSecsEntry.SetText(strconv.Itoa(settings.DefaultAutoRefreshSecs))
 			confirmDeleteCheck.SetChecked(settings.DefaultConfirmBeforeDelete)
+			minimizeToTrayCheck.SetChecked(settings.DefaultMinimizeToTray)
+			notifyDownCheck.SetChecked(settings.DefaultNotifyIfaceDown)
+			notifyConnectCheck.SetChecked(settings.DefaultNotifyPeerConnect)
+			notifyDropCheck.SetChecked(settings.DefaultNotifyPeerDrop)
+			notifyToggleCheck.SetChecked(settings.DefaultNotifyToggleFailed)
+			notifyScanCheck.SetChecked(settings.DefaultNotifyScanChanges)
+			staleMinutesEntry.SetText(strconv.Itoa(settings.DefaultPeerStaleMinutes))
+			expiryMinutesEntry.SetText(strconv.Itoa(settings.DefaultExpiryCheckMinutes))
+			keepLastEntry.SetText(strconv.Itoa(settings.DefaultBackupKeepLast))
+			keepDaysEntry.SetText(strconv.Itoa(settings.DefaultBackupKeepDays))
 			themeSelect.SetSelected(settings.DefaultThemeVariant)
 			workersEntry.SetText(strconv.Itoa(settings.DefaultScanWorkers))
 			scanTimeoutEntry.SetText(strconv.Itoa(settings.DefaultScanTimeoutSecs))
+			scanProfilesEntry.SetText(strings.ReplaceAll(settings.DefaultScanProfiles, "; ", "\n"))
+			scheduledScansEntry.SetText(settings.DefaultScheduledScans)
+			scanHistoryEntry.SetText(settings.DefaultScanHistoryFile)
+			monitorSecsEntry.SetText(strconv.Itoa(settings.DefaultMonitorSecs))
+			monitorPortEntry.SetText(strconv.Itoa(settings.DefaultMonitorTCPPort))
+			apiEnabledCheck.SetChecked(settings.DefaultAPIEnabled)
+			apiSocketEntry.SetText(settings.DefaultAPISocket)
+			apiAddressEntry.SetText(settings.DefaultAPIAddress)
+			apiTokenEntry.SetText(settings.DefaultAPIToken)
+			metricsEnabledCheck.SetChecked(settings.DefaultMetricsEnabled)
+			metricsAddressEntry.SetText(settings.DefaultMetricsAddress)
 			privSelect.SetSelected(settings.DefaultPrivilegeEscalation)
 			fontSizeSelect.SetSelected(settings.DefaultFontSize)
 			useCustomFontCheck.SetChecked(settings.DefaultUseCustomFont)
@@ -367,7 +531,12 @@ func (sv *SettingsView) Show() {
 			container.NewPadded(windowCard),
 			container.NewPadded(appearanceCard),
 			container.NewPadded(behaviorCard),
+			container.NewPadded(notifyCard),
+			container.NewPadded(backupCard),
 			container.NewPadded(scanCard),
+			container.NewPadded(monitorCard),
+			container.NewPadded(apiCard),
+			container.NewPadded(metricsCard),
 			container.NewPadded(privCard),
 		)),
 	)
@@ -377,10 +546,16 @@ func (sv *SettingsView) Show() {
 }
 
 func (sv *SettingsView) validate(
-	wgPathEntry, clientDirEntry *widget.Entry,
+	wgPathEntry, clientDirEntry, trafficFileEntry, auditFileEntry *widget.Entry,
 	widthEntry, heightEntry *widget.Entry, fullscreenCheck *widget.Check,
-	autoRefreshCheck *widget.Check, refreshSecsEntry *widget.Entry, confirmDeleteCheck *widget.Check, themeSelect *widget.Select,
-	workersEntry, scanTimeoutEntry *widget.Entry,
+	autoRefreshCheck *widget.Check, refreshSecsEntry *widget.Entry, confirmDeleteCheck, minimizeToTrayCheck *widget.Check, themeSelect *widget.Select,
+	staleMinutesEntry, expiryMinutesEntry *widget.Entry,
+	notifyDownCheck, notifyConnectCheck, notifyDropCheck, notifyToggleCheck, notifyScanCheck *widget.Check,
+	keepLastEntry, keepDaysEntry *widget.Entry,
+	workersEntry, scanTimeoutEntry, scanProfilesEntry, scheduledScansEntry, scanHistoryEntry *widget.Entry,
+	monitorSecsEntry, monitorPortEntry *widget.Entry,
+	apiEnabledCheck *widget.Check, apiSocketEntry, apiAddressEntry, apiTokenEntry *widget.Entry,
+	metricsEnabledCheck *widget.Check, metricsAddressEntry *widget.Entry,
 	privSelect *widget.Select,
 	fontSizeSelect *widget.Select, useCustomFontCheck *widget.Check,
 	// Light mode colors
@@ -402,6 +577,9 @@ func (sv *SettingsView) validate(
 	if clientDirEntry.Text == "" {
 		return nil, fmt.Errorf("client config directory cannot be empty")
 	}
+	if strings.TrimSpace(auditFileEntry.Text) == "" {
+		return nil, fmt.Errorf("audit log file cannot be empty")
+	}
 
 	width, err := strconv.Atoi(widthEntry.Text)
 	if err != nil || width < 400 {
@@ -417,6 +595,49 @@ func (sv *SettingsView) validate(
 		return nil, fmt.Errorf("refresh interval must be a number >= 1")
 	}
 
+	staleMinutes, err := strconv.Atoi(staleMinutesEntry.Text)
+	if err != nil || staleMinutes < 1 {
+		return nil, fmt.Errorf("peer stale time must be a number >= 1")
+	}
+
+	apiSocket := strings.TrimSpace(apiSocketEntry.Text)
+	apiAddress := strings.TrimSpace(apiAddressEntry.Text)
+	apiToken := strings.TrimSpace(apiTokenEntry.Text)
+	if apiEnabledCheck.Checked {
+		if apiAddress == "" && apiSocket == "" {
+			return nil, fmt.Errorf("API needs a socket path or a TCP address")
+		}
+		if apiAddress != "" {
+			if _, _, err := net.SplitHostPort(apiAddress); err != nil {
+				return nil, fmt.Errorf("API address must be host:port")
+			}
+			if apiToken == "" {
+				return nil, fmt.Errorf("API token is required when listening on TCP")
+			}
+		}
+	}
+
+	metricsAddress := strings.TrimSpace(metricsAddressEntry.Text)
+	if metricsEnabledCheck.Checked {
+		if _, _, err := net.SplitHostPort(metricsAddress); err != nil {
+			return nil, fmt.Errorf("metrics address must be host:port (e.g. 127.0.0.1:9586)")
+		}
+	}
+
+	expiryMinutes, err := strconv.Atoi(expiryMinutesEntry.Text)
+	if err != nil || expiryMinutes < 0 {
+		return nil, fmt.Errorf("expired peer check must be a number >= 0")
+	}
+
+	keepLast, err := strconv.Atoi(keepLastEntry.Text)
+	if err != nil || keepLast < 1 {
+		return nil, fmt.Errorf("versions to keep must be a number >= 1")
+	}
+	keepDays, err := strconv.Atoi(keepDaysEntry.Text)
+	if err != nil || keepDays < 0 {
+		return nil, fmt.Errorf("daily retention must be a number >= 0")
+	}
+
 	workers, err := strconv.Atoi(workersEntry.Text)
 	if err != nil || workers < 1 {
 		return nil, fmt.Errorf("scan workers must be a number >= 1")
@@ -427,6 +648,29 @@ func (sv *SettingsView) validate(
 		return nil, fmt.Errorf("scan timeout must be a number >= 1")
 	}
 
+	scanProfiles, err := netscan.ParseProfiles(scanProfilesEntry.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(scanProfiles) == 0 {
+		return nil, fmt.Errorf("at least one port profile is required")
+	}
+
+	scheduledScans, err := scanwatch.ParseSchedule(scheduledScansEntry.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	monitorSecs, err := strconv.Atoi(monitorSecsEntry.Text)
+	if err != nil || monitorSecs < 0 {
+		return nil, fmt.Errorf("reachability interval must be a number >= 0")
+	}
+
+	monitorPort, err := strconv.Atoi(monitorPortEntry.Text)
+	if err != nil || monitorPort < 1 || monitorPort > 65535 {
+		return nil, fmt.Errorf("reachability TCP port must be between 1 and 65535")
+	}
+
 	privMethod := privSelect.Selected
 	if privMethod == "pkexec (not installed)" {
 		privMethod = "pkexec"
@@ -496,20 +740,45 @@ func (sv *SettingsView) validate(
 	return &settings.AppSettings{
 		WGConfigPath:        wgPathEntry.Text,
 		ClientConfigDir:     clientDirEntry.Text,
+		TrafficHistoryFile:  strings.TrimSpace(trafficFileEntry.Text),
+		AuditLogFile:        strings.TrimSpace(auditFileEntry.Text),
 		WindowWidth:         width,
 		WindowHeight:        height,
 		StartFullscreen:     fullscreenCheck.Checked,
 		AutoRefreshEnabled:  autoRefreshCheck.Checked,
 		AutoRefreshSecs:     refreshSecs,
 		ConfirmBeforeDelete: confirmDeleteCheck.Checked,
+		MinimizeToTray:      minimizeToTrayCheck.Checked,
+		PeerStaleMinutes:    staleMinutes,
+		ExpiryCheckMinutes:  expiryMinutes,
+		BackupKeepLast:      keepLast,
+		BackupKeepDays:      keepDays,
+		APIEnabled:          apiEnabledCheck.Checked,
+		APISocket:           apiSocket,
+		APIAddress:          apiAddress,
+		APIToken:            apiToken,
+		MetricsEnabled:      metricsEnabledCheck.Checked,
+		MetricsAddress:      metricsAddress,
 		ThemeVariant:        themeVariant,
 		ScanWorkers:         workers,
 		ScanTimeoutSecs:     scanTimeout,
+		ScanProfiles:        netscan.FormatProfiles(scanProfiles),
+		ScheduledScans:      scanwatch.FormatSchedule(scheduledScans),
+		ScanHistoryFile:     strings.TrimSpace(scanHistoryEntry.Text),
+		MonitorSecs:         monitorSecs,
+		MonitorTCPPort:      monitorPort,
 		PrivilegeEscalation: privMethod,
 		FontSize:            fontSize,
 		AccentColor:         lightAccentEntry.Text, // Keep for backward compatibility
 		UseCustomFont:       useCustomFontCheck.Checked,
 
+		// Desktop notifications
+		NotifyInterfaceDown:    notifyDownCheck.Checked,
+		NotifyPeerConnected:    notifyConnectCheck.Checked,
+		NotifyPeerDisconnected: notifyDropCheck.Checked,
+		NotifyToggleFailed:     notifyToggleCheck.Checked,
+		NotifyScanChanges:      notifyScanCheck.Checked,
+
 		// Light mode colors
 		LightAccentColor:         lightAccentEntry.Text,
 		LightBackgroundColor:     lightBackgroundEntry.Text,
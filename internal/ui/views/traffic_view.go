@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/traffic"
+	"wgAdmin/internal/wgwidget"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// trafficRanges are the selectable chart windows, in display order
+var trafficRanges = []struct {
+	label  string
+	window time.Duration
+}{
+	{"5 min", 5 * time.Minute},
+	{"1 hour", time.Hour},
+	{"24 hours", 24 * time.Hour},
+}
+
+// TrafficView shows throughput charts for an interface and its peers
+type TrafficView struct {
+	ifaceName string
+	history   *traffic.Recorder
+	peers     []controller.PeerStats
+}
+
+// NewTrafficView creates a new traffic view
+func NewTrafficView(ifaceName string, history *traffic.Recorder, peers []controller.PeerStats) *TrafficView {
+	return &TrafficView{
+		ifaceName: ifaceName,
+		history:   history,
+		peers:     peers,
+	}
+}
+
+// Show opens the traffic window; charts are redrawn every few seconds until it is closed
+func (v *TrafficView) Show() {
+	win := fyne.CurrentApp().NewWindow("Traffic: " + v.ifaceName)
+	win.Resize(fyne.NewSize(700, 600))
+
+	keys := []string{v.ifaceName}
+	charts := []*wgwidget.RateChart{wgwidget.NewRateChart()}
+	chartList := container.NewVBox(newChartTitle("Interface "+v.ifaceName), charts[0])
+
+	for _, p := range v.peers {
+		name := p.Name
+		if name == "" {
+			name = p.PublicKey
+		}
+		chart := wgwidget.NewRateChart()
+		keys = append(keys, traffic.PeerKey(v.ifaceName, p.PublicKey))
+		charts = append(charts, chart)
+		chartList.Add(newChartTitle("Peer " + name))
+		chartList.Add(chart)
+	}
+
+	window := trafficRanges[0].window
+	update := func() {
+		for i, chart := range charts {
+			chart.SetRates(v.history.Rates(keys[i], window), window)
+		}
+	}
+
+	labels := make([]string, len(trafficRanges))
+	for i, r := range trafficRanges {
+		labels[i] = r.label
+	}
+	rangeSelect := widget.NewSelect(labels, func(selected string) {
+		for _, r := range trafficRanges {
+			if r.label == selected {
+				window = r.window
+			}
+		}
+		update()
+	})
+	rangeSelect.SetSelected(labels[0])
+
+	note := widget.NewLabel("Samples are taken on every refresh of the main window.")
+	note.TextStyle = fyne.TextStyle{Italic: true}
+
+	header := container.NewVBox(
+		container.NewHBox(widget.NewLabel("Range:"), rangeSelect),
+		note,
+		widget.NewSeparator(),
+	)
+	win.SetContent(container.NewPadded(container.NewBorder(header, nil, nil, nil, container.NewVScroll(chartList))))
+
+	stop := make(chan struct{})
+	win.SetOnClosed(func() {
+		close(stop)
+	})
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fyne.Do(update)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	win.Show()
+}
+
+func newChartTitle(text string) *widget.Label {
+	label := widget.NewLabel(text)
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	return label
+}
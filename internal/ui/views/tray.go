@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// setupTray adds the system tray menu when the driver supports one and lets
+// the window hide to it on close when MinimizeToTray is set
+func (v *MainView) setupTray() {
+	desk, ok := fyne.CurrentApp().(desktop.App)
+	if !ok {
+		return
+	}
+	v.tray = desk
+	v.updateTray()
+
+	v.window.SetCloseIntercept(func() {
+		if v.appSettings().MinimizeToTray {
+			v.window.Hide()
+			return
+		}
+		v.window.Close()
+	})
+}
+
+// updateTray rebuilds the tray menu from the last refreshed interfaces
+func (v *MainView) updateTray() {
+	if v.tray == nil {
+		return
+	}
+	v.tray.SetSystemTrayMenu(v.trayMenu())
+}
+
+// trayMenu lists every tunnel with a checkmark for active ones; selecting a
+// tunnel toggles it
+func (v *MainView) trayMenu() *fyne.Menu {
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Open wgAdmin", func() {
+			v.window.Show()
+			v.window.RequestFocus()
+		}),
+		fyne.NewMenuItemSeparator(),
+	}
+
+	if len(v.interfaces) == 0 {
+		none := fyne.NewMenuItem("No tunnels", nil)
+		none.Disabled = true
+		items = append(items, none)
+	}
+	for _, iface := range v.interfaces {
+		name, active := iface.Name, iface.Active
+		item := fyne.NewMenuItem(name, func() {
+			v.toggleInterface(name, !active)
+		})
+		item.Checked = active
+		items = append(items, item)
+	}
+
+	return fyne.NewMenu("wgAdmin", items...)
+}
@@ -7,8 +7,14 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/ipam"
+	"wgAdmin/internal/peermeta"
+	"wgAdmin/internal/reachability"
 	"wgAdmin/internal/ui/helpers"
+	"wgAdmin/internal/wgwidget"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -25,7 +31,7 @@ import (
 // TunnelForm handles tunnel creation and editing
 type TunnelForm struct {
 	window          fyne.Window
-	ctrl            *wg.WG
+	ctrl            controller.Controller
 	isEdit          bool
 	isPeer          bool
 	name            string
@@ -47,6 +53,7 @@ type TunnelForm struct {
 	peers           []config.PeerConfig
 	peersList       *widget.List
 	peerPrivateKeys map[string]string
+	health          func(publicKey string) reachability.Stats // nil hides reachability
 
 	// Callbacks
 	onSave   func(name string, config *config.Config) error
@@ -54,7 +61,7 @@ type TunnelForm struct {
 }
 
 // NewTunnelForm creates a new tunnel form
-func NewTunnelForm(parent fyne.Window, ctrl *wg.WG, existingName string, existingConfig *config.Config, onSave func(string, *config.Config) error, onCancel func(), clientConfigDir string) *TunnelForm {
+func NewTunnelForm(parent fyne.Window, ctrl controller.Controller, existingName string, existingConfig *config.Config, onSave func(string, *config.Config) error, onCancel func(), clientConfigDir string) *TunnelForm {
 	tunnelName := existingName
 	isEdit := existingName != ""
 	if isEdit {
@@ -206,20 +213,7 @@ func (f *TunnelForm) Show() {
 	)
 
 	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
-		cfg, errs := f.validate()
-		if len(errs) > 0 {
-			helpers.ShowError(errs[0], win)
-			return
-		}
-
-		name := f.getTunnelName()
-		if err := f.onSave(name, cfg); err != nil {
-			fmt.Println("error saving", err)
-			helpers.ShowError(err, win)
-			return
-		}
-		f.generateClientConfigs(name, cfg, win)
-		win.Close()
+		f.save(win)
 	})
 	saveBtn.Importance = widget.HighImportance
 
@@ -245,6 +239,29 @@ func (f *TunnelForm) Show() {
 	win.Show()
 }
 
+// togglePeer disables an enabled peer or enables a disabled one. A disabled
+// peer stays in the config but is not loaded into the device.
+func (f *TunnelForm) togglePeer(id int) {
+	meta := peermeta.Parse(f.peers[id].Name)
+	meta.Disabled = !meta.Disabled
+	f.peers[id].Name = meta.String()
+	f.peersList.Refresh()
+}
+
+// selectPeer scrolls the peers list to the peer with publicKey and selects it
+func (f *TunnelForm) selectPeer(publicKey string) {
+	if publicKey == "" || f.peersList == nil {
+		return
+	}
+	for i, p := range f.peers {
+		if p.PublicKey.String() == publicKey {
+			f.peersList.ScrollTo(i)
+			f.peersList.Select(i)
+			return
+		}
+	}
+}
+
 // ShowPeers displays the peers editing form
 func (f *TunnelForm) ShowPeers() {
 	title := "Peers / Clients"
@@ -253,14 +270,16 @@ func (f *TunnelForm) ShowPeers() {
 	}
 
 	win := fyne.CurrentApp().NewWindow(title)
-	win.Resize(fyne.NewSize(700, 750))
+	win.Resize(fyne.NewSize(900, 750))
 
 	f.peersList = widget.NewList(
 		func() int { return len(f.peers) },
 		func() fyne.CanvasObject {
 			return container.NewHBox(
 				widget.NewLabel("Peer"),
+				wgwidget.NewPeerHealth(reachability.DefaultKeep),
 				layout.NewSpacer(),
+				widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
 				widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil),
 				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
 			)
@@ -268,8 +287,10 @@ func (f *TunnelForm) ShowPeers() {
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
 			c := obj.(*fyne.Container)
 			label := c.Objects[0].(*widget.Label)
-			editBtn := c.Objects[2].(*widget.Button)
-			deleteBtn := c.Objects[3].(*widget.Button)
+			health := c.Objects[1].(*wgwidget.PeerHealth)
+			toggleBtn := c.Objects[3].(*widget.Button)
+			editBtn := c.Objects[4].(*widget.Button)
+			deleteBtn := c.Objects[5].(*widget.Button)
 
 			peer := f.peers[id]
 			pubKeyStr := peer.PublicKey.String()
@@ -277,11 +298,34 @@ func (f *TunnelForm) ShowPeers() {
 			if len(displayKey) > 12 {
 				displayKey = displayKey[:12]
 			}
-			peerName := peer.Name
+			meta := peermeta.Parse(peer.Name)
+			peerName := meta.Name
 			if peerName == "" {
 				peerName = "(unnamed)"
 			}
-			label.SetText(fmt.Sprintf("%s... - %s", displayKey, peerName))
+			text := fmt.Sprintf("%s... - %s", displayKey, peerName)
+			if remaining := meta.Remaining(time.Now()); remaining != "" {
+				text += " (" + remaining + ")"
+			}
+			if meta.Disabled {
+				text += " (disabled)"
+				toggleBtn.SetIcon(theme.MediaPlayIcon())
+			} else {
+				toggleBtn.SetIcon(theme.MediaPauseIcon())
+			}
+			label.SetText(text)
+
+			health.Hide()
+			if f.health != nil && !meta.Disabled {
+				if stats := f.health(pubKeyStr); len(stats.Samples) > 0 {
+					health.SetStats(stats)
+					health.Show()
+				}
+			}
+
+			toggleBtn.OnTapped = func() {
+				f.togglePeer(id)
+			}
 
 			editBtn.OnTapped = func() {
 				peerCopy := f.peers[id]
@@ -294,6 +338,7 @@ func (f *TunnelForm) ShowPeers() {
 					}
 					f.peersList.Refresh()
 				}, nil)
+				peerForm.SetAddressPool(f.addressPool(), f.peers, id)
 				peerForm.Show(win)
 			}
 
@@ -318,36 +363,37 @@ func (f *TunnelForm) ShowPeers() {
 			}
 			f.peersList.Refresh()
 		}, nil)
+		peerForm.SetAddressPool(f.addressPool(), f.peers, -1)
 		peerForm.Show(win)
 	})
 
+	importCSVBtn := widget.NewButtonWithIcon("Import CSV", theme.FileTextIcon(), func() {
+		f.showCSVImport(win)
+	})
+
+	qrBtn := widget.NewButtonWithIcon("Client QR Codes", theme.MediaPhotoIcon(), func() {
+		paths, err := ClientConfigPaths(f.clientConfigDir, f.getTunnelName())
+		if err != nil {
+			helpers.ShowError(err, win)
+			return
+		}
+		NewClientQRView(f.getTunnelName(), paths).Show()
+	})
+
 	sizedList := container.NewGridWrap(
-		fyne.NewSize(560, float32(f.peersList.Length()*50)),
+		fyne.NewSize(760, float32(f.peersList.Length()*50)),
 		f.peersList,
 	)
 
 	peersSection := container.NewBorder(
 		widget.NewLabel("Peers"),
-		addPeerBtn,
+		container.NewHBox(addPeerBtn, importCSVBtn, qrBtn),
 		nil, nil,
 		sizedList,
 	)
 
 	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
-		cfg, errs := f.validate()
-		if len(errs) > 0 {
-			helpers.ShowError(errs[0], win)
-			return
-		}
-
-		name := f.getTunnelName()
-		if err := f.onSave(name, cfg); err != nil {
-			fmt.Println("error saving", err)
-			helpers.ShowError(err, win)
-			return
-		}
-		f.generateClientConfigs(name, cfg, win)
-		win.Close()
+		f.save(win)
 	})
 	saveBtn.Importance = widget.HighImportance
 
@@ -373,6 +419,44 @@ func (f *TunnelForm) ShowPeers() {
 	win.Show()
 }
 
+// addressPool returns the tunnel subnets entered in the Address field
+func (f *TunnelForm) addressPool() []net.IPNet {
+	addrs, err := ipam.ParseCIDRList(f.addressEntry.Text)
+	if err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// save validates the form and previews the change before writing it
+func (f *TunnelForm) save(win fyne.Window) {
+	cfg, errs := f.validate()
+	if len(errs) > 0 {
+		helpers.ShowError(errs[0], win)
+		return
+	}
+
+	name := f.getTunnelName()
+	var current *config.Config
+	if f.ctrl.ConfigExists(name) {
+		current, _ = controller.ReadConfig(f.ctrl.GetConfigPath(name))
+	}
+	ShowConfigDiff(win, "Save "+name, "Save", current, cfg, func() {
+		f.write(name, cfg, win)
+	})
+}
+
+// write hands the reviewed config to onSave and closes win on success
+func (f *TunnelForm) write(name string, cfg *config.Config, win fyne.Window) {
+	if err := f.onSave(name, cfg); err != nil {
+		fmt.Println("error saving", err)
+		helpers.ShowError(err, win)
+		return
+	}
+	f.generateClientConfigs(name, cfg, win)
+	win.Close()
+}
+
 func (f *TunnelForm) generateClientConfigs(tunnelName string, serverCfg *config.Config, win fyne.Window) {
 	if len(f.peerPrivateKeys) == 0 {
 		return
@@ -409,6 +493,7 @@ func (f *TunnelForm) generateClientConfigs(tunnelName string, serverCfg *config.
 		if !ok {
 			continue
 		}
+		peerName := peermeta.Name(peer.Name)
 
 		allowedIPs := make([]string, len(peer.AllowedIPs))
 		for i, ip := range peer.AllowedIPs {
@@ -416,30 +501,29 @@ func (f *TunnelForm) generateClientConfigs(tunnelName string, serverCfg *config.
 		}
 
 		peerOpts := wg.PeerOpts{
-			Name:                peer.Name,
+			Name:                peerName,
 			PublicKey:           pubKeyStr,
 			AllowedIPs:          allowedIPs,
 			Endpoint:            fmt.Sprintf("%s:%d", host, port),
 			PersistentKeepalive: peer.PersistentKeepalive,
 		}
 
-		clientPath := filepath.Join(clientDir, peer.Name+".conf")
+		clientPath := filepath.Join(clientDir, peerName+".conf")
 		_, err := os.Stat(clientPath)
 		if os.IsExist(err) {
 			os.Rename(clientPath, clientPath+".bkp")
 		}
 		_, err = clientCtrl.NewClientConfig(*serverCfg, peerOpts, privKey, true)
 		if err != nil {
-			helpers.ShowError(fmt.Errorf("client config for '%s': %w", peer.Name, err), win)
+			helpers.ShowError(fmt.Errorf("client config for '%s': %w", peerName, err), win)
 			continue
 		}
 		generated = append(generated, clientPath)
 	}
 
+	// The form window closes after saving, so the result gets its own window
 	if len(generated) > 0 {
-		absDir, _ := filepath.Abs(clientDir)
-		msg := fmt.Sprintf("Generated %d client config(s) in:\n%s", len(generated), absDir)
-		helpers.ShowInformation("Client Configs", msg, win)
+		NewClientQRView(tunnelName, generated).Show()
 	}
 }
 
@@ -469,27 +553,11 @@ func (f *TunnelForm) validate() (*config.Config, []error) {
 	if f.addressEntry.Text == "" {
 		return nil, []error{wg.ValidationError{Field: "Address", Message: "required"}}
 	}
-	for _, addr := range strings.Split(f.addressEntry.Text, ",") {
-		addr = strings.TrimSpace(addr)
-		if addr == "" {
-			continue
-		}
-		if !strings.Contains(addr, "/") {
-			if strings.Contains(addr, ":") {
-				addr += "/128"
-			} else {
-				addr += "/32"
-			}
-		}
-
-		_, ipNet, err := net.ParseCIDR(addr)
-		if err != nil {
-			return nil, []error{wg.ValidationError{Field: "Address", Message: "invalid CIDR format"}}
-		}
-		fmt.Println("addr:", addr)
-		fmt.Println("IpNet:", ipNet)
-		cfg.Interface.Address = append(cfg.Interface.Address, *ipNet)
+	addrs, err := ipam.ParseCIDRList(f.addressEntry.Text)
+	if err != nil {
+		return nil, []error{wg.ValidationError{Field: "Address", Message: "invalid CIDR format"}}
 	}
+	cfg.Interface.Address = addrs
 
 	if f.dnsEntry.Text != "" {
 		for _, dns := range strings.Split(f.dnsEntry.Text, ",") {
@@ -548,6 +616,10 @@ func (f *TunnelForm) validate() (*config.Config, []error) {
 		}
 	}
 
+	if err := ipam.CheckPeers(cfg.Peers); err != nil {
+		return nil, []error{wg.ValidationError{Field: "Peer.AllowedIPs", Message: err.Error()}}
+	}
+
 	errs := wg.ValidateConfig(cfg)
 	return cfg, errs
 }
@@ -0,0 +1,844 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"wgAdmin/internal/audit"
+	"wgAdmin/internal/bundle"
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/history"
+	"wgAdmin/internal/reachability"
+	"wgAdmin/internal/scanwatch"
+	"wgAdmin/internal/settings"
+	"wgAdmin/internal/wgwidget"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/MrVasquez96/go-wg/wg/config"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func newTestMainView(t *testing.T) (*MainView, *controller.Fake) {
+	t.Helper()
+	test.NewTempApp(t)
+
+	dir := t.TempDir()
+	fake, err := controller.NewFake(dir)
+	if err != nil {
+		t.Fatalf("NewFake: %v", err)
+	}
+
+	cfg := &settings.AppSettings{
+		WGConfigPath:        dir,
+		ClientConfigDir:     t.TempDir(),
+		AuditLogFile:        filepath.Join(dir, "audit.jsonl"),
+		AutoRefreshSecs:     settings.DefaultAutoRefreshSecs,
+		ConfirmBeforeDelete: false,
+		ThemeVariant:        settings.DefaultThemeVariant,
+	}
+
+	w := test.NewTempWindow(t, nil)
+	ctrl := controller.NewVersioned(fake, history.NewStore(history.DefaultDir(dir), history.Policy{KeepLast: 10}))
+	v := NewMainView(w, ctrl, cfg)
+	w.SetContent(v.Build(fyne.AppMetadata{Name: "wgAdmin"}))
+	return v, fake
+}
+
+func writeTestTunnel(t *testing.T, ctrl controller.Controller, name, addr string) {
+	t.Helper()
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, ipNet, err := net.ParseCIDR(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipNet.IP = ip
+	cfg := config.Config{
+		Interface: config.InterfaceConfig{
+			PrivateKey: key,
+			Address:    []net.IPNet{*ipNet},
+			MTU:        1420,
+		},
+	}
+	if err := ctrl.WriteConfig(name, cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+}
+
+// waitFor polls cond until it holds, since the views do their work in goroutines
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// findButton walks obj for a button with the given label
+func findButton(obj fyne.CanvasObject, label string) *widget.Button {
+	switch o := obj.(type) {
+	case *widget.Button:
+		if o.Text == label {
+			return o
+		}
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			if b := findButton(child, label); b != nil {
+				return b
+			}
+		}
+	case *container.Scroll:
+		return findButton(o.Content, label)
+	case *widget.Card:
+		return findButton(o.Content, label)
+	case *widget.PopUp:
+		return findButton(o.Content, label)
+	}
+	return nil
+}
+
+// confirmDiff taps the confirm button of the diff preview shown over win
+func confirmDiff(t *testing.T, win fyne.Window, label string) {
+	t.Helper()
+	top := win.Canvas().Overlays().Top()
+	if top == nil {
+		t.Fatal("no diff preview shown")
+	}
+	btn := findButton(top, label)
+	if btn == nil {
+		t.Fatalf("%q button not found in diff preview", label)
+	}
+	test.Tap(btn)
+}
+
+// lastWindow returns the most recently opened window
+func lastWindow(t *testing.T) fyne.Window {
+	t.Helper()
+	windows := fyne.CurrentApp().Driver().AllWindows()
+	if len(windows) == 0 {
+		t.Fatal("no windows open")
+	}
+	return windows[len(windows)-1]
+}
+
+func TestMainViewAddTunnel(t *testing.T) {
+	v, fake := newTestMainView(t)
+
+	key, _ := wgtypes.GeneratePrivateKey()
+	form := v.showAddTunnelForm()
+	form.nameEntry.SetText("wg0")
+	form.privateKeyEntry.SetText(key.String())
+	form.addressEntry.SetText("10.8.0.1/24")
+
+	win := lastWindow(t)
+	save := findButton(win.Content(), "Save")
+	if save == nil {
+		t.Fatal("save button not found")
+	}
+	test.Tap(save)
+	confirmDiff(t, win, "Save")
+
+	if !fake.ConfigExists("wg0") {
+		t.Fatal("expected wg0.conf to be written")
+	}
+	waitFor(t, "wg0 to be listed", func() bool { return v.findInterface("wg0") != nil })
+
+	iface := v.findInterface("wg0")
+	if ip := net.ParseIP(iface.IP); ip == nil || !ip.Mask(net.CIDRMask(24, 32)).Equal(net.ParseIP("10.8.0.0")) {
+		t.Errorf("IP = %q, want an address in 10.8.0.0/24", iface.IP)
+	}
+	if iface.PublicKey != key.PublicKey().String() {
+		t.Errorf("PublicKey = %q, want %q", iface.PublicKey, key.PublicKey().String())
+	}
+}
+
+func TestMainViewEditTunnel(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+
+	cfg, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := v.openForm("wg0", cfg, true)
+	form.addressEntry.SetText("10.9.0.1/24")
+	win := lastWindow(t)
+	test.Tap(findButton(win.Content(), "Save"))
+	confirmDiff(t, win, "Save")
+
+	updated, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Interface.Address) != 1 || !updated.Interface.Address[0].Contains(net.ParseIP("10.9.0.1")) {
+		t.Errorf("Address = %v, want an address in 10.9.0.0/24", updated.Interface.Address)
+	}
+}
+
+func TestMainViewToggle(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+
+	v.toggleInterface("wg0", true)
+	waitFor(t, "wg0 to become active", func() bool { return fake.IsActive("wg0") })
+	waitFor(t, "card to show active state", func() bool {
+		iface := v.findInterface("wg0")
+		return iface != nil && iface.Active
+	})
+
+	v.toggleInterface("wg0", false)
+	waitFor(t, "wg0 to become inactive", func() bool { return !fake.IsActive("wg0") })
+}
+
+func TestMainViewDeleteAndRestore(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+
+	v.confirmDeleteTunnel("wg0")
+	waitFor(t, "wg0 to be deleted", func() bool { return !fake.ConfigExists("wg0") })
+
+	backups, err := fake.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Name != "wg0" {
+		t.Fatalf("backups = %+v, want one backup of wg0", backups)
+	}
+
+	versions, err := v.ctrl.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("versioned backups = %+v, want the deletion backup and a delete version", versions)
+	}
+
+	restored := false
+	bv := NewBackupView(v.window, v.ctrl, v.audit, func() { restored = true })
+	bv.Show()
+	if bv.timeline == nil || len(bv.timeline.Items) != 1 {
+		t.Fatal("expected a timeline with one tunnel")
+	}
+
+	bv.restore(backups[0])
+	if !restored {
+		t.Error("onRestore was not called")
+	}
+	if !fake.ConfigExists("wg0") {
+		t.Error("expected wg0.conf to be restored")
+	}
+
+	var deleted controller.Backup
+	for _, b := range versions {
+		if b.Reason == history.ReasonDelete {
+			deleted = b
+		}
+	}
+	bv.restoreAs(deleted, "wg0") // refused: wg0 exists again
+	bv.restoreAs(deleted, "wg1")
+	if !fake.ConfigExists("wg1") {
+		t.Error("expected wg1.conf to be restored from the delete version")
+	}
+
+	entries, err := audit.Read(v.audit.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action+" "+e.Tunnel+" "+e.Result)
+	}
+	want := []string{"delete wg0 ok", "restore wg0 ok", "restore wg0 error", "restore wg1 ok"}
+	if fmt.Sprint(actions) != fmt.Sprint(want) {
+		t.Errorf("audit actions = %v, want %v", actions, want)
+	}
+}
+
+func TestMainViewHotApplyPeers(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+	v.Refresh()
+	waitFor(t, "wg0 to be listed as active", func() bool {
+		iface := v.findInterface("wg0")
+		return iface != nil && iface.Active
+	})
+
+	cfg, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerKey, _ := wgtypes.GeneratePrivateKey()
+	_, allowed, _ := net.ParseCIDR("10.8.0.2/32")
+
+	form := v.openForm("wg0", cfg, false)
+	form.peers = append(form.peers, config.PeerConfig{
+		Name:       "laptop",
+		PublicKey:  peerKey.PublicKey(),
+		AllowedIPs: []net.IPNet{*allowed},
+	})
+	win := lastWindow(t)
+	test.Tap(findButton(win.Content(), "Save"))
+	confirmDiff(t, win, "Save")
+
+	dev, err := fake.Device("wg0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.Peers) != 1 || dev.Peers[0].PublicKey != peerKey.PublicKey() {
+		t.Fatalf("device peers = %+v, want the new peer", dev.Peers)
+	}
+	if diffs, err := controller.CheckDrift(fake, "wg0"); err != nil || len(diffs) != 0 {
+		t.Errorf("drift after hot apply = %v, %v; want none", diffs, err)
+	}
+}
+
+func TestBundleExportImport(t *testing.T) {
+	src, srcFake := newTestMainView(t)
+	writeTestTunnel(t, srcFake, "wg0", "10.8.0.1/24")
+	writeTestTunnel(t, srcFake, "wg1", "10.9.0.1/24")
+	clientDir := filepath.Join(src.settings.ClientConfigDir, "wg0")
+	if err := os.MkdirAll(clientDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(clientDir, "phone.conf"), []byte("[Interface]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bundle.Collect(src.ctrl, src.settings.ClientConfigDir, src.settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bundle.Encrypt(b, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Decrypt(data, "wrong horse"); err != bundle.ErrPassphrase {
+		t.Fatalf("Decrypt with wrong passphrase: err = %v, want ErrPassphrase", err)
+	}
+	opened, err := bundle.Decrypt(data, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened.Tunnels) != 2 || len(opened.Clients) != 1 {
+		t.Fatalf("bundle has %d tunnels and %d clients, want 2 and 1", len(opened.Tunnels), len(opened.Clients))
+	}
+
+	// The target already has a wg1, which must not be selected by default
+	dst, dstFake := newTestMainView(t)
+	writeTestTunnel(t, dstFake, "wg1", "10.10.0.1/24")
+	bv := NewBundleView(dst.window, dst.ctrl, dst.audit, dst.settings, nil)
+	bv.Show()
+	bv.load(opened)
+
+	if len(bv.items) != 2 {
+		t.Fatalf("planned %d items, want 2", len(bv.items))
+	}
+	for i, item := range bv.items {
+		if item.Err != nil {
+			t.Errorf("%s: unexpected error %v", item.Name, item.Err)
+		}
+		if item.Conflict != (item.Name == "wg1") {
+			t.Errorf("%s: conflict = %v", item.Name, item.Conflict)
+		}
+		if bv.checks[i].Checked == item.Conflict {
+			t.Errorf("%s: checked = %v, want only new tunnels selected", item.Name, bv.checks[i].Checked)
+		}
+	}
+
+	bv.restore(bv.selected(), true, false)
+	if !dstFake.ConfigExists("wg0") {
+		t.Error("expected wg0 to be restored")
+	}
+	if _, err := os.Stat(filepath.Join(dst.settings.ClientConfigDir, "wg0", "phone.conf")); err != nil {
+		t.Errorf("client config not restored: %v", err)
+	}
+	cfg, err := config.ParseConfig(dstFake.GetConfigPath("wg1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Interface.Address[0].String(); got != "10.10.0.1/24" {
+		t.Errorf("wg1 was overwritten: address %s", got)
+	}
+}
+
+func TestTrayMenu(t *testing.T) {
+	v, fake := newTestMainView(t)
+	if menu := v.trayMenu(); len(menu.Items) != 3 || !menu.Items[2].Disabled {
+		t.Fatalf("empty tray menu = %+v, want open, separator and a disabled placeholder", menu.Items)
+	}
+
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+	writeTestTunnel(t, fake, "wg1", "10.9.0.1/24")
+	if err := fake.ToggleInterface("wg1", true); err != nil {
+		t.Fatal(err)
+	}
+	v.Refresh()
+	waitFor(t, "interfaces to load", func() bool { return len(v.interfaces) == 2 })
+
+	menu := v.trayMenu()
+	checked := make(map[string]bool)
+	for _, item := range menu.Items[2:] {
+		checked[item.Label] = item.Checked
+	}
+	if len(checked) != 2 || checked["wg0"] || !checked["wg1"] {
+		t.Fatalf("tray items = %v, want wg0 unchecked and wg1 checked", checked)
+	}
+
+	fyne.DoAndWait(menu.Items[2].Action)
+	waitFor(t, "wg0 to be toggled from the tray", func() bool { return fake.IsActive("wg0") })
+}
+
+func TestPeersCSVImport(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+	cfg, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := v.openForm("wg0", cfg, false)
+	win := lastWindow(t)
+	plan, err := form.planCSVImport(strings.NewReader("name,public_key,allowed_ips,keepalive\nlaptop-1,,,25\nlaptop-2,,,\nbad,,300.1.1.1,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Valid()) != 2 || plan.Invalid() != 1 {
+		t.Fatalf("plan = %+v, want 2 valid rows and 1 error", plan.Rows)
+	}
+	if len(form.peers) != 0 {
+		t.Fatal("the dry run changed the peer list")
+	}
+
+	form.addImportedPeers(plan.Valid())
+	if len(form.peerPrivateKeys) != 2 {
+		t.Errorf("kept %d generated private keys, want 2", len(form.peerPrivateKeys))
+	}
+	test.Tap(findButton(win.Content(), "Save"))
+	confirmDiff(t, win, "Save")
+
+	saved, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Peers) != 2 || saved.Peers[0].Name != "laptop-1" || saved.Peers[1].AllowedIPs[0].String() != "10.8.0.3/32" {
+		t.Fatalf("saved peers = %+v", saved.Peers)
+	}
+}
+
+func TestPeerDisableToggle(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+	v.Refresh()
+	waitFor(t, "wg0 to be listed as active", func() bool {
+		iface := v.findInterface("wg0")
+		return iface != nil && iface.Active
+	})
+
+	peerKey, _ := wgtypes.GeneratePrivateKey()
+	_, allowed, _ := net.ParseCIDR("10.8.0.2/32")
+	save := func(toggle bool) {
+		t.Helper()
+		cfg, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		form := v.openForm("wg0", cfg, false)
+		if len(form.peers) == 0 {
+			form.peers = append(form.peers, config.PeerConfig{
+				Name:       "laptop",
+				PublicKey:  peerKey.PublicKey(),
+				AllowedIPs: []net.IPNet{*allowed},
+			})
+		}
+		if toggle {
+			form.togglePeer(0)
+		}
+		win := lastWindow(t)
+		test.Tap(findButton(win.Content(), "Save"))
+		confirmDiff(t, win, "Save")
+	}
+	devicePeers := func() int {
+		t.Helper()
+		dev, err := fake.Device("wg0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(dev.Peers)
+	}
+
+	save(false)
+	save(true)
+	if n := devicePeers(); n != 0 {
+		t.Errorf("device has %d peers after disabling, want 0", n)
+	}
+	loaded, err := config.ParseConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Peers) != 0 {
+		t.Errorf("config loads %d peers into the device, want 0", len(loaded.Peers))
+	}
+	edited, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited.Peers) != 1 || edited.Peers[0].PublicKey != peerKey.PublicKey() ||
+		edited.Peers[0].AllowedIPs[0].String() != "10.8.0.2/32" || edited.Peers[0].Name != "laptop | disabled" {
+		t.Fatalf("disabled peer read back as %+v", edited.Peers)
+	}
+
+	save(true)
+	if n := devicePeers(); n != 1 {
+		t.Errorf("device has %d peers after enabling, want 1", n)
+	}
+	if diffs, err := controller.CheckDrift(fake, "wg0"); err != nil || len(diffs) != 0 {
+		t.Errorf("drift after enabling = %v, %v; want none", diffs, err)
+	}
+}
+
+func TestSearchJumpToPeer(t *testing.T) {
+	v, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+	writeTestTunnel(t, fake, "wg1", "10.9.0.1/24")
+
+	cfg, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerKey, _ := wgtypes.GeneratePrivateKey()
+	_, allowed, _ := net.ParseCIDR("10.8.0.14/32")
+	cfg.Peers = append(cfg.Peers, config.PeerConfig{Name: "phone", PublicKey: peerKey.PublicKey(), AllowedIPs: []net.IPNet{*allowed}})
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	v.Refresh()
+	waitFor(t, "wg1 to be listed", func() bool { return v.findInterface("wg1") != nil })
+
+	v.filterEntry.SetText("10.8.0.14")
+	cards := 0
+	for _, obj := range v.listContainer.Objects {
+		if _, ok := obj.(*wgwidget.InterfaceCard); ok {
+			cards++
+		}
+	}
+	if cards != 1 {
+		t.Errorf("%d tunnels listed, want only wg0 which routes the address", cards)
+	}
+	openBtn := findButton(v.listContainer, "Open")
+	if openBtn == nil {
+		t.Fatal("no search result for the peer routing 10.8.0.14")
+	}
+	test.Tap(openBtn)
+	if title := lastWindow(t).Title(); title != "Edit Peer: wg0" {
+		t.Errorf("jump opened %q, want the peers form of wg0", title)
+	}
+}
+
+func TestScanViewRunStopAndClose(t *testing.T) {
+	test.NewTempApp(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	cfg := &settings.AppSettings{ScanWorkers: 4, ScanTimeoutSecs: 1, ScanProfiles: settings.DefaultScanProfiles}
+	v := NewScanView("wg0", "127.0.0.1/32", cfg, nil)
+	v.Show()
+	v.profileSelect.SetSelected("Custom")
+	v.portsEntry.SetText(fmt.Sprint(port))
+	test.Tap(v.startBtn)
+	waitFor(t, "the scan to finish", func() bool { return !v.startBtn.Disabled() })
+	if len(v.results) != 1 || scanCell(v.results[0], 2) != fmt.Sprint(port) {
+		t.Fatalf("results = %+v, want 127.0.0.1 with port %d", v.results, port)
+	}
+	if v.exportBtn.Disabled() {
+		t.Error("export is disabled although there are results")
+	}
+
+	// The last choice is remembered for the tunnel
+	if c := settings.LoadScanChoice(fyne.CurrentApp().Preferences(), "wg0"); c.Profile != "Custom" || c.Ports != fmt.Sprint(port) {
+		t.Errorf("saved choice = %+v", c)
+	}
+
+	// Closing the window ends a paused scan
+	v.cidrEntry.SetText("127.0.0.0/24")
+	test.Tap(v.startBtn)
+	test.Tap(v.pauseBtn)
+	if v.pauseBtn.Text != "Resume" {
+		t.Errorf("pause button reads %q while paused", v.pauseBtn.Text)
+	}
+	v.win.Close()
+	waitFor(t, "the scan to end after closing the window", func() bool { return v.cancel == nil })
+}
+
+func TestScheduledScanHistory(t *testing.T) {
+	v, fake := newTestMainView(t)
+	v.settings.ScanTimeoutSecs = 1
+	v.settings.ScanHistoryFile = filepath.Join(t.TempDir(), "scans.json")
+	writeTestTunnel(t, fake, "wg0", "127.0.0.1/32")
+	writeTestTunnel(t, fake, "wg1", "127.0.0.2/32")
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	settings.SaveScanChoice(fyne.CurrentApp().Preferences(), "wg0",
+		settings.ScanChoice{Profile: "Custom", Ports: fmt.Sprint(port)})
+
+	// The first run is the baseline; the second sees the port closed
+	ctx := context.Background()
+	v.runScheduledScan(ctx, v.ctrl, v.settings, "wg0")
+	ln.Close()
+	v.runScheduledScan(ctx, v.ctrl, v.settings, "wg0")
+	v.runScheduledScan(ctx, v.ctrl, v.settings, "wg1") // inactive, skipped
+
+	runs := v.scanHistory.Runs("wg0")
+	if len(runs) != 2 || runs[0].CIDR != "127.0.0.1/32" {
+		t.Fatalf("runs = %+v, want two scans of 127.0.0.1/32", runs)
+	}
+	want := []scanwatch.PortChange{{IP: "127.0.0.1", Port: port}}
+	if !reflect.DeepEqual(runs[0].Changes.ClosedPorts, want) {
+		t.Errorf("changes = %+v, want port %d closed", runs[0].Changes, port)
+	}
+	if len(v.scanHistory.Runs("wg1")) != 0 {
+		t.Error("inactive wg1 was scanned")
+	}
+
+	saved := scanwatch.NewHistory(scanHistoryKeep)
+	if err := saved.Load(v.settings.ScanHistoryFile); err != nil || len(saved.Runs("wg0")) != 2 {
+		t.Errorf("history file holds %d runs (%v), want 2", len(saved.Runs("wg0")), err)
+	}
+
+	hv := NewScanHistoryView("wg0", v.scanHistory)
+	hv.Show()
+	if line := fmt.Sprintf("- 127.0.0.1:%d  closed", port); !strings.Contains(hv.detail.Text, line) {
+		t.Errorf("history detail = %q, want %q", hv.detail.Text, line)
+	}
+}
+
+func TestReachabilityMonitor(t *testing.T) {
+	v, fake := newTestMainView(t)
+	v.settings.MonitorSecs = 30
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+
+	cfg, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := func(name, allowed string) config.PeerConfig {
+		key, _ := wgtypes.GeneratePrivateKey()
+		_, n, _ := net.ParseCIDR(allowed)
+		return config.PeerConfig{Name: name, PublicKey: key.PublicKey(), AllowedIPs: []net.IPNet{*n}}
+	}
+	cfg.Peers = []config.PeerConfig{
+		peer("loopback", "127.0.0.1/32"),
+		peer("site", "192.168.50.0/24"),       // no single tunnel address
+		peer("old | disabled", "10.8.0.9/32"), // not loaded, not probed
+	}
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := monitorTargets(v.ctrl)
+	if len(targets) != 1 || targets[0].Addr.String() != "127.0.0.1" {
+		t.Fatalf("targets = %+v, want only the loopback peer", targets)
+	}
+
+	// Nothing listens on the port, so the probe is answered with a refusal
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	v.monitor.Run(context.Background(), targets, reachability.TCP(port), time.Second)
+
+	v.Refresh()
+	key := targets[0].PublicKey
+	waitFor(t, "the loopback peer to be healthy", func() bool {
+		return v.peerHealth("wg0")[key].Health == reachability.Good
+	})
+	if _, ok := v.peerHealth("wg0")[cfg.Peers[1].PublicKey.String()]; ok {
+		t.Error("unprobed peer has reachability stats")
+	}
+
+	form := v.openForm("wg0", cfg, false)
+	if form.health == nil || len(form.health(key).Samples) != 1 {
+		t.Error("peers list does not show the probed peer's reachability")
+	}
+
+	// Another round with the same health updates the card in place
+	v.rebuild()
+	card := v.cards["wg0"]
+	v.monitor.Run(context.Background(), targets, reachability.TCP(port), time.Second)
+	v.refreshHealth()
+	if v.cards["wg0"] != card {
+		t.Error("a monitor round without health changes rebuilt the card")
+	}
+	// A peer going down changes the card's peer count, so it is rebuilt
+	lost := func(ctx context.Context, addr netip.Addr, timeout time.Duration) (time.Duration, error) {
+		return 0, errors.New("timeout")
+	}
+	for range reachability.DownAfter {
+		v.monitor.Run(context.Background(), targets, lost, time.Second)
+	}
+	v.refreshHealth()
+	if v.cards["wg0"] == card {
+		t.Error("a peer going down did not rebuild the card")
+	}
+
+	v.settings.MonitorSecs = 0
+	if v.peerHealth("wg0") != nil {
+		t.Error("reachability shown with the monitor off")
+	}
+}
+
+func TestAutoRefreshIntervalResizesTrafficHistory(t *testing.T) {
+	v, _ := newTestMainView(t)
+	now := time.Now()
+	for i := range 10 {
+		v.history.Record("wg0", nil, now.Add(time.Duration(i-10)*time.Second))
+	}
+
+	// Half a day between refreshes leaves room for 3 samples in the span
+	updated := *v.settings
+	updated.AutoRefreshSecs = int(historySpan / time.Second / 2)
+	v.applySettings(&updated)
+	if rates := v.history.Rates("wg0", time.Hour); len(rates) != 2 {
+		t.Errorf("rates after resize = %d, want 2 from the newest 3 samples", len(rates))
+	}
+}
+
+func TestApplySettingsWhileLoopsRun(t *testing.T) {
+	v, _ := newTestMainView(t)
+	ctrl := v.controller()
+
+	// Background loops read the controller and settings while they change
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = v.controller().GetConfigPath("wg0")
+				_ = v.appSettings().WGConfigPath
+				_ = v.auditLog().Path()
+			}
+		}
+	}()
+
+	updated := *v.appSettings()
+	updated.WGConfigPath = t.TempDir()
+	updated.AuditLogFile = filepath.Join(updated.WGConfigPath, "audit.jsonl")
+	v.applySettings(&updated)
+	close(stop)
+	<-done
+
+	if v.controller() == ctrl || v.appSettings() != &updated {
+		t.Error("controller or settings not replaced")
+	}
+	if v.auditLog().Path() != updated.AuditLogFile {
+		t.Errorf("audit log = %s, want %s", v.auditLog().Path(), updated.AuditLogFile)
+	}
+}
+
+func TestSaveDeviceKeepsDisabledPeers(t *testing.T) {
+	_, fake := newTestMainView(t)
+	writeTestTunnel(t, fake, "wg0", "10.8.0.1/24")
+
+	cfg, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := func(name, allowed string) config.PeerConfig {
+		key, _ := wgtypes.GeneratePrivateKey()
+		_, n, _ := net.ParseCIDR(allowed)
+		return config.PeerConfig{Name: name, PublicKey: key.PublicKey(), AllowedIPs: []net.IPNet{*n}}
+	}
+	enabled, disabled := peer("laptop", "10.8.0.2/32"), peer("old | disabled", "10.8.0.9/32")
+	cfg.Peers = []config.PeerConfig{enabled, disabled}
+	if err := fake.WriteConfig("wg0", *cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ToggleInterface("wg0", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := controller.SaveDeviceToFile(fake, "wg0"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := controller.ReadConfig(fake.GetConfigPath("wg0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Peers) != 2 {
+		t.Fatalf("saved %d peers, want the enabled and the disabled one", len(saved.Peers))
+	}
+	got := saved.Peers[1]
+	if got.PublicKey != disabled.PublicKey || got.Name != "old | disabled" || got.AllowedIPs[0].String() != "10.8.0.9/32" {
+		t.Errorf("disabled peer saved as %+v", got)
+	}
+}
+
+func TestRetentionKeepsBackupsAndNewestVersion(t *testing.T) {
+	test.NewTempApp(t)
+	dir := t.TempDir()
+	fake, err := controller.NewFake(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nonsense settings: no versions and no daily window
+	ctrl := controller.NewVersioned(fake, history.NewStore(history.DefaultDir(dir), history.Policy{KeepLast: 0, KeepDailyDays: 0}))
+	writeTestTunnel(t, ctrl, "wg0", "10.8.0.1/24")
+	writeTestTunnel(t, ctrl, "wg1", "10.9.0.1/24")
+	if err := ctrl.DeleteInterface("wg1", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ctrl.ApplyRetention(); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := fake.ListBackups(); len(backups) != 1 {
+		t.Errorf("%d pre-delete backups left, want 1", len(backups))
+	}
+	if versions, _ := ctrl.History().Versions("wg0"); len(versions) != 1 {
+		t.Errorf("%d versions of wg0 left, want the newest", len(versions))
+	}
+}
@@ -3,6 +3,7 @@ package wgwidget
 import (
 	"fmt"
 	"image/color"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -12,17 +13,37 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/MrVasquez96/go-wg/wg/config"
 
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/reachability"
 	customTheme "wgAdmin/internal/ui/theme"
 )
 
 // InterfaceCardCallbacks holds callback functions for interface card actions
 type InterfaceCardCallbacks struct {
-	OnToggle     func(name string, activate bool)
-	OnScan       func(name, ip string)
-	OnEdit       func(name string)
-	OnPeers      func(name string)
-	OnDelete     func(name string)
-	OnCopyPubKey func(pubKey string)
+	OnToggle      func(name string, activate bool)
+	OnScan        func(name, ip string)
+	OnEdit        func(name string)
+	OnPeers       func(name string)
+	OnDelete      func(name string)
+	OnCopyPubKey  func(pubKey string)
+	OnExpandPeers func(name string, expanded bool)
+	OnTraffic     func(name string)
+	OnDrift       func(name string)
+}
+
+// InterfaceCardState holds live data shown on the card in addition to the interface itself
+type InterfaceCardState struct {
+	// Peers is the device state of each peer, only set for active interfaces
+	Peers []controller.PeerStats
+	// PeersExpanded shows the peer statistics section
+	PeersExpanded bool
+	// StaleAfter marks peers without a handshake in this long as stale
+	StaleAfter time.Duration
+	// Drift lists where the running device differs from the config file
+	Drift []controller.Difference
+	// Health is the reachability of each probed peer by public key, nil
+	// when the monitor is off
+	Health map[string]reachability.Stats
 }
 
 // InterfaceCard represents a card widget for a WireGuard interface
@@ -30,14 +51,20 @@ type InterfaceCard struct {
 	widget.BaseWidget
 
 	iface     config.Interface
+	state     InterfaceCardState
 	callbacks InterfaceCardCallbacks
 	container *fyne.Container
+
+	// indicators are the reachability indicators of the probed peers by
+	// public key
+	indicators map[string]*PeerHealth
 }
 
 // NewInterfaceCard creates a new interface card
-func NewInterfaceCard(iface config.Interface, callbacks InterfaceCardCallbacks) *InterfaceCard {
+func NewInterfaceCard(iface config.Interface, state InterfaceCardState, callbacks InterfaceCardCallbacks) *InterfaceCard {
 	card := &InterfaceCard{
 		iface:     iface,
+		state:     state,
 		callbacks: callbacks,
 	}
 	card.ExtendBaseWidget(card)
@@ -119,6 +146,12 @@ func (c *InterfaceCard) buildCard() *fyne.Container {
 		scanBtn.Disable()
 	}
 
+	trafficBtn := widget.NewButtonWithIcon("Traffic", theme.HistoryIcon(), func() {
+		if c.callbacks.OnTraffic != nil {
+			c.callbacks.OnTraffic(c.iface.Name)
+		}
+	})
+
 	editBtn := widget.NewButtonWithIcon("Edit", theme.DocumentCreateIcon(), func() {
 		if c.callbacks.OnEdit != nil {
 			c.callbacks.OnEdit(c.iface.Name)
@@ -142,6 +175,15 @@ func (c *InterfaceCard) buildCard() *fyne.Container {
 		statusBadge,
 		ipLabel,
 	)
+	if len(c.state.Drift) > 0 {
+		driftBtn := widget.NewButtonWithIcon(fmt.Sprintf("Drifted (%d)", len(c.state.Drift)), theme.WarningIcon(), func() {
+			if c.callbacks.OnDrift != nil {
+				c.callbacks.OnDrift(c.iface.Name)
+			}
+		})
+		driftBtn.Importance = widget.WarningImportance
+		statusContent.Add(driftBtn)
+	}
 	leftContent := container.NewVBox(
 		title,
 		statusContent,
@@ -151,6 +193,7 @@ func (c *InterfaceCard) buildCard() *fyne.Container {
 		layout.NewSpacer(),
 		toggleBtn,
 		scanBtn,
+		trafficBtn,
 		editPeersBtn,
 		editBtn,
 		deleteBtn,
@@ -166,6 +209,9 @@ func (c *InterfaceCard) buildCard() *fyne.Container {
 	if pubKeyRow != nil {
 		cardContent.Add(pubKeyRow)
 	}
+	if c.iface.Active {
+		cardContent.Add(c.buildPeersSection())
+	}
 
 	// Card background
 	var bgColor color.Color
@@ -187,6 +233,77 @@ func (c *InterfaceCard) buildCard() *fyne.Container {
 	return container.NewStack(shadow, bg, padded)
 }
 
+// UpdateHealth shows new reachability stats without rebuilding the card. It
+// returns false when the card has to be rebuilt instead: a peer started or
+// stopped being probed or changed health level, which the peer count shows.
+func (c *InterfaceCard) UpdateHealth(health map[string]reachability.Stats) bool {
+	if len(health) != len(c.indicators) {
+		return false
+	}
+	for key, stats := range health {
+		if _, ok := c.indicators[key]; !ok || stats.Health != c.state.Health[key].Health {
+			return false
+		}
+	}
+	for key, stats := range health {
+		c.indicators[key].SetStats(stats)
+	}
+	c.state.Health = health
+	return true
+}
+
+// buildPeersSection returns the expandable live peer statistics
+func (c *InterfaceCard) buildPeersSection() fyne.CanvasObject {
+	stale, down := 0, 0
+	for _, p := range c.state.Peers {
+		if p.IsStale(c.state.StaleAfter) {
+			stale++
+		}
+		if c.state.Health[p.PublicKey].Health == reachability.Down {
+			down++
+		}
+	}
+
+	label := fmt.Sprintf("Peers (%d", len(c.state.Peers))
+	if stale > 0 {
+		label += fmt.Sprintf(", %d stale", stale)
+	}
+	if down > 0 {
+		label += fmt.Sprintf(", %d unreachable", down)
+	}
+	label += ")"
+	icon := theme.MenuExpandIcon()
+	if c.state.PeersExpanded {
+		icon = theme.MenuDropDownIcon()
+	}
+
+	details, indicators := newPeerStatsSection(c.state.Peers, c.state.StaleAfter, c.state.Health)
+	c.indicators = indicators
+	if !c.state.PeersExpanded {
+		details.Hide()
+	}
+
+	var toggleBtn *widget.Button
+	toggleBtn = widget.NewButtonWithIcon(label, icon, func() {
+		c.state.PeersExpanded = !c.state.PeersExpanded
+		if c.state.PeersExpanded {
+			details.Show()
+			toggleBtn.SetIcon(theme.MenuDropDownIcon())
+		} else {
+			details.Hide()
+			toggleBtn.SetIcon(theme.MenuExpandIcon())
+		}
+		if c.callbacks.OnExpandPeers != nil {
+			c.callbacks.OnExpandPeers(c.iface.Name, c.state.PeersExpanded)
+		}
+		c.Refresh()
+	})
+	toggleBtn.Importance = widget.LowImportance
+	toggleBtn.Alignment = widget.ButtonAlignLeading
+
+	return container.NewVBox(toggleBtn, details)
+}
+
 // CreateRenderer implements fyne.Widget
 func (c *InterfaceCard) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(c.container)
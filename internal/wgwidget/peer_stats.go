@@ -0,0 +1,125 @@
+package wgwidget
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"wgAdmin/internal/controller"
+	"wgAdmin/internal/reachability"
+	customTheme "wgAdmin/internal/ui/theme"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// FormatBytes returns a human readable byte count (e.g. "1.5 MiB")
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatAge returns how long ago t was (e.g. "42s ago"), or "never" for the zero time
+func FormatAge(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// newPeerStatsSection builds one row per peer; peers without a handshake
+// within staleAfter are highlighted with the warning colors. Probed peers
+// also show their reachability from health, with the indicators returned by
+// public key.
+func newPeerStatsSection(peers []controller.PeerStats, staleAfter time.Duration, health map[string]reachability.Stats) (fyne.CanvasObject, map[string]*PeerHealth) {
+	indicators := make(map[string]*PeerHealth)
+	if len(peers) == 0 {
+		empty := widget.NewLabel("No peers on the running device.")
+		empty.TextStyle = fyne.TextStyle{Italic: true}
+		return empty, indicators
+	}
+
+	rows := container.NewVBox()
+	for _, p := range peers {
+		var indicator *PeerHealth
+		if stats, probed := health[p.PublicKey]; probed {
+			indicator = NewPeerHealth(reachability.DefaultKeep)
+			indicator.SetStats(stats)
+			indicators[p.PublicKey] = indicator
+		}
+		rows.Add(newPeerStatsRow(p, p.IsStale(staleAfter), indicator))
+	}
+	return rows, indicators
+}
+
+// newPeerStatsRow builds the row of a peer; indicator is nil when the peer
+// is not probed
+func newPeerStatsRow(p controller.PeerStats, stale bool, indicator *PeerHealth) fyne.CanvasObject {
+	variant := customTheme.CurrentVariant()
+
+	dotColor := customTheme.AppColors.Active(variant)
+	if stale {
+		dotColor = customTheme.AppColors.Warning(variant)
+	}
+	dot := canvas.NewCircle(dotColor)
+	dot.Resize(fyne.NewSize(10, 10))
+
+	name := p.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	displayKey := p.PublicKey
+	if len(displayKey) > 12 {
+		displayKey = displayKey[:12] + "..."
+	}
+	nameLabel := widget.NewLabel(fmt.Sprintf("%s  %s", name, displayKey))
+	nameLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	handshake := "Handshake: " + FormatAge(p.LastHandshake)
+	if stale {
+		handshake += " (stale)"
+	}
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "(none)"
+	}
+
+	details := widget.NewLabel(fmt.Sprintf("%s | rx %s | tx %s | Endpoint: %s | Allowed IPs: %s",
+		handshake, FormatBytes(p.RxBytes), FormatBytes(p.TxBytes), endpoint, strings.Join(p.AllowedIPs, ", ")))
+	details.TextStyle = fyne.TextStyle{Monospace: true}
+	details.Wrapping = fyne.TextWrapWord
+
+	header := fyne.CanvasObject(nameLabel)
+	if indicator != nil {
+		header = container.NewHBox(nameLabel, indicator)
+	}
+	content := container.NewBorder(nil, nil, container.NewCenter(container.NewGridWrap(fyne.NewSize(10, 10), dot)), nil,
+		container.NewVBox(header, details))
+
+	if !stale {
+		return content
+	}
+	bg := canvas.NewRectangle(customTheme.AppColors.StatusWarningBg(variant))
+	bg.CornerRadius = 6
+	return container.NewStack(bg, content)
+}
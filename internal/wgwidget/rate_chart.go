@@ -0,0 +1,183 @@
+package wgwidget
+
+import (
+	"image/color"
+	"time"
+
+	"wgAdmin/internal/traffic"
+	customTheme "wgAdmin/internal/ui/theme"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// RateChart draws rx/tx throughput over a time window as two line graphs
+type RateChart struct {
+	widget.BaseWidget
+
+	rates  []traffic.Rate
+	window time.Duration
+}
+
+// NewRateChart creates an empty chart
+func NewRateChart() *RateChart {
+	c := &RateChart{window: 5 * time.Minute}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetRates replaces the plotted data, spreading it over window ending now
+func (c *RateChart) SetRates(rates []traffic.Rate, window time.Duration) {
+	c.rates = rates
+	c.window = window
+	c.Refresh()
+}
+
+// CreateRenderer implements fyne.Widget
+func (c *RateChart) CreateRenderer() fyne.WidgetRenderer {
+	variant := customTheme.CurrentVariant()
+
+	bg := canvas.NewRectangle(customTheme.AppColors.CardBackground(variant))
+	bg.StrokeColor = customTheme.AppColors.Border(variant)
+	bg.StrokeWidth = 1
+	bg.CornerRadius = 6
+
+	r := &rateChartRenderer{
+		chart:   c,
+		bg:      bg,
+		peak:    canvas.NewText("", theme.Color(theme.ColorNameForeground)),
+		rxLabel: canvas.NewText("rx", theme.Color(theme.ColorNamePrimary)),
+		txLabel: canvas.NewText("tx", theme.Color(theme.ColorNameSuccess)),
+	}
+	r.peak.TextSize = theme.CaptionTextSize()
+	r.rxLabel.TextSize = theme.CaptionTextSize()
+	r.txLabel.TextSize = theme.CaptionTextSize()
+	r.rxLabel.TextStyle = fyne.TextStyle{Bold: true}
+	r.txLabel.TextStyle = fyne.TextStyle{Bold: true}
+	r.rebuild(c.Size())
+	return r
+}
+
+type rateChartRenderer struct {
+	chart   *RateChart
+	bg      *canvas.Rectangle
+	peak    *canvas.Text
+	rxLabel *canvas.Text
+	txLabel *canvas.Text
+	objects []fyne.CanvasObject
+}
+
+func (r *rateChartRenderer) Layout(size fyne.Size) {
+	r.rebuild(size)
+}
+
+func (r *rateChartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(320, 120)
+}
+
+func (r *rateChartRenderer) Refresh() {
+	r.rebuild(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *rateChartRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *rateChartRenderer) Destroy() {}
+
+// rebuild averages the rates into buckets across the chart width and
+// regenerates the line segments.
+func (r *rateChartRenderer) rebuild(size fyne.Size) {
+	r.bg.Resize(size)
+	r.objects = []fyne.CanvasObject{r.bg, r.peak, r.rxLabel, r.txLabel}
+
+	pad := theme.Padding()
+	labelHeight := r.peak.MinSize().Height
+	plotX, plotY := pad, pad+labelHeight
+	plotW, plotH := size.Width-2*pad, size.Height-2*pad-labelHeight
+	if plotW <= 0 || plotH <= 0 {
+		return
+	}
+
+	buckets := int(plotW / 4)
+	if buckets > 240 {
+		buckets = 240
+	}
+	if buckets < 2 {
+		return
+	}
+	rx, tx, ok := bucketRates(r.chart.rates, r.chart.window, buckets)
+
+	peak := 0.0
+	for i := range rx {
+		if ok[i] {
+			peak = max(peak, rx[i], tx[i])
+		}
+	}
+
+	r.peak.Text = "peak " + FormatBytes(int64(peak)) + "/s"
+	r.peak.Move(fyne.NewPos(pad, pad/2))
+	r.txLabel.Move(fyne.NewPos(size.Width-pad-r.txLabel.MinSize().Width, pad/2))
+	r.rxLabel.Move(fyne.NewPos(r.txLabel.Position().X-pad-r.rxLabel.MinSize().Width, pad/2))
+
+	if peak == 0 {
+		peak = 1
+	}
+	step := plotW / float32(buckets)
+	point := func(i int, v float64) fyne.Position {
+		return fyne.NewPos(plotX+step*(float32(i)+0.5), plotY+plotH-float32(v/peak)*plotH)
+	}
+
+	// Buckets can be empty when sampling is slower than the bucket width,
+	// so each point is joined to the previous bucket that has data.
+	addLine := func(values []float64, col color.Color) {
+		prev := -1
+		for i := 0; i < buckets; i++ {
+			if !ok[i] {
+				continue
+			}
+			if prev >= 0 {
+				line := canvas.NewLine(col)
+				line.StrokeWidth = 2
+				line.Position1 = point(prev, values[prev])
+				line.Position2 = point(i, values[i])
+				r.objects = append(r.objects, line)
+			}
+			prev = i
+		}
+	}
+	addLine(rx, r.rxLabel.Color)
+	addLine(tx, r.txLabel.Color)
+}
+
+// bucketRates averages rates into n equal time buckets over window ending now.
+// ok[i] is false for buckets without data.
+func bucketRates(rates []traffic.Rate, window time.Duration, n int) (rx, tx []float64, ok []bool) {
+	rx, tx, ok = make([]float64, n), make([]float64, n), make([]bool, n)
+	counts := make([]int, n)
+	if window <= 0 {
+		return rx, tx, ok
+	}
+
+	start := time.Now().Add(-window)
+	for _, rate := range rates {
+		i := int(float64(rate.Time.Sub(start)) / float64(window) * float64(n))
+		if i < 0 || i >= n {
+			continue
+		}
+		rx[i] += rate.Rx
+		tx[i] += rate.Tx
+		counts[i]++
+	}
+	for i := range counts {
+		if counts[i] > 0 {
+			rx[i] /= float64(counts[i])
+			tx[i] /= float64(counts[i])
+			ok[i] = true
+		}
+	}
+	return rx, tx, ok
+}
@@ -0,0 +1,182 @@
+package wgwidget
+
+import (
+	"image/color"
+	"time"
+
+	"wgAdmin/internal/reachability"
+	customTheme "wgAdmin/internal/ui/theme"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Sparkline draws recent probe latencies as a small line graph, with a red
+// tick for every lost probe. Samples fill slots from the right.
+type Sparkline struct {
+	widget.BaseWidget
+
+	samples []reachability.Sample
+	slots   int
+}
+
+// NewSparkline creates an empty sparkline with room for slots samples
+func NewSparkline(slots int) *Sparkline {
+	s := &Sparkline{slots: max(slots, 2)}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// SetSamples replaces the plotted samples, oldest first
+func (s *Sparkline) SetSamples(samples []reachability.Sample) {
+	s.samples = samples
+	s.Refresh()
+}
+
+// CreateRenderer implements fyne.Widget
+func (s *Sparkline) CreateRenderer() fyne.WidgetRenderer {
+	r := &sparklineRenderer{line: s}
+	r.rebuild(s.Size())
+	return r
+}
+
+type sparklineRenderer struct {
+	line    *Sparkline
+	objects []fyne.CanvasObject
+}
+
+func (r *sparklineRenderer) Layout(size fyne.Size) {
+	r.rebuild(size)
+}
+
+func (r *sparklineRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(90, 20)
+}
+
+func (r *sparklineRenderer) Refresh() {
+	r.rebuild(r.line.Size())
+	canvas.Refresh(r.line)
+}
+
+func (r *sparklineRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *sparklineRenderer) Destroy() {}
+
+// rebuild scales the latencies to the slowest sample and regenerates the
+// line segments and loss ticks
+func (r *sparklineRenderer) rebuild(size fyne.Size) {
+	r.objects = nil
+	samples := r.line.samples
+	slots := max(r.line.slots, len(samples))
+	if len(samples) == 0 || size.Width <= 0 || size.Height <= 0 {
+		return
+	}
+	if len(samples) > slots {
+		samples = samples[len(samples)-slots:]
+	}
+
+	var peak time.Duration
+	for _, s := range samples {
+		peak = max(peak, s.Latency)
+	}
+	if peak == 0 {
+		peak = 1
+	}
+
+	step := size.Width / float32(slots-1)
+	offset := slots - len(samples)
+	point := func(i int) fyne.Position {
+		v := float32(samples[i].Latency) / float32(peak)
+		return fyne.NewPos(step*float32(offset+i), size.Height-1-v*(size.Height-2))
+	}
+
+	lineColor := theme.Color(theme.ColorNamePrimary)
+	lossColor := theme.Color(theme.ColorNameError)
+	prev := -1
+	for i, s := range samples {
+		if !s.OK {
+			tick := canvas.NewLine(lossColor)
+			tick.StrokeWidth = 2
+			x := step * float32(offset+i)
+			tick.Position1 = fyne.NewPos(x, size.Height*0.4)
+			tick.Position2 = fyne.NewPos(x, size.Height)
+			r.objects = append(r.objects, tick)
+			prev = -1
+			continue
+		}
+		if prev >= 0 {
+			seg := canvas.NewLine(lineColor)
+			seg.StrokeWidth = 1.5
+			seg.Position1 = point(prev)
+			seg.Position2 = point(i)
+			r.objects = append(r.objects, seg)
+		} else {
+			dot := canvas.NewCircle(lineColor)
+			p := point(i)
+			dot.Move(fyne.NewPos(p.X-1.5, p.Y-1.5))
+			dot.Resize(fyne.NewSize(3, 3))
+			r.objects = append(r.objects, dot)
+		}
+		prev = i
+	}
+}
+
+// HealthColor is the indicator color of a health level
+func HealthColor(h reachability.Health) color.Color {
+	variant := customTheme.CurrentVariant()
+	switch h {
+	case reachability.Good:
+		return customTheme.AppColors.Active(variant)
+	case reachability.Degraded:
+		return customTheme.AppColors.Warning(variant)
+	case reachability.Down:
+		return theme.Color(theme.ColorNameError)
+	}
+	return customTheme.AppColors.Inactive(variant)
+}
+
+// PeerHealth shows the reachability of a peer: a colored dot, a sparkline
+// of the recent latencies and a short summary
+type PeerHealth struct {
+	widget.BaseWidget
+
+	dot     *canvas.Circle
+	spark   *Sparkline
+	summary *widget.Label
+	content *fyne.Container
+}
+
+// NewPeerHealth creates an indicator plotting up to slots samples
+func NewPeerHealth(slots int) *PeerHealth {
+	h := &PeerHealth{
+		dot:     canvas.NewCircle(HealthColor(reachability.Unknown)),
+		spark:   NewSparkline(slots),
+		summary: widget.NewLabel(""),
+	}
+	h.summary.TextStyle = fyne.TextStyle{Monospace: true}
+	h.content = container.NewHBox(
+		container.NewCenter(container.NewGridWrap(fyne.NewSize(10, 10), h.dot)),
+		container.NewCenter(h.spark),
+		h.summary,
+	)
+	h.ExtendBaseWidget(h)
+	return h
+}
+
+// SetStats shows stats
+func (h *PeerHealth) SetStats(stats reachability.Stats) {
+	h.dot.FillColor = HealthColor(stats.Health)
+	h.dot.Refresh()
+	h.spark.SetSamples(stats.Samples)
+	h.summary.SetText(stats.Summary())
+}
+
+// CreateRenderer implements fyne.Widget
+func (h *PeerHealth) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(h.content)
+}
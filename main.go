@@ -4,17 +4,30 @@ import (
 	"fmt"
 	"os"
 
+	"wgAdmin/internal/cli"
+	"wgAdmin/internal/controller"
 	"wgAdmin/internal/settings"
 	"wgAdmin/internal/ui/theme"
 	ui "wgAdmin/internal/ui/views"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
-	"github.com/MrVasquez96/go-wg/wg"
 )
 
 func main() {
-	a := app.NewWithID("com.wireguard.manager")
+	// Headless mode: subcommands run against the same controller and
+	// settings as the GUI, read from the preferences file without starting
+	// Fyne, so they work without a display.
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		cfg, err := settings.LoadFile(settings.PreferencesFile())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(cli.ExitError)
+		}
+		os.Exit(cli.Main(controller.Open(cfg.WGConfigPath, cfg.BackupPolicy()), cfg, os.Args[1:]))
+	}
+
+	a := app.NewWithID(settings.AppID)
 	//
 	meta := a.Metadata()
 	cfg := settings.Load(a.Preferences())
@@ -45,7 +58,7 @@ func main() {
 		fmt.Fprintln(os.Stderr, "")
 	}
 
-	ctrl := wg.New(cfg.WGConfigPath)
+	ctrl := controller.Open(cfg.WGConfigPath, cfg.BackupPolicy())
 
 	version := "unknown"
 	if meta.Version != "" {
@@ -58,7 +71,8 @@ func main() {
 		w.SetFullScreen(true)
 	}
 
-	mainView := ui.NewMainView(w, &ctrl, cfg).Build(meta)
+	view := ui.NewMainView(w, ctrl, cfg)
+	mainView := view.Build(meta)
 	w.SetContent(mainView)
 	mainView.Refresh()
 
@@ -69,4 +83,5 @@ func main() {
 	}
 
 	w.ShowAndRun()
+	view.Close()
 }